@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohMinTTL DoH 解析结果的最短缓存时间（记录的 TTL 更短时按此缓存，避免每次连接都查询）
+const dohMinTTL = 60 * time.Second
+
+// dohMaxResponseSize DoH 响应的最大长度
+const dohMaxResponseSize = 64 * 1024
+
+// dialer 建立 TCP 连接，按 hosts 覆盖、DoH 或自定义 DNS 服务器解析域名（都未配置时使用系统 DNS）
+type dialer struct {
+	net   *net.Dialer
+	hosts map[string]string // key: 小写域名
+	doh   *dohResolver
+}
+
+// newDialer 按网络选项创建拨号器，tlsConfig 用于访问 DoH 服务器
+func newDialer(opts ClientOptions, tlsConfig *tls.Config) (*dialer, error) {
+	d := &dialer{
+		net:   &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second},
+		hosts: make(map[string]string, len(opts.Hosts)),
+	}
+	for host, ip := range opts.Hosts {
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("hosts 中 %s 的 IP 无效: %s", host, ip)
+		}
+		d.hosts[strings.ToLower(host)] = ip
+	}
+	if len(opts.DNSServers) > 0 {
+		d.net.Resolver = newDNSResolver(opts.DNSServers, opts.DialTimeout)
+	}
+	if opts.DoHURL != "" {
+		doh, err := newDoHResolver(opts.DoHURL, &dialer{net: d.net, hosts: d.hosts}, tlsConfig, opts.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		d.doh = doh
+	}
+	return d, nil
+}
+
+// DialContext 建立连接（http.Transport.DialContext）
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return d.net.DialContext(ctx, network, address)
+	}
+	if ip, ok := d.hosts[strings.ToLower(host)]; ok {
+		return d.net.DialContext(ctx, network, net.JoinHostPort(ip, port))
+	}
+	if d.doh == nil || net.ParseIP(host) != nil {
+		return d.net.DialContext(ctx, network, address)
+	}
+
+	ips, err := d.doh.LookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := d.net.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// newDNSResolver 创建使用指定 DNS 服务器的解析器（轮流使用各服务器，一个失败时重试会换下一个）
+func newDNSResolver(servers []string, timeout time.Duration) *net.Resolver {
+	addresses := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		addresses = append(addresses, server)
+	}
+
+	var next uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := addresses[int(atomic.AddUint32(&next, 1)-1)%len(addresses)]
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// dohResolver DNS over HTTPS 解析器（RFC 8484，POST application/dns-message）
+type dohResolver struct {
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]dohAnswer // key: 小写域名
+}
+
+// dohAnswer 缓存的解析结果
+type dohAnswer struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// newDoHResolver 创建 DoH 解析器，访问 DoH 服务器时不使用代理（DoH 服务器的域名按 hosts 或 DNS 服务器解析）
+func newDoHResolver(dohURL string, d *dialer, tlsConfig *tls.Config, timeout time.Duration) (*dohResolver, error) {
+	parsed, err := url.Parse(dohURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("DoH 地址无效（需要 https:// 地址）: %s", dohURL)
+	}
+	return &dohResolver{
+		url: dohURL,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext:       d.DialContext,
+				TLSClientConfig:   tlsConfig,
+				ForceAttemptHTTP2: true,
+				IdleConnTimeout:   90 * time.Second,
+			},
+			Timeout: timeout,
+		},
+		cache: make(map[string]dohAnswer),
+	}, nil
+}
+
+// LookupIP 解析域名的 IP（优先 IPv4，没有 A 记录时查询 AAAA 记录）
+func (r *dohResolver) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.Lock()
+	answer, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(answer.expires) {
+		return answer.ips, nil
+	}
+
+	ips, ttl, err := r.query(ctx, host, dnsmessage.TypeA)
+	if err == nil && len(ips) == 0 {
+		ips, ttl, err = r.query(ctx, host, dnsmessage.TypeAAAA)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DoH 解析 %s 失败: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("DoH 解析 %s 失败: 没有 A/AAAA 记录", host)
+	}
+
+	r.mu.Lock()
+	r.cache[host] = dohAnswer{ips: ips, expires: time.Now().Add(max(ttl, dohMinTTL))}
+	r.mu.Unlock()
+	return ips, nil
+}
+
+// query 查询一种记录，返回 IP 和最短的 TTL
+func (r *dohResolver) query(ctx context.Context, host string, recordType dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("域名无效: %w", err)
+	}
+	// RFC 8484 建议 ID 为 0，便于 HTTP 缓存
+	question := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: recordType, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := question.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("构造 DNS 查询失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, &StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dohMaxResponseSize))
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(body); err != nil {
+		return nil, 0, fmt.Errorf("解析 DNS 响应失败: %w", err)
+	}
+	if answer.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DNS 响应码: %s", answer.RCode)
+	}
+
+	var ips []net.IP
+	var ttl time.Duration
+	for _, resource := range answer.Answers {
+		var ip net.IP
+		switch body := resource.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			ip = net.IP(body.AAAA[:])
+		default:
+			continue // CNAME 等记录
+		}
+		recordTTL := time.Duration(resource.Header.TTL) * time.Second
+		if len(ips) == 0 || recordTTL < ttl {
+			ttl = recordTTL
+		}
+		ips = append(ips, ip)
+	}
+	return ips, ttl, nil
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
 	"wails-contract-warn/logger"
 )
@@ -15,22 +14,24 @@ type ProxyClient struct {
 	client *http.Client
 }
 
-// NewProxyClient 创建代理客户端
-func NewProxyClient() *ProxyClient {
-	// 配置 HTTP 客户端，支持代理和自定义 DNS
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		IdleConnTimeout:      90 * time.Second,
-		TLSHandshakeTimeout: 10 * time.Second,
-		DisableKeepAlives:   false,
-	}
+// StatusError API 返回非200状态码
+// 调用方可以通过 errors.As 取得状态码和响应头（如 429 的 Retry-After）
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+}
 
-	// 如果设置了代理环境变量，会自动使用
-	// 也可以通过代码设置：transport.Proxy = http.ProxyURL(proxyURL)
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API 返回错误 (状态码: %d)", e.StatusCode)
+}
 
+// NewProxyClient 创建代理客户端
+// 代理、DNS、hosts 覆盖、CA 证书和超时读取 symbols.json 的 network 配置（见 DefaultTransport），
+// 未配置代理时使用 HTTP_PROXY/HTTPS_PROXY 环境变量
+func NewProxyClient() *ProxyClient {
 	client := &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second, // 30秒超时
+		Transport: DefaultTransport(),
+		Timeout:   OptionsFromConfig().Timeout,
 	}
 
 	return &ProxyClient{
@@ -38,6 +39,20 @@ func NewProxyClient() *ProxyClient {
 	}
 }
 
+// NewProxyClientWithOptions 按指定的网络选项创建代理客户端（不读取配置文件，不与其他客户端共用连接池）
+func NewProxyClientWithOptions(opts ClientOptions) (*ProxyClient, error) {
+	transport, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ProxyClient{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+		},
+	}, nil
+}
+
 // FetchAPI 代理获取 API 数据
 func (p *ProxyClient) FetchAPI(url string, headers map[string]string) (map[string]interface{}, error) {
 	logger.Debugf("代理请求: %s", url)
@@ -91,11 +106,21 @@ func (p *ProxyClient) FetchAPI(url string, headers map[string]string) (map[strin
 
 // FetchAPIRaw 获取原始响应（不解析 JSON）
 func (p *ProxyClient) FetchAPIRaw(url string, headers map[string]string) ([]byte, error) {
+	body, _, err := p.FetchAPIRawWithHeader(url, headers)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// FetchAPIRawWithHeader 获取原始响应和响应头
+// 状态码非200时同样返回响应体和响应头（便于读取交易所的错误信息和限流相关的响应头）
+func (p *ProxyClient) FetchAPIRawWithHeader(url string, headers map[string]string) ([]byte, http.Header, error) {
 	logger.Debugf("代理请求(原始): %s", url)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %w", err)
+		return nil, nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	// 设置默认请求头
@@ -111,24 +136,24 @@ func (p *ProxyClient) FetchAPIRaw(url string, headers map[string]string) ([]byte
 	resp, err := p.client.Do(req)
 	if err != nil {
 		logger.Errorf("请求失败: %v", err)
-		return nil, fmt.Errorf("请求失败: %w", err)
+		return nil, nil, fmt.Errorf("请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
+		return nil, resp.Header, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	// 检查状态码
 	if resp.StatusCode != http.StatusOK {
 		logger.Warnf("API 返回非200状态码: %d", resp.StatusCode)
-		return nil, fmt.Errorf("API 返回错误 (状态码: %d)", resp.StatusCode)
+		return body, resp.Header, &StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
 	}
 
 	logger.Debugf("代理请求成功: %s (状态码: %d, 大小: %d bytes)", url, resp.StatusCode, len(body))
-	return body, nil
+	return body, resp.Header, nil
 }
 
 // min 辅助函数
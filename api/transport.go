@@ -0,0 +1,158 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// ClientOptions 代理客户端的网络选项（字段含义见 config.NetworkConfig）
+type ClientOptions struct {
+	Proxy               string            // 代理地址，为空时使用 HTTP_PROXY/HTTPS_PROXY 环境变量
+	DNSServers          []string          // 自定义 DNS 服务器，为空时使用系统 DNS
+	DoHURL              string            // DNS over HTTPS 地址，配置后优先于 DNSServers
+	Hosts               map[string]string // 域名 → IP，优先于 DNS 解析
+	CAFiles             []string          // 额外信任的 CA 证书文件（PEM 格式）
+	Timeout             time.Duration     // 单个请求的超时
+	DialTimeout         time.Duration     // 建立连接的超时
+	TLSHandshakeTimeout time.Duration     // TLS 握手的超时
+}
+
+// defaultOptions 未配置网络时的默认选项
+func defaultOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:             30 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// OptionsFromConfig 读取 symbols.json 中 network 配置的网络选项（读取配置失败时返回默认选项）
+func OptionsFromConfig() ClientOptions {
+	opts := defaultOptions()
+	network, err := config.GetNetworkConfig()
+	if err != nil {
+		return opts
+	}
+	opts.Proxy = network.Proxy
+	opts.DNSServers = network.DNSServers
+	opts.DoHURL = network.DoHURL
+	opts.Hosts = network.Hosts
+	opts.CAFiles = network.CAFiles
+	opts.Timeout = time.Duration(network.TimeoutSeconds) * time.Second
+	opts.DialTimeout = time.Duration(network.DialTimeoutSeconds) * time.Second
+	opts.TLSHandshakeTimeout = time.Duration(network.TLSHandshakeTimeoutSeconds) * time.Second
+	return opts
+}
+
+// NewTransport 按网络选项创建 HTTP Transport
+// 使用代理时目标域名由代理服务器解析，Hosts、DNSServers 和 DoHURL 只用于解析代理服务器的地址
+func NewTransport(opts ClientOptions) (*http.Transport, error) {
+	proxy, err := proxyFunc(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := tlsConfigFor(opts.CAFiles)
+	if err != nil {
+		return nil, err
+	}
+	dialer, err := newDialer(opts, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   true, // 自定义 DialContext 和 TLSClientConfig 后需要显式启用 HTTP/2
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+		DisableKeepAlives:   false,
+	}, nil
+}
+
+// defaultTransport 按配置文件创建的共享 Transport（所有 NewProxyClient 创建的客户端共用连接池）
+var defaultTransport struct {
+	once      sync.Once
+	transport *http.Transport
+}
+
+// DefaultTransport 获取按 symbols.json 的 network 配置创建的共享 Transport（首次调用时创建）
+// 配置无效时记录错误并使用默认设置，WebSocket 连接也通过它取得代理、拨号和证书设置
+func DefaultTransport() *http.Transport {
+	defaultTransport.once.Do(func() {
+		opts := OptionsFromConfig()
+		transport, err := NewTransport(opts)
+		if err != nil {
+			logger.Errorf("网络配置无效，使用默认设置: %v", err)
+			transport, _ = NewTransport(defaultOptions())
+		} else if opts.Proxy != "" || len(opts.DNSServers) > 0 || opts.DoHURL != "" || len(opts.Hosts) > 0 || len(opts.CAFiles) > 0 {
+			logger.Infof("网络配置: 代理=%s, DNS=%v, DoH=%s, hosts=%d 条, CA 证书=%d 个",
+				redactProxy(opts.Proxy), opts.DNSServers, opts.DoHURL, len(opts.Hosts), len(opts.CAFiles))
+		}
+		defaultTransport.transport = transport
+	})
+	return defaultTransport.transport
+}
+
+// proxyFunc 解析代理地址，为空时使用环境变量中的代理
+// socks5h:// 按 socks5:// 处理（两者都由代理服务器解析域名，WebSocket 只支持 socks5）
+func proxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("代理地址无效: %s", proxy)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	case "socks5h":
+		proxyURL.Scheme = "socks5"
+	default:
+		return nil, fmt.Errorf("不支持的代理协议: %s（可选: http、https、socks5）", proxyURL.Scheme)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// redactProxy 隐藏代理地址中的密码（用于日志）
+func redactProxy(proxy string) string {
+	if proxy == "" {
+		return "环境变量"
+	}
+	if proxyURL, err := url.Parse(proxy); err == nil {
+		return proxyURL.Redacted()
+	}
+	return proxy
+}
+
+// tlsConfigFor 在系统证书的基础上加入额外信任的 CA 证书，没有额外证书时返回 nil（使用默认设置）
+func tlsConfigFor(caFiles []string) (*tls.Config, error) {
+	if len(caFiles) == 0 {
+		return nil, nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, file := range caFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA 证书 %s 中没有有效的 PEM 证书", file)
+		}
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
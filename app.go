@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"strings"
 	"time"
 
 	"wails-contract-warn/api"
 	"wails-contract-warn/config"
 	"wails-contract-warn/database"
+	"wails-contract-warn/export"
+	"wails-contract-warn/importer"
 	"wails-contract-warn/indicator"
 	"wails-contract-warn/logger"
 	"wails-contract-warn/models"
@@ -33,8 +34,12 @@ type App struct {
 	realtimeSyncService   *service.RealtimeSyncService
 	realtimePriceService  *service.RealtimePriceService
 	gapFillService        *service.GapFillService
+	retentionService      *service.RetentionService
+	streamService         *service.StreamService
 	proxyClient           *api.ProxyClient
 	dbInit                bool
+	memoryMode            bool  // 未配置数据库或配置为 memory:// 时使用内存存储（演示模式）
+	dbErr                 error // 配置的数据库连接失败的原因（不会切换到演示模式，查询数据时返回该错误）
 }
 
 // NewApp 创建新的应用实例
@@ -60,11 +65,54 @@ func (a *App) startup(ctx context.Context) {
 	if dsn != "" {
 		logger.Infof("正在连接数据库: %s", maskDSN(dsn))
 		if err := database.InitDB(dsn); err != nil {
+			// 不切换到演示模式：演示数据会被当成真实行情显示
 			logger.Errorf("数据库初始化失败: %v", err)
+			database.CloseDB()
+			a.dbErr = fmt.Errorf("数据库连接失败: %w", err)
+		} else if database.IsMemoryDSN(dsn) {
+			a.startMemoryMode()
 		} else {
 			a.dbInit = true
 			logger.Info("数据库连接成功，表结构已创建")
 
+			// 按配置在后台根据实际数据重建已同步时间段
+			if syncConfig, err := config.GetSyncConfig(); err == nil && syncConfig.RebuildRangesOnStartup {
+				go func() {
+					if _, err := database.RebuildSyncTimeRangesFromConfig(); err != nil {
+						logger.Errorf("启动时重建同步时间段失败: %v", err)
+					}
+				}()
+			}
+
+			// 加载缓存的交易对元数据（规范化交易对、计算回填起点），超过有效期时在后台重新拉取
+			if count, err := database.LoadSymbolRegistry(); err != nil {
+				logger.Errorf("加载交易对元数据失败: %v", err)
+			} else {
+				logger.Infof("已加载 %d 个交易对的元数据", count)
+			}
+			go func() {
+				if err := datasync.RefreshSymbolRegistryIfStale(); err != nil {
+					logger.Warnf("更新交易对元数据失败: %v", err)
+				}
+			}()
+
+			// 已下架、暂停交易或已更名的交易对不再调度同步任务，状态变化时推送 symbol-status 事件
+			datasync.SetEventEmitter(func(event string, data ...interface{}) {
+				if a.ctx != nil {
+					runtime.EventsEmit(a.ctx, event, data...)
+				}
+			})
+			if count, err := database.LoadInactiveSymbols(); err != nil {
+				logger.Errorf("加载停止同步的交易对失败: %v", err)
+			} else if count > 0 {
+				logger.Infof("%d 个交易对已停止同步（已下架、暂停交易或已更名）", count)
+			}
+			go func() {
+				if err := datasync.ApplySymbolAliases(); err != nil {
+					logger.Errorf("处理更名的交易对失败: %v", err)
+				}
+			}()
+
 			// 默认只启动历史数据同步服务
 			if err := a.StartHistoricalSyncService(); err != nil {
 				logger.Errorf("启动历史数据同步服务失败: %v", err)
@@ -73,14 +121,49 @@ func (a *App) startup(ctx context.Context) {
 			// 启动实时价格服务和历史空缺补充服务
 			a.StartRealtimePriceService()
 			a.StartGapFillService()
+
+			// 启用了 WebSocket 实时K线时，已订阅的币种不再由实时价格服务轮询
+			a.StartStreamService()
+
+			// 启用了数据保留策略时，在后台定期清理过期的1分钟K线
+			a.StartRetentionService()
 		}
 	} else {
 		logger.Warn("未配置数据库连接，将使用内存模式")
+		a.startMemoryMode()
 	}
 
 	logger.Info("应用初始化完成")
 }
 
+// demoDataFile 内存模式下默认加载的测试数据及对应币种
+const (
+	demoDataFile   = "test1.json"
+	demoDataSymbol = "BTC_USDT"
+)
+
+// startMemoryMode 切换到内存存储（演示模式），并加载默认测试数据
+// 内存模式下 GetMarketData 等方法从内存读取，手动同步的数据也会写入内存
+func (a *App) startMemoryMode() {
+	if _, ok := database.GetStore().(*database.MemoryStore); !ok {
+		if err := database.InitDB(database.MemoryDSN); err != nil {
+			logger.Errorf("初始化内存存储失败: %v", err)
+			return
+		}
+	}
+	a.memoryMode = true
+	logger.Info("已切换到内存模式（数据不会持久化）")
+
+	if _, err := a.SeedTestData(demoDataFile, demoDataSymbol); err != nil {
+		logger.Warnf("加载演示数据失败: %v", err)
+	}
+}
+
+// storeReady 检查是否有可用的K线存储（数据库或内存）
+func (a *App) storeReady() bool {
+	return a.dbInit || a.memoryMode
+}
+
 // domReady DOM 准备就绪时调用
 func (a *App) domReady(ctx context.Context) {
 	// 可以在这里执行一些初始化操作
@@ -123,6 +206,19 @@ func (a *App) shutdown(ctx context.Context) {
 		logger.Debug("历史空缺补充服务已停止")
 	}
 
+	if a.retentionService != nil {
+		a.retentionService.Stop()
+		logger.Debug("数据保留服务已停止")
+	}
+
+	if a.streamService != nil {
+		a.streamService.Stop()
+		logger.Debug("实时K线服务已停止")
+	}
+
+	// 丢弃排队中的同步任务
+	datasync.StopScheduler()
+
 	if a.dbInit {
 		database.CloseDB()
 		logger.Debug("数据库连接已关闭")
@@ -135,12 +231,17 @@ func (a *App) shutdown(ctx context.Context) {
 func (a *App) GetMarketData(symbol string, period string) (string, error) {
 	logger.Debugf("获取市场数据: symbol=%s, period=%s", symbol, period)
 
-	// 如果数据库已初始化，从数据库读取
-	if a.dbInit {
+	// 如果数据库（或内存存储）已初始化，从存储读取
+	if a.storeReady() {
 		logger.Debug("从数据库读取市场数据")
 		return a.getMarketDataFromDB(symbol, period)
 	}
 
+	// 配置的数据库连接失败时返回错误，由前端提示
+	if a.dbErr != nil {
+		return "", a.dbErr
+	}
+
 	// 数据库未初始化，返回空数组
 	logger.Warn("数据库未初始化，返回空数据。请先初始化数据库。")
 	emptyData := []models.KLineData{}
@@ -156,21 +257,12 @@ func (a *App) GetMarketData(symbol string, period string) (string, error) {
 
 // normalizeSymbol 规范化symbol格式，将 BTCUSDT 转换为 BTC_USDT
 func normalizeSymbol(symbol string) string {
-	// 如果已经包含下划线，直接返回
-	if strings.Contains(symbol, "_") {
+	normalized, err := utils.NormalizeSymbol(symbol)
+	if err != nil {
+		// 无法识别时返回原值
 		return symbol
 	}
-	// 尝试将 BTCUSDT 转换为 BTC_USDT
-	// 常见的USDT交易对
-	usdtPairs := []string{"USDT", "USDC", "BTC", "ETH", "BNB", "BUSD"}
-	for _, pair := range usdtPairs {
-		if strings.HasSuffix(symbol, pair) && len(symbol) > len(pair) {
-			base := symbol[:len(symbol)-len(pair)]
-			return base + "_" + pair
-		}
-	}
-	// 如果无法识别，返回原值
-	return symbol
+	return normalized
 }
 
 // getMarketDataFromDB 从数据库获取市场数据并聚合
@@ -183,21 +275,17 @@ func (a *App) getMarketDataFromDB(symbol string, period string) (string, error)
 	targetIntervalMin := utils.ParseIntervalToMinutes(period)
 	logger.Debugf("目标周期: %d 分钟", targetIntervalMin)
 
-	// 2. 计算需要多少根1分钟K线（假设需要最近1000根目标周期）
+	// 2. 读取最近1000根目标周期K线（优先使用聚合表，否则从1分钟K线聚合）
 	targetCount := 1000
-	needed1mCount := utils.CalculateNeeded1mCount(targetCount, targetIntervalMin)
-	logger.Debugf("需要 %d 根1分钟K线", needed1mCount)
-
-	// 3. 从数据库获取1分钟K线（使用规范化后的symbol）
-	klines1m, err := database.GetKLines1mByCount(normalizedSymbol, needed1mCount)
+	klines, err := utils.LoadKLines(normalizedSymbol, targetIntervalMin, targetCount)
 	if err != nil {
 		logger.Errorf("从数据库获取K线失败: symbol=%s, normalizedSymbol=%s, error=%v", symbol, normalizedSymbol, err)
 		return "", err
 	}
-	logger.Infof("从数据库获取到 %d 根1分钟K线: symbol=%s, normalizedSymbol=%s", len(klines1m), symbol, normalizedSymbol)
-	
+	logger.Infof("从数据库获取到 %d 根K线: symbol=%s, normalizedSymbol=%s, period=%s", len(klines), symbol, normalizedSymbol, period)
+
 	// 如果没有数据，记录警告并检查表是否存在
-	if len(klines1m) == 0 {
+	if len(klines) == 0 {
 		// 检查表是否存在
 		lastTime, err := database.GetLatestKLineTime(normalizedSymbol)
 		if err != nil {
@@ -213,22 +301,8 @@ func (a *App) getMarketDataFromDB(symbol string, period string) (string, error)
 		return string(jsonData), nil
 	}
 
-	// 4. 聚合为目标周期
-	klines := utils.AggregateKlines(klines1m, targetIntervalMin)
-	logger.Infof("聚合后得到 %d 根K线 (从 %d 根1分钟K线聚合)", len(klines), len(klines1m))
-
-	// 5. 转换为前端需要的格式
-	result := make([]models.KLineData, len(klines))
-	for i, k := range klines {
-		result[i] = models.KLineData{
-			Time:   k.OpenTime,
-			Open:   k.Open,
-			High:   k.High,
-			Low:    k.Low,
-			Close:  k.Close,
-			Volume: k.Volume,
-		}
-	}
+	// 3. 转换为前端需要的格式
+	result := toKLineData(klines)
 
 	jsonData, err := json.Marshal(result)
 	if err != nil {
@@ -240,34 +314,40 @@ func (a *App) getMarketDataFromDB(symbol string, period string) (string, error)
 	return string(jsonData), nil
 }
 
+// toKLineData 转换为前端需要的K线格式
+func toKLineData(klines []utils.KLine) []models.KLineData {
+	result := make([]models.KLineData, len(klines))
+	for i, k := range klines {
+		result[i] = models.KLineData{
+			Time:        k.OpenTime,
+			Open:        k.Open,
+			High:        k.High,
+			Low:         k.Low,
+			Close:       k.Close,
+			Volume:      k.Volume,
+			QuoteVolume: k.QuoteVolume,
+			TradeCount:  k.TradeCount,
+		}
+	}
+	return result
+}
+
 // GetIndicators 计算技术指标
 func (a *App) GetIndicators(symbol string, period string) (string, error) {
 	var klineData []models.KLineData
 
-	// 如果数据库已初始化，从数据库读取
-	if a.dbInit {
+	// 如果数据库（或内存存储）已初始化，从存储读取
+	if a.storeReady() {
 		// 规范化symbol格式
 		normalizedSymbol := normalizeSymbol(symbol)
 		targetIntervalMin := utils.ParseIntervalToMinutes(period)
 		targetCount := 1000
-		needed1mCount := utils.CalculateNeeded1mCount(targetCount, targetIntervalMin)
-		klines1m, err := database.GetKLines1mByCount(normalizedSymbol, needed1mCount)
+		klines, err := utils.LoadKLines(normalizedSymbol, targetIntervalMin, targetCount)
 		if err != nil {
 			logger.Errorf("从数据库获取K线失败: %v", err)
 			return "", err
 		}
-		klines := utils.AggregateKlines(klines1m, targetIntervalMin)
-		klineData = make([]models.KLineData, len(klines))
-		for i, k := range klines {
-			klineData[i] = models.KLineData{
-				Time:   k.OpenTime,
-				Open:   k.Open,
-				High:   k.High,
-				Low:    k.Low,
-				Close:  k.Close,
-				Volume: k.Volume,
-			}
-		}
+		klineData = toKLineData(klines)
 	} else {
 		// 数据库未初始化，返回空指标
 		logger.Warn("数据库未初始化，返回空指标。请先初始化数据库。")
@@ -286,30 +366,18 @@ func (a *App) GetIndicators(symbol string, period string) (string, error) {
 func (a *App) GetAlertSignals(symbol string, period string) (string, error) {
 	var klineData []models.KLineData
 
-	// 如果数据库已初始化，从数据库读取
-	if a.dbInit {
+	// 如果数据库（或内存存储）已初始化，从存储读取
+	if a.storeReady() {
 		// 规范化symbol格式
 		normalizedSymbol := normalizeSymbol(symbol)
 		targetIntervalMin := utils.ParseIntervalToMinutes(period)
 		targetCount := 1000
-		needed1mCount := utils.CalculateNeeded1mCount(targetCount, targetIntervalMin)
-		klines1m, err := database.GetKLines1mByCount(normalizedSymbol, needed1mCount)
+		klines, err := utils.LoadKLines(normalizedSymbol, targetIntervalMin, targetCount)
 		if err != nil {
 			logger.Errorf("从数据库获取K线失败: %v", err)
 			return "", err
 		}
-		klines := utils.AggregateKlines(klines1m, targetIntervalMin)
-		klineData = make([]models.KLineData, len(klines))
-		for i, k := range klines {
-			klineData[i] = models.KLineData{
-				Time:   k.OpenTime,
-				Open:   k.Open,
-				High:   k.High,
-				Low:    k.Low,
-				Close:  k.Close,
-				Volume: k.Volume,
-			}
-		}
+		klineData = toKLineData(klines)
 	} else {
 		// 数据库未初始化，返回空信号
 		logger.Warn("数据库未初始化，返回空信号。请先初始化数据库。")
@@ -338,7 +406,7 @@ func (a *App) StopMarketDataStream(symbol string) error {
 func (a *App) SyncKlineData(symbol string) (string, error) {
 	logger.Infof("开始同步K线数据: symbol=%s", symbol)
 
-	if !a.dbInit {
+	if !a.storeReady() {
 		logger.Warn("数据库未初始化，无法同步")
 		return "", fmt.Errorf("数据库未初始化")
 	}
@@ -356,7 +424,7 @@ func (a *App) SyncKlineData(symbol string) (string, error) {
 // SyncSymbolData 同步指定币种的数据（按周分批获取）
 // 从当日向前，先获取一周，再获取一周，按天检查状态
 func (a *App) SyncSymbolData(symbol string, weeks int) (string, error) {
-	if !a.dbInit {
+	if !a.storeReady() {
 		return "", fmt.Errorf("数据库未初始化")
 	}
 
@@ -379,7 +447,7 @@ func (a *App) SyncSymbolData(symbol string, weeks int) (string, error) {
 func (a *App) SyncKlineDataInitial(symbol string, days int) (string, error) {
 	logger.Infof("开始初始同步K线数据: symbol=%s, days=%d", symbol, days)
 
-	if !a.dbInit {
+	if !a.storeReady() {
 		logger.Warn("数据库未初始化，无法同步")
 		return "", fmt.Errorf("数据库未初始化")
 	}
@@ -406,10 +474,381 @@ func (a *App) InitDatabase(dsn string) (string, error) {
 	}
 
 	a.dbInit = true
+	a.memoryMode = false
+	a.dbErr = nil
 	logger.Info("数据库初始化成功")
 	return "数据库初始化成功", nil
 }
 
+// GetMigrationStatus 获取数据库迁移状态（JSON）
+func (a *App) GetMigrationStatus() (string, error) {
+	if !a.dbInit {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	report, err := database.GetMigrationStatus()
+	if err != nil {
+		return "", err
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// ScanDataIntegrity 扫描1分钟K线的数据完整性（JSON 报告）
+// symbol 为空时扫描配置中所有启用的币种；reopen 为 true 时把问题时间段从已同步记录中移除，由后续同步重新拉取
+func (a *App) ScanDataIntegrity(symbol string, reopen bool) (string, error) {
+	if !a.storeReady() {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	var reports []*database.IntegrityReport
+	if symbol == "" {
+		var err error
+		reports, err = database.ScanIntegrityFromConfig(0, 0)
+		if err != nil {
+			logger.Errorf("完整性扫描失败: %v", err)
+			return "", err
+		}
+	} else {
+		report, err := database.ScanIntegrity(normalizeSymbol(symbol), 0, 0)
+		if err != nil {
+			logger.Errorf("完整性扫描失败: symbol=%s, error=%v", symbol, err)
+			return "", err
+		}
+		reports = append(reports, report)
+	}
+
+	if reopen {
+		for _, report := range reports {
+			if _, err := database.RepairIntegrity(report, false); err != nil {
+				logger.Errorf("重新打开问题时间段失败: symbol=%s, error=%v", report.Symbol, err)
+				return "", err
+			}
+		}
+	}
+
+	jsonData, err := json.Marshal(reports)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// RebuildSyncTimeRanges 根据K线表中的实际数据重建已同步时间段（JSON 结果）
+// symbol 为空时重建配置中所有启用的币种
+func (a *App) RebuildSyncTimeRanges(symbol string) (string, error) {
+	if !a.storeReady() {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	var results []*database.SyncRangeRebuildResult
+	if symbol == "" {
+		var err error
+		results, err = database.RebuildSyncTimeRangesFromConfig()
+		if err != nil {
+			logger.Errorf("重建同步时间段失败: %v", err)
+			return "", err
+		}
+	} else {
+		result, err := database.RebuildSyncTimeRanges(normalizeSymbol(symbol))
+		if err != nil {
+			logger.Errorf("重建同步时间段失败: symbol=%s, error=%v", symbol, err)
+			return "", err
+		}
+		results = append(results, result)
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// RebuildAggregates 根据1分钟K线重建聚合K线表（5m/15m/1h/4h/1d）
+// symbol 为空时重建配置中所有启用的币种
+func (a *App) RebuildAggregates(symbol string) (string, error) {
+	if !a.dbInit {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+	if !database.SupportsAggregates() {
+		return "", database.ErrAggregateNotSupported
+	}
+
+	if symbol == "" {
+		count, err := database.RebuildAggregatesFromConfig()
+		if err != nil {
+			logger.Errorf("重建聚合K线失败: %v", err)
+			return "", err
+		}
+		return fmt.Sprintf("已重建 %d 个币种的聚合K线", count), nil
+	}
+
+	normalizedSymbol := normalizeSymbol(symbol)
+	if err := database.RebuildAggregates(normalizedSymbol); err != nil {
+		logger.Errorf("重建聚合K线失败: symbol=%s, error=%v", normalizedSymbol, err)
+		return "", err
+	}
+	return fmt.Sprintf("已重建 %s 的聚合K线", normalizedSymbol), nil
+}
+
+// exportRequest ExportKLines 的参数
+type exportRequest struct {
+	Symbol     string   `json:"symbol"`
+	Period     string   `json:"period"`    // 1m、5m、15m、1h、4h、1d，默认 1m
+	StartTime  int64    `json:"startTime"` // 毫秒时间戳，0 表示不限
+	EndTime    int64    `json:"endTime"`   // 毫秒时间戳，0 表示不限
+	Format     string   `json:"format"`    // csv、jsonl、parquet，默认根据文件扩展名判断
+	Columns    []string `json:"columns"`   // 默认 time, open, high, low, close, volume
+	Timezone   string   `json:"timezone"`  // time 列的时区，如 Asia/Shanghai，默认 UTC
+	TimeLayout string   `json:"timeLayout"`
+	Path       string   `json:"path"` // 为空时弹出保存文件对话框
+}
+
+// ExportKLines 把数据库中的K线导出为 CSV、JSON Lines 或 Parquet 文件
+// request 为 JSON 格式的 exportRequest，返回 JSON 格式的导出结果；用户取消保存对话框时返回空字符串
+func (a *App) ExportKLines(request string) (string, error) {
+	if !a.storeReady() {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	var req exportRequest
+	if err := json.Unmarshal([]byte(request), &req); err != nil {
+		return "", fmt.Errorf("解析导出参数失败: %w", err)
+	}
+	symbol := normalizeSymbol(req.Symbol)
+	if req.Period == "" {
+		req.Period = "1m"
+	}
+
+	path := req.Path
+	if path == "" {
+		format := req.Format
+		if format == "" {
+			format = export.FormatCSV
+		}
+		var err error
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "导出K线数据",
+			DefaultFilename: fmt.Sprintf("%s_%s.%s", symbol, req.Period, format),
+		})
+		if err != nil {
+			return "", fmt.Errorf("打开保存对话框失败: %w", err)
+		}
+		if path == "" {
+			return "", nil
+		}
+	}
+
+	result, err := export.ToFile(path, export.Options{
+		Symbol:     symbol,
+		Interval:   req.Period,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+		Format:     req.Format,
+		Columns:    req.Columns,
+		Timezone:   req.Timezone,
+		TimeLayout: req.TimeLayout,
+	})
+	if err != nil {
+		logger.Errorf("导出K线失败: symbol=%s, period=%s, error=%v", symbol, req.Period, err)
+		return "", err
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// ImportKLines 从 CSV / JSON 归档文件导入1分钟K线
+// symbol 为空时从文件名推断；path 为空时弹出文件选择对话框（可多选）。返回 JSON 格式的导入结果，用户取消时返回空字符串
+func (a *App) ImportKLines(path string, symbol string) (string, error) {
+	if !a.storeReady() {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	paths := []string{path}
+	if path == "" {
+		var err error
+		paths, err = runtime.OpenMultipleFilesDialog(a.ctx, runtime.OpenDialogOptions{
+			Title: "导入K线数据",
+			Filters: []runtime.FileFilter{
+				{DisplayName: "K线数据 (*.csv;*.json;*.jsonl)", Pattern: "*.csv;*.json;*.jsonl"},
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("打开文件对话框失败: %w", err)
+		}
+		if len(paths) == 0 {
+			return "", nil
+		}
+	}
+
+	opts := importer.Options{}
+	if symbol != "" {
+		opts.Symbol = normalizeSymbol(symbol)
+	}
+	results, err := importer.ImportFiles(paths, opts)
+	if err != nil {
+		logger.Errorf("导入K线失败: %v", err)
+		return "", err
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// PurgeExpiredKLines 按保留策略立即清理所有启用币种的过期1分钟K线（JSON 结果）
+func (a *App) PurgeExpiredKLines() (string, error) {
+	if !a.storeReady() {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	results, err := database.PurgeExpiredFromConfig(nil)
+	if err != nil {
+		logger.Errorf("清理过期K线失败: %v", err)
+		return "", err
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// GetSyncSchedulerStatus 获取同步任务调度器的状态（JSON：并发数、执行中和各优先级排队中的任务数）
+func (a *App) GetSyncSchedulerStatus() (string, error) {
+	jsonData, err := json.Marshal(datasync.GetSchedulerStats())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// GetFailedSyncRanges 获取同步失败、等待重试的时间段（JSON 数组，symbol 为空时返回所有币种）
+func (a *App) GetFailedSyncRanges(symbol string) (string, error) {
+	if !a.dbInit {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+	ranges, err := database.GetFailedRanges(symbol)
+	if err != nil {
+		return "", err
+	}
+	if ranges == nil {
+		ranges = []database.FailedRange{}
+	}
+	jsonData, err := json.Marshal(ranges)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// GetValidationReport 获取K线校验报告（JSON：按原因统计的隔离数量和隔离的K线，symbol 为空时汇总所有币种，时间为 0 时不限制）
+func (a *App) GetValidationReport(symbol string, startTime, endTime int64) (string, error) {
+	if !a.dbInit {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+	if symbol != "" {
+		symbol = normalizeSymbol(symbol)
+	}
+	report, err := database.GetValidationReport(symbol, startTime, endTime)
+	if err != nil {
+		return "", err
+	}
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// GetSymbolMetadata 获取交易对元数据（JSON：币种、价格和数量精度、最小下单量、上线时间、交易状态，没有元数据时返回 null）
+func (a *App) GetSymbolMetadata(symbol string) (string, error) {
+	meta, ok := database.LookupSymbolMeta(normalizeSymbol(symbol))
+	if !ok {
+		return "null", nil
+	}
+	jsonData, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// GetInactiveSymbols 获取停止同步的交易对（JSON 数组：交易对、状态 delisted/suspended/renamed、原因、发现时间）
+func (a *App) GetInactiveSymbols() (string, error) {
+	if !a.dbInit {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+	symbols, err := database.GetInactiveSymbols()
+	if err != nil {
+		return "", err
+	}
+	if symbols == nil {
+		symbols = []database.InactiveSymbol{}
+	}
+	jsonData, err := json.Marshal(symbols)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// ReactivateSymbol 恢复已停止同步的交易对（如交易所恢复交易后）
+func (a *App) ReactivateSymbol(symbol string) error {
+	if !a.dbInit {
+		return fmt.Errorf("数据库未初始化")
+	}
+	_, err := datasync.ReactivateSymbol(normalizeSymbol(symbol))
+	return err
+}
+
+// GetBackfillProgress 获取历史回填进度（JSON 数组：状态、下一个时间段、完成百分比、预计剩余秒数，symbol 为空时返回所有币种）
+func (a *App) GetBackfillProgress(symbol string) (string, error) {
+	if !a.dbInit {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+	if symbol != "" {
+		symbol = normalizeSymbol(symbol)
+	}
+	progress, err := datasync.GetBackfillProgress(symbol)
+	if err != nil {
+		return "", err
+	}
+	jsonData, err := json.Marshal(progress)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// PauseBackfill 暂停币种的历史回填
+func (a *App) PauseBackfill(symbol string) error {
+	if !a.dbInit {
+		return fmt.Errorf("数据库未初始化")
+	}
+	return datasync.PauseBackfill(normalizeSymbol(symbol))
+}
+
+// ResumeBackfill 恢复币种的历史回填（从保存的进度继续）
+func (a *App) ResumeBackfill(symbol string) error {
+	if !a.dbInit {
+		return fmt.Errorf("数据库未初始化")
+	}
+	return datasync.ResumeBackfill(normalizeSymbol(symbol))
+}
+
 // StartAutoSync 启动自动同步服务（使用优先级同步）
 func (a *App) StartAutoSync(symbol string, intervalSeconds int) (string, error) {
 	if !a.dbInit {
@@ -547,6 +986,71 @@ func (a *App) StartGapFillService() {
 	logger.Info("历史空缺补充服务已启动（每5分钟检查一次当天空缺）")
 }
 
+// StartRetentionService 启动数据保留服务（symbols.json 中 retention.enabled 为 true 时）
+func (a *App) StartRetentionService() {
+	if !a.dbInit {
+		logger.Warn("数据库未初始化，无法启动数据保留服务")
+		return
+	}
+
+	retention, err := config.GetRetentionConfig()
+	if err != nil {
+		logger.Errorf("获取保留策略失败: %v", err)
+		return
+	}
+	if !retention.Enabled {
+		logger.Debug("未启用数据保留策略，1分钟K线将永久保留")
+		return
+	}
+
+	// 如果服务已经在运行，直接返回
+	if a.retentionService != nil && a.retentionService.IsRunning() {
+		logger.Warn("数据保留服务已在运行")
+		return
+	}
+
+	retentionService := service.NewRetentionService(retention.PurgeIntervalMinutes)
+	retentionService.Start()
+	a.retentionService = retentionService
+	logger.Infof("数据保留服务已启动（热门币种保留 %d 天，小币种保留 %d 天，0 表示永久保留）",
+		retention.Hot.Keep1mDays, retention.Minor.Keep1mDays)
+}
+
+// StartStreamService 启动 WebSocket 实时K线服务（symbols.json 中 sync_config.stream_enabled 为 true 时）
+func (a *App) StartStreamService() {
+	if !a.dbInit {
+		logger.Warn("数据库未初始化，无法启动实时K线服务")
+		return
+	}
+
+	syncConfig, err := config.GetSyncConfig()
+	if err != nil {
+		logger.Errorf("获取同步配置失败: %v", err)
+		return
+	}
+	if !syncConfig.StreamEnabled {
+		logger.Debug("未启用 WebSocket 实时K线，实时价格由轮询获取")
+		return
+	}
+
+	// 如果服务已经在运行，直接返回
+	if a.streamService != nil && a.streamService.IsRunning() {
+		logger.Warn("实时K线服务已在运行")
+		return
+	}
+
+	// 创建EventEmitter函数
+	eventEmitter := func(event string, data ...interface{}) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, event, data...)
+		}
+	}
+
+	streamService := service.NewStreamService(eventEmitter)
+	streamService.Start()
+	a.streamService = streamService
+}
+
 // StartPrioritySync 启动优先级同步服务（从配置文件读取币种）（保留用于兼容）
 func (a *App) StartPrioritySync() (string, error) {
 	if !a.dbInit {
@@ -962,6 +1466,53 @@ func (a *App) LoadTestData(filename string) (string, error) {
 	return string(jsonData), nil
 }
 
+// SeedTestData 将测试数据文件写入当前K线存储（主要用于内存模式演示）
+// filename: 测试数据文件名（如 "test1.json"）
+// symbol: 写入的交易对（如 "BTC_USDT"）
+func (a *App) SeedTestData(filename string, symbol string) (string, error) {
+	if !a.storeReady() {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	dataStr, err := a.LoadTestData(filename)
+	if err != nil {
+		return "", err
+	}
+
+	var klines []models.KLineData
+	if err := json.Unmarshal([]byte(dataStr), &klines); err != nil {
+		return "", fmt.Errorf("解析 K 线数据失败: %w", err)
+	}
+
+	// 测试数据的时间戳不一定按分钟对齐，写入前对齐到分钟
+	normalizedSymbol := normalizeSymbol(symbol)
+	klines1m := make([]database.KLine1m, 0, len(klines))
+	for _, k := range klines {
+		openTime := k.Time / 60000 * 60000
+		klines1m = append(klines1m, database.KLine1m{
+			Symbol:      normalizedSymbol,
+			OpenTime:    openTime,
+			Open:        k.Open,
+			High:        k.High,
+			Low:         k.Low,
+			Close:       k.Close,
+			Volume:      k.Volume,
+			QuoteVolume: k.QuoteVolume,
+			TradeCount:  k.TradeCount,
+			CloseTime:   openTime + 60000 - 1,
+		})
+	}
+
+	result, err := database.SaveKLine1m(klines1m)
+	if err != nil {
+		return "", fmt.Errorf("写入测试数据失败: %w", err)
+	}
+
+	logger.Infof("测试数据已写入存储: file=%s, symbol=%s, 插入=%d, 跳过=%d",
+		filename, normalizedSymbol, result.InsertedCount, result.SkippedCount)
+	return fmt.Sprintf("已写入 %d 条K线（跳过 %d 条）", result.InsertedCount, result.SkippedCount), nil
+}
+
 // TestBollingerHammerAlert 使用测试数据测试布林带上轨+锤子形态预警
 // filename: 测试数据文件名
 func (a *App) TestBollingerHammerAlert(filename string) (string, error) {
@@ -1110,6 +1661,8 @@ func (a *App) AnalyzeTestData(filename string) (string, error) {
 				"low":         k.Low,
 				"close":       k.Close,
 				"volume":      k.Volume,
+				"quoteVolume": k.QuoteVolume,
+				"tradeCount":  k.TradeCount,
 				"body":        body,
 				"upperShadow": upperShadow,
 				"lowerShadow": lowerShadow,
@@ -0,0 +1,733 @@
+// klinectl 无界面的K线数据维护工具
+//
+// 用法:
+//
+//	klinectl migrate [-dsn DSN] [-status] [-dry-run]
+//	klinectl rebuild-aggregates [-dsn DSN] [-symbol BTC_USDT]
+//	klinectl scan [-dsn DSN] [-symbol BTC_USDT] [-start 2024-01-01] [-end 2024-12-31] [-reopen] [-delete-invalid] [-json]
+//	klinectl rebuild-ranges [-dsn DSN] [-symbol BTC_USDT]
+//	klinectl quarantine [-dsn DSN] [-symbol BTC_USDT] [-start 2024-01-01] [-end 2024-12-31] [-json]
+//	klinectl symbols [-dsn DSN] [-refresh] [-symbol BTC_USDT]
+//	klinectl purge [-dsn DSN] [-symbol BTC_USDT] [-keep-days 90]
+//	klinectl export -symbol BTC_USDT -out btc.parquet [-dsn DSN] [-period 1m] [-start 2024-01-01] [-end 2024-12-31] [-tz Asia/Shanghai] [-columns time,open,close] [-format csv|jsonl|parquet]
+//	klinectl import [-dsn DSN] [-symbol BTC_USDT] [-layout auto|gate|binance] [-dry-run] FILE...
+//	klinectl fetch -symbol BTC_USDT -start 2024-01-01 [-end 2024-01-02] [-exchange binance_futures] [-base-url http://127.0.0.1:8080] [-save] [-dsn DSN]
+//	klinectl bench-insert [-dsn DSN] [-rows 20000] [-chunk 500]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+	"wails-contract-warn/export"
+	"wails-contract-warn/importer"
+	"wails-contract-warn/logger"
+	datasync "wails-contract-warn/sync"
+	"wails-contract-warn/utils"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{name: "migrate", usage: "执行数据库迁移（-status 查看状态，-dry-run 只打印将要执行的语句）", run: runMigrate},
+	{name: "rebuild-aggregates", usage: "根据1分钟K线重建聚合K线表（5m/15m/1h/4h/1d）", run: runRebuildAggregates},
+	{name: "scan", usage: "扫描1分钟K线的数据完整性（缺失、重复、未对齐、OHLC 异常）", run: runScan},
+	{name: "rebuild-ranges", usage: "根据K线表中的实际数据重建 sync_time_ranges", run: runRebuildRanges},
+	{name: "quarantine", usage: "查看校验未通过、被隔离的K线（与参考交易所偏差过大或价格尖刺）", run: runQuarantine},
+	{name: "symbols", usage: "查看交易对元数据（精度、最小下单量、上线时间），-refresh 从交易所重新拉取", run: runSymbols},
+	{name: "purge", usage: "按保留策略清理过期的1分钟K线（聚合K线保留）", run: runPurge},
+	{name: "export", usage: "把K线导出为 CSV、JSON Lines 或 Parquet 文件", run: runExport},
+	{name: "import", usage: "从 CSV / JSON 归档文件导入1分钟K线", run: runImport},
+	{name: "fetch", usage: "通过交易所适配器拉取指定时间范围的1分钟K线（可指向本地回放服务调试）", run: runFetch},
+	{name: "bench-insert", usage: "对比逐行插入和多行批量插入的写入速度", run: runBenchInsert},
+}
+
+func main() {
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "info"
+	}
+	logger.Init(logLevel, os.Getenv("LOG_PRETTY") != "false")
+
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s 失败: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", os.Args[1])
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法: klinectl <命令> [参数]")
+	fmt.Fprintln(os.Stderr, "\n命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\n使用 klinectl <命令> -h 查看命令参数")
+}
+
+// openDB 连接数据库（默认使用 config.GetDBDSN，即 DB_DSN 环境变量）
+func openDB(dsn string) error {
+	if err := database.InitDB(dsn); err != nil {
+		return fmt.Errorf("数据库初始化失败: %w", err)
+	}
+	return nil
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	status := fs.Bool("status", false, "只查看迁移状态")
+	dryRun := fs.Bool("dry-run", false, "只打印将要执行的语句，不修改数据库")
+	fs.Parse(args)
+
+	// 不使用 InitDB，避免连接时自动执行迁移
+	if err := database.OpenDB(*dsn); err != nil {
+		return fmt.Errorf("数据库初始化失败: %w", err)
+	}
+	defer database.CloseDB()
+
+	var report *database.MigrationReport
+	var err error
+	if *status {
+		report, err = database.GetMigrationStatus()
+	} else {
+		report, err = database.Migrate(*dryRun)
+	}
+	if report != nil {
+		printMigrationReport(report)
+	}
+	return err
+}
+
+func printMigrationReport(report *database.MigrationReport) {
+	fmt.Printf("数据库: %s\n", report.Dialect)
+	fmt.Printf("当前版本: %d，最新版本: %d\n", report.CurrentVersion, report.LatestVersion)
+	fmt.Printf("K线表: %d 张\n", len(report.KLineTables))
+
+	fmt.Println("\n迁移:")
+	for _, m := range report.Migrations {
+		state := "未执行"
+		if m.Applied {
+			state = "已执行 " + time.UnixMilli(m.AppliedAt).Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %04d %-30s %s\n", m.Version, m.Name, state)
+	}
+
+	if len(report.Statements) > 0 {
+		if report.DryRun {
+			fmt.Println("\n将要执行的语句 (dry-run):")
+		} else {
+			fmt.Println("\n已执行的语句:")
+		}
+		for _, stmt := range report.Statements {
+			fmt.Printf("  %s;\n", stmt)
+		}
+	}
+}
+
+func runRebuildAggregates(args []string) error {
+	fs := flag.NewFlagSet("rebuild-aggregates", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT（为空时处理配置中所有启用的币种）")
+	fs.Parse(args)
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	if *symbol != "" {
+		return database.RebuildAggregates(*symbol)
+	}
+
+	count, err := database.RebuildAggregatesFromConfig()
+	if err != nil {
+		return err
+	}
+	logger.Infof("已重建 %d 个币种的聚合K线", count)
+	return nil
+}
+
+func runRebuildRanges(args []string) error {
+	fs := flag.NewFlagSet("rebuild-ranges", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT（为空时处理配置中所有启用的币种）")
+	fs.Parse(args)
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	var results []*database.SyncRangeRebuildResult
+	if *symbol != "" {
+		result, err := database.RebuildSyncTimeRanges(*symbol)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	} else {
+		var err error
+		if results, err = database.RebuildSyncTimeRangesFromConfig(); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range results {
+		fmt.Printf("%-12s 时间段: %d -> %d，覆盖 %d 分钟\n", r.Symbol, r.RangesBefore, r.RangesAfter, r.CoveredMinutes)
+	}
+	return nil
+}
+
+func runPurge(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT（为空时处理配置中所有启用的币种）")
+	keepDays := fs.Int("keep-days", 0, "保留最近N天的1分钟K线（为 0 时使用 symbols.json 中的保留策略）")
+	fs.Parse(args)
+
+	retention, err := config.GetRetentionConfig()
+	if err != nil {
+		return err
+	}
+	if *keepDays == 0 && !retention.Enabled {
+		return fmt.Errorf("symbols.json 中未启用保留策略，请通过 -keep-days 指定保留天数")
+	}
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	var symbols []string
+	if *symbol != "" {
+		symbols = append(symbols, *symbol)
+	} else {
+		allSymbols, err := config.GetAllEnabledSymbols()
+		if err != nil {
+			return err
+		}
+		for _, s := range allSymbols {
+			symbols = append(symbols, s.Symbol)
+		}
+	}
+
+	opts := database.PurgeOptions{
+		BatchMinutes: retention.PurgeBatchMinutes,
+		Pause:        time.Duration(retention.PurgePauseMs) * time.Millisecond,
+	}
+	for _, s := range symbols {
+		days := *keepDays
+		if days == 0 {
+			if days, err = config.GetKeep1mDays(s); err != nil {
+				return err
+			}
+		}
+		cutoff := database.RetentionCutoff(days, time.Now())
+		if cutoff == 0 {
+			fmt.Printf("%-12s 永久保留，跳过\n", s)
+			continue
+		}
+
+		result, err := database.PurgeKLines1mBefore(s, cutoff, opts)
+		if err != nil {
+			return fmt.Errorf("清理失败 (币种: %s): %w", s, err)
+		}
+		fmt.Printf("%-12s 早于 %s: 删除 %d 条，批次 %d\n", s,
+			time.UnixMilli(cutoff).UTC().Format("2006-01-02"), result.DeletedRows, result.Batches)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT")
+	period := fs.String("period", "1m", "周期: 1m、5m、15m、1h、4h、1d")
+	start := fs.String("start", "", "开始日期（按 -tz 时区解析），如 2024-01-01")
+	end := fs.String("end", "", "结束日期（包含当天，按 -tz 时区解析），如 2024-12-31")
+	out := fs.String("out", "", "输出文件路径（扩展名为 .csv、.jsonl、.parquet 时自动识别格式）")
+	format := fs.String("format", "", "导出格式: csv、jsonl、parquet（默认根据 -out 的扩展名判断）")
+	tz := fs.String("tz", "UTC", "time 列使用的时区，如 Asia/Shanghai")
+	columns := fs.String("columns", strings.Join(export.DefaultColumns, ","), "导出的列（逗号分隔），可选: "+strings.Join(export.AllColumns, ","))
+	fs.Parse(args)
+
+	if *symbol == "" || *out == "" {
+		return fmt.Errorf("必须指定 -symbol 和 -out")
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("无效的时区 %s: %w", *tz, err)
+	}
+	startTime, err := parseDateIn(*start, loc)
+	if err != nil {
+		return err
+	}
+	endTime, err := parseDateIn(*end, loc)
+	if err != nil {
+		return err
+	}
+	if endTime > 0 {
+		endTime += 24*60*60*1000 - 1
+	}
+	cols, err := export.ParseColumns(*columns)
+	if err != nil {
+		return err
+	}
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	result, err := export.ToFile(*out, export.Options{
+		Symbol:    *symbol,
+		Interval:  *period,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Format:    *format,
+		Columns:   cols,
+		Timezone:  *tz,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已导出 %d 行到 %s（格式: %s，%d 字节）\n", result.Rows, result.Path, result.Format, result.Bytes)
+	if result.Rows > 0 {
+		fmt.Printf("时间范围: %s ~ %s\n",
+			time.UnixMilli(result.StartTime).In(loc).Format("2006-01-02 15:04"),
+			time.UnixMilli(result.EndTime).In(loc).Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT（默认从文件名推断，如 BTCUSDT-1m-2024-01.csv）")
+	layout := fs.String("layout", importer.LayoutAuto, "无表头 CSV 和 JSON 二维数组的列布局: auto、gate、binance")
+	dryRun := fs.Bool("dry-run", false, "只解析和校验，不写入数据库")
+	fs.Parse(args)
+
+	// 支持通配符（Windows 的命令行不会展开）
+	var paths []string
+	for _, pattern := range fs.Args() {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("无效的文件路径 %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("文件不存在: %s", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("必须指定要导入的文件")
+	}
+
+	if !*dryRun {
+		if err := openDB(*dsn); err != nil {
+			return err
+		}
+		defer database.CloseDB()
+	}
+
+	results, err := importer.ImportFiles(paths, importer.Options{Symbol: *symbol, Layout: *layout, DryRun: *dryRun})
+	for _, r := range results {
+		fmt.Printf("%s: 币种=%s, 格式=%s, 行数=%d, 插入=%d, 跳过=%d, 无效=%d, 重复=%d, 时间段=%d\n",
+			r.File, r.Symbol, r.Format, r.TotalRows, r.InsertedCount, r.SkippedCount, r.InvalidRows, r.DuplicateRows, r.Ranges)
+		if r.EndTime > 0 {
+			fmt.Printf("  时间范围: %s ~ %s\n",
+				time.UnixMilli(r.StartTime).UTC().Format("2006-01-02 15:04"),
+				time.UnixMilli(r.EndTime).UTC().Format("2006-01-02 15:04"))
+		}
+		for _, e := range r.Errors {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	return err
+}
+
+func runFetch(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db，仅 -save 时使用）")
+	exchange := fs.String("exchange", "", "交易所: "+strings.Join(datasync.AdapterNames(), "、")+"（默认使用币种配置的交易所）")
+	baseURL := fs.String("base-url", "", "接口地址（默认使用配置或官方地址）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT")
+	start := fs.String("start", "", "开始日期（UTC），如 2024-01-01")
+	end := fs.String("end", "", "结束日期（包含当天，UTC），默认与 -start 相同")
+	save := fs.Bool("save", false, "保存到数据库并记录已同步时间段")
+	fs.Parse(args)
+
+	if *symbol == "" || *start == "" {
+		return fmt.Errorf("必须指定 -symbol 和 -start")
+	}
+	if *end == "" {
+		*end = *start
+	}
+	startTime, err := parseDate(*start)
+	if err != nil {
+		return err
+	}
+	endTime, err := parseDate(*end)
+	if err != nil {
+		return err
+	}
+	endTime += 24*60*60*1000 - 1
+
+	name := *exchange
+	if name == "" {
+		name = config.GetSymbolExchange(*symbol)
+	}
+	adapter, err := datasync.NewAdapter(name, *baseURL)
+	if err != nil {
+		return err
+	}
+
+	klines, err := datasync.FetchRange(adapter, api.NewProxyClient(), *symbol, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s: 拉取 %d 根K线\n", adapter.Name(), *symbol, len(klines))
+	if len(klines) > 0 {
+		first, last := klines[0], klines[len(klines)-1]
+		fmt.Printf("时间范围: %s ~ %s\n",
+			time.UnixMilli(first.OpenTime).UTC().Format("2006-01-02 15:04"),
+			time.UnixMilli(last.OpenTime).UTC().Format("2006-01-02 15:04"))
+		fmt.Printf("首根: open=%v high=%v low=%v close=%v volume=%v quote_volume=%v trades=%v\n", first.Open, first.High, first.Low, first.Close, first.Volume, first.QuoteVolume, first.TradeCount)
+		fmt.Printf("末根: open=%v high=%v low=%v close=%v volume=%v quote_volume=%v trades=%v\n", last.Open, last.High, last.Low, last.Close, last.Volume, last.QuoteVolume, last.TradeCount)
+	}
+	if !*save || len(klines) == 0 {
+		return nil
+	}
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+	result, err := database.SaveKLine1m(klines)
+	if err != nil {
+		return err
+	}
+	// 只记录到最后一根K线为止（结束日期可能包含尚未到来的时间）
+	if err := database.AddSyncTimeRange(*symbol, startTime, min(endTime, klines[len(klines)-1].CloseTime)); err != nil {
+		return err
+	}
+	fmt.Printf("已保存: 插入=%d, 跳过=%d, 失败=%d\n", result.InsertedCount, result.SkippedCount, result.ErrorCount)
+	return nil
+}
+
+// parseDate 解析 2006-01-02 格式的日期（UTC），为空时返回 0
+func parseDate(value string) (int64, error) {
+	return parseDateIn(value, time.UTC)
+}
+
+// parseDateIn 按指定时区解析 2006-01-02 格式的日期，返回当天零点的毫秒时间戳（空字符串返回 0）
+func parseDateIn(value string, loc *time.Location) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return 0, fmt.Errorf("日期格式错误（应为 2006-01-02）: %s", value)
+	}
+	return t.UnixMilli(), nil
+}
+
+func runScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT（为空时扫描配置中所有启用的币种）")
+	start := fs.String("start", "", "开始日期（UTC），如 2024-01-01")
+	end := fs.String("end", "", "结束日期（UTC，包含当天），如 2024-12-31")
+	reopen := fs.Bool("reopen", false, "把问题时间段从已同步记录中移除，下次同步时重新拉取")
+	deleteInvalid := fs.Bool("delete-invalid", false, "配合 -reopen 使用，同时删除问题K线")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出报告")
+	fs.Parse(args)
+
+	startTime, err := parseDate(*start)
+	if err != nil {
+		return err
+	}
+	endTime, err := parseDate(*end)
+	if err != nil {
+		return err
+	}
+	if endTime > 0 {
+		endTime += 24*60*60*1000 - 1
+	}
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	var reports []*database.IntegrityReport
+	if *symbol != "" {
+		report, err := database.ScanIntegrity(*symbol, startTime, endTime)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	} else {
+		reports, err = database.ScanIntegrityFromConfig(startTime, endTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, report := range reports {
+			printIntegrityReport(report)
+		}
+	}
+
+	if *reopen {
+		for _, report := range reports {
+			result, err := database.RepairIntegrity(report, *deleteInvalid)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "[%s] 重新打开 %d 个时间段，删除 %d 条问题K线\n",
+				report.Symbol, result.ReopenedRanges, result.DeletedRows)
+		}
+	}
+	return nil
+}
+
+func printIntegrityReport(report *database.IntegrityReport) {
+	format := func(ms int64) string {
+		return time.UnixMilli(ms).UTC().Format("2006-01-02 15:04")
+	}
+
+	fmt.Printf("== %s (%s)\n", report.Symbol, report.TableName)
+	if report.TotalRows == 0 {
+		fmt.Println("  无数据")
+		return
+	}
+	fmt.Printf("  范围: %s ~ %s UTC，行数: %d\n", format(report.StartTime), format(report.EndTime), report.TotalRows)
+	fmt.Printf("  缺失: %d 分钟，%d 个时间段（其中 %d 分钟被记录为已同步）\n",
+		report.MissingMinutes, len(report.MissingRanges), report.MissingInSyncedRanges)
+	for _, issueType := range []string{database.IssueDuplicate, database.IssueMisaligned, database.IssueOHLC, database.IssueInvalidPrice} {
+		if count := report.IssueCounts[issueType]; count > 0 {
+			fmt.Printf("  %s: %d\n", issueType, count)
+		}
+	}
+	if report.Healthy() {
+		fmt.Println("  ✓ 未发现问题")
+		return
+	}
+
+	const maxPrinted = 20
+	for i, r := range report.MissingRanges {
+		if i == maxPrinted {
+			fmt.Printf("  ... 共 %d 个缺失时间段\n", len(report.MissingRanges))
+			break
+		}
+		fmt.Printf("  缺失 %s ~ %s\n", format(r.StartTime), format(r.EndTime))
+	}
+	for i, issue := range report.Issues {
+		if i == maxPrinted {
+			fmt.Printf("  ... 共 %d 条问题明细\n", len(report.Issues))
+			break
+		}
+		fmt.Printf("  %s %s: %s\n", format(issue.OpenTime), issue.Type, issue.Detail)
+	}
+}
+
+func runQuarantine(args []string) error {
+	fs := flag.NewFlagSet("quarantine", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	symbol := fs.String("symbol", "", "币种，如 BTC_USDT（为空时汇总所有币种）")
+	start := fs.String("start", "", "开始日期（UTC），如 2024-01-01")
+	end := fs.String("end", "", "结束日期（UTC，包含当天），如 2024-12-31")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出报告")
+	fs.Parse(args)
+
+	startTime, err := parseDate(*start)
+	if err != nil {
+		return err
+	}
+	endTime, err := parseDate(*end)
+	if err != nil {
+		return err
+	}
+	if endTime > 0 {
+		endTime += 24*60*60*1000 - 1
+	}
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	report, err := database.GetValidationReport(*symbol, startTime, endTime)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("隔离的K线: %d", report.Total)
+	for _, reason := range []string{database.QuarantineCrossExchange, database.QuarantineSpike} {
+		if count := report.ReasonCounts[reason]; count > 0 {
+			fmt.Printf("，%s: %d", reason, count)
+		}
+	}
+	fmt.Println()
+	if report.Total == 0 {
+		fmt.Println("  ✓ 未发现可疑K线")
+		return nil
+	}
+	fmt.Printf("最大偏差: %.0f bps\n", report.MaxDeviation)
+	for _, r := range report.Rows {
+		fmt.Printf("  %s %s %-14s 收盘价=%.8g 参考(%s)=%.8g 偏差=%.0fbps\n",
+			r.Symbol, time.UnixMilli(r.OpenTime).UTC().Format("2006-01-02 15:04"), r.Reason,
+			r.Close, r.Reference, r.ReferencePrice, r.DeviationBps)
+	}
+	return nil
+}
+
+func runSymbols(args []string) error {
+	fs := flag.NewFlagSet("symbols", flag.ExitOnError)
+	dsn := fs.String("dsn", config.GetDBDSN(), "数据库DSN（MySQL DSN 或 sqlite:///path/to/file.db）")
+	refresh := fs.Bool("refresh", false, "从交易所的交易对接口重新拉取元数据")
+	symbol := fs.String("symbol", "", "只显示指定交易对，如 BTC_USDT（也可以写 BTCUSDT）")
+	fs.Parse(args)
+
+	if err := openDB(*dsn); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	count, err := database.LoadSymbolRegistry()
+	if err != nil {
+		return err
+	}
+	if *refresh {
+		if count, err = datasync.RefreshSymbolRegistry(); err != nil {
+			return err
+		}
+	}
+	updatedAt := "-"
+	if ts := database.SymbolRegistryUpdatedAt(); ts > 0 {
+		updatedAt = time.UnixMilli(ts).UTC().Format("2006-01-02 15:04")
+	}
+	fmt.Printf("交易对元数据: %d 个，更新时间: %s\n", count, updatedAt)
+	if *symbol == "" {
+		return nil
+	}
+
+	normalized, err := utils.NormalizeSymbol(*symbol)
+	if err != nil {
+		return err
+	}
+	meta, ok := database.LookupSymbolMeta(normalized)
+	if !ok {
+		return fmt.Errorf("没有 %s 的元数据", normalized)
+	}
+	listedAt := "未知"
+	if meta.ListedAt > 0 {
+		listedAt = time.UnixMilli(meta.ListedAt).UTC().Format("2006-01-02 15:04")
+	}
+	fmt.Printf("  %s（%s/%s）价格精度=%d 数量精度=%d 最小数量=%g 最小金额=%g 上线时间=%s 状态=%s\n",
+		meta.Symbol, meta.Base, meta.Quote, meta.PricePrecision, meta.AmountPrecision,
+		meta.MinBaseAmount, meta.MinQuoteAmount, listedAt, meta.TradeStatus)
+	return nil
+}
+
+func runBenchInsert(args []string) error {
+	fs := flag.NewFlagSet("bench-insert", flag.ExitOnError)
+	dsn := fs.String("dsn", "", "数据库DSN（默认使用临时 SQLite 文件；测试远程 MySQL 时传入其 DSN）")
+	rows := fs.Int("rows", 20000, "每轮写入的K线数量")
+	chunk := fs.Int("chunk", database.DefaultInsertChunkSize, "批量插入时每条语句包含的K线数量")
+	fs.Parse(args)
+
+	if *dsn == "" {
+		dir, err := os.MkdirTemp("", "klinectl-bench")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		*dsn = database.SQLiteDSNPrefix + dir + "/bench.db"
+	}
+
+	if err := database.OpenDB(*dsn); err != nil {
+		return fmt.Errorf("数据库初始化失败: %w", err)
+	}
+	defer database.CloseDB()
+
+	// 每轮使用独立的临时表，结束后删除
+	base := time.Now().Truncate(time.Minute).UnixMilli() - int64(*rows)*60000
+	round := func(name string, chunkSize int) (time.Duration, error) {
+		symbol := fmt.Sprintf("BENCH_%s_%d", name, time.Now().UnixNano())
+		defer database.DB.Exec("DROP TABLE IF EXISTS " + database.GetTableName(symbol))
+
+		klines := make([]database.KLine1m, *rows)
+		for i := range klines {
+			openTime := base + int64(i)*60000
+			price := 100 + float64(i%100)/10
+			klines[i] = database.KLine1m{Symbol: symbol, OpenTime: openTime, Open: price, High: price + 1, Low: price - 1, Close: price + 0.5, Volume: 1, CloseTime: openTime + 59999}
+		}
+
+		database.SetInsertChunkSize(chunkSize)
+		started := time.Now()
+		result, err := database.GetStore().SaveKLine1m(klines)
+		elapsed := time.Since(started)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Printf("%-8s chunk=%-5d 插入=%d 跳过=%d 失败=%d 耗时=%s (%.0f 行/秒)\n",
+			name, chunkSize, result.InsertedCount, result.SkippedCount, result.ErrorCount,
+			elapsed.Round(time.Millisecond), float64(*rows)/elapsed.Seconds())
+		return elapsed, nil
+	}
+
+	single, err := round("single", 1)
+	if err != nil {
+		return err
+	}
+	bulk, err := round("bulk", *chunk)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("批量插入速度提升: %.1fx\n", single.Seconds()/bulk.Seconds())
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 )
 
 // GetDBDSN 从环境变量或配置文件获取数据库DSN
+// 支持 MySQL DSN 和 sqlite:///path/to/file.db（本地 SQLite 文件）
 func GetDBDSN() string {
 	// 优先从环境变量读取
 	if dsn := os.Getenv("DB_DSN"); dsn != "" {
@@ -1,156 +1,431 @@
-package config
-
-import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"sort"
-)
-
-// SymbolConfig 币种配置
-type SymbolConfig struct {
-	Symbol      string `json:"symbol"`
-	Priority    int    `json:"priority"`
-	Enabled     bool   `json:"enabled"`
-	Description string `json:"description"`
-}
-
-// SyncConfig 同步配置
-type SyncConfig struct {
-	PriorityRecentDays       int  `json:"priority_recent_days"`        // 优先同步最近N天的数据
-	HistoricalStartYear      int  `json:"historical_start_year"`       // 历史数据起始年份
-	BatchSize                int  `json:"batch_size"`                  // 每批拉取数量
-	RequestIntervalMs        int  `json:"request_interval_ms"`         // 请求间隔（毫秒）
-	IdleSyncEnabled          bool `json:"idle_sync_enabled"`           // 是否启用空闲同步
-	IdleCheckIntervalSeconds int  `json:"idle_check_interval_seconds"` // 空闲检查间隔（秒）
-}
-
-// SymbolsConfig 币种配置文件结构
-type SymbolsConfig struct {
-	HotSymbols   []SymbolConfig `json:"hot_symbols"`
-	MinorSymbols []SymbolConfig `json:"minor_symbols"`
-	SyncConfig   SyncConfig     `json:"sync_config"`
-}
-
-var symbolsConfig *SymbolsConfig
-
-// LoadSymbolsConfig 加载币种配置
-func LoadSymbolsConfig() (*SymbolsConfig, error) {
-	if symbolsConfig != nil {
-		return symbolsConfig, nil
-	}
-
-	// 默认配置文件路径
-	configPath := "config/symbols.json"
-	if path := os.Getenv("SYMBOLS_CONFIG_PATH"); path != "" {
-		configPath = path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取配置文件失败: %w", err)
-	}
-
-	var config SymbolsConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("解析配置文件失败: %w", err)
-	}
-
-	// 设置默认值
-	if config.SyncConfig.BatchSize == 0 {
-		config.SyncConfig.BatchSize = 1000
-	}
-	if config.SyncConfig.RequestIntervalMs == 0 {
-		config.SyncConfig.RequestIntervalMs = 200
-	}
-	if config.SyncConfig.PriorityRecentDays == 0 {
-		config.SyncConfig.PriorityRecentDays = 1
-	}
-	if config.SyncConfig.HistoricalStartYear == 0 {
-		config.SyncConfig.HistoricalStartYear = 2020
-	}
-	if config.SyncConfig.IdleCheckIntervalSeconds == 0 {
-		config.SyncConfig.IdleCheckIntervalSeconds = 60
-	}
-
-	symbolsConfig = &config
-	return symbolsConfig, nil
-}
-
-// GetAllEnabledSymbols 获取所有启用的币种（按优先级排序）
-func GetAllEnabledSymbols() ([]SymbolConfig, error) {
-	config, err := LoadSymbolsConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	var allSymbols []SymbolConfig
-	allSymbols = append(allSymbols, config.HotSymbols...)
-	allSymbols = append(allSymbols, config.MinorSymbols...)
-
-	// 过滤启用的币种
-	var enabled []SymbolConfig
-	for _, s := range allSymbols {
-		if s.Enabled {
-			enabled = append(enabled, s)
-		}
-	}
-
-	// 按优先级排序
-	sort.Slice(enabled, func(i, j int) bool {
-		return enabled[i].Priority < enabled[j].Priority
-	})
-
-	return enabled, nil
-}
-
-// GetHotSymbols 获取热门币种（按优先级排序）
-func GetHotSymbols() ([]SymbolConfig, error) {
-	config, err := LoadSymbolsConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	var enabled []SymbolConfig
-	for _, s := range config.HotSymbols {
-		if s.Enabled {
-			enabled = append(enabled, s)
-		}
-	}
-
-	sort.Slice(enabled, func(i, j int) bool {
-		return enabled[i].Priority < enabled[j].Priority
-	})
-
-	return enabled, nil
-}
-
-// GetMinorSymbols 获取小币种（按优先级排序）
-func GetMinorSymbols() ([]SymbolConfig, error) {
-	config, err := LoadSymbolsConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	var enabled []SymbolConfig
-	for _, s := range config.MinorSymbols {
-		if s.Enabled {
-			enabled = append(enabled, s)
-		}
-	}
-
-	sort.Slice(enabled, func(i, j int) bool {
-		return enabled[i].Priority < enabled[j].Priority
-	})
-
-	return enabled, nil
-}
-
-// GetSyncConfig 获取同步配置
-func GetSyncConfig() (*SyncConfig, error) {
-	config, err := LoadSymbolsConfig()
-	if err != nil {
-		return nil, err
-	}
-	return &config.SyncConfig, nil
-}
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SymbolConfig 币种配置
+type SymbolConfig struct {
+	Symbol      string `json:"symbol"`
+	Priority    int    `json:"priority"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description"`
+	Exchange    string `json:"exchange,omitempty"`    // K线数据来源的交易所（默认 gateio）
+	MarketType  string `json:"market_type,omitempty"` // 市场类型: spot（现货，默认）、usdt_futures（USDT 永续合约）
+	// ReferenceExchange 交叉校验使用的参考交易所（适配器名称，如 binance_futures），为空时不隔离K线（尖刺只记录日志）
+	ReferenceExchange string `json:"reference_exchange,omitempty"`
+}
+
+// 币种的市场类型
+const (
+	MarketSpot        = "spot"
+	MarketUSDTFutures = "usdt_futures"
+)
+
+// FuturesSymbolSuffix 合约币种的存储后缀
+// BTC_USDT 合约的K线和同步记录存储在 BTC_USDT_PERP 下（klines_1m_BTC_USDT_PERP 等表），与现货分开
+const FuturesSymbolSuffix = "_PERP"
+
+// MarketSymbol 币种在指定市场的存储名（现货为原名，合约加 FuturesSymbolSuffix）
+func MarketSymbol(symbol, marketType string) string {
+	if marketType == MarketUSDTFutures && !strings.HasSuffix(symbol, FuturesSymbolSuffix) {
+		return symbol + FuturesSymbolSuffix
+	}
+	return symbol
+}
+
+// SplitMarketSymbol 从存储名拆出交易所的交易对和市场类型（BTC_USDT_PERP → BTC_USDT, usdt_futures）
+func SplitMarketSymbol(symbol string) (pair, marketType string) {
+	if strings.HasSuffix(symbol, FuturesSymbolSuffix) {
+		return strings.TrimSuffix(symbol, FuturesSymbolSuffix), MarketUSDTFutures
+	}
+	return symbol, MarketSpot
+}
+
+// applyMarketType 校验市场类型，合约币种的 Symbol 改为带后缀的存储名
+// 之后按 Symbol 建表、查找同步记录、清理数据的代码都无需区分市场
+func (s *SymbolConfig) applyMarketType() error {
+	switch s.MarketType {
+	case "", MarketSpot:
+		s.MarketType = MarketSpot
+	case MarketUSDTFutures:
+		s.Symbol = MarketSymbol(s.Symbol, s.MarketType)
+	default:
+		return fmt.Errorf("币种 %s 的市场类型无效: %s（可选: %s、%s）", s.Symbol, s.MarketType, MarketSpot, MarketUSDTFutures)
+	}
+	return nil
+}
+
+// DefaultExchange 未配置 exchange 的币种使用的交易所
+const DefaultExchange = "gateio"
+
+// DefaultUpsertRecentMinutes 未配置 upsert_recent_minutes 时使用 upsert 保存的最近分钟数
+const DefaultUpsertRecentMinutes = 5
+
+// SyncConfig 同步配置
+type SyncConfig struct {
+	PriorityRecentDays       int  `json:"priority_recent_days"`        // 优先同步最近N天的数据
+	HistoricalStartYear      int  `json:"historical_start_year"`       // 历史数据起始年份
+	BatchSize                int  `json:"batch_size"`                  // 每批拉取数量
+	RequestIntervalMs        int  `json:"request_interval_ms"`         // 请求间隔（毫秒）
+	IdleSyncEnabled          bool `json:"idle_sync_enabled"`           // 是否启用空闲同步
+	IdleCheckIntervalSeconds int  `json:"idle_check_interval_seconds"` // 空闲检查间隔（秒）
+	InsertChunkSize          int  `json:"insert_chunk_size"`           // 每条多行 INSERT 语句包含的K线数量
+	UpsertRecentMinutes      int  `json:"upsert_recent_minutes"`       // 最近N分钟的K线使用 upsert 覆盖（修正未收盘数据），0 表示关闭，未配置时为 DefaultUpsertRecentMinutes
+	RebuildRangesOnStartup   bool `json:"rebuild_ranges_on_startup"`   // 启动时根据实际数据重建 sync_time_ranges
+	StreamEnabled            bool `json:"stream_enabled"`              // 是否通过 WebSocket 实时接收K线（启用后实时价格服务不再轮询已订阅的币种）
+	MaxConcurrentSyncs       int  `json:"max_concurrent_syncs"`        // 同步任务调度器的并发数（各交易所的请求频率仍受限流控制）
+	RetryMaxAttempts         int  `json:"retry_max_attempts"`          // 每页请求遇到限流或网络错误时的最多尝试次数
+}
+
+// RetentionTier 单个币种分组（热门币种、小币种）的数据保留策略
+type RetentionTier struct {
+	Keep1mDays int `json:"keep_1m_days"` // 1分钟K线保留天数（0 表示永久保留）
+}
+
+// RetentionConfig 数据保留策略（聚合K线永久保留，只清理过期的1分钟K线）
+type RetentionConfig struct {
+	Enabled              bool          `json:"enabled"`                // 是否启用定期清理
+	Hot                  RetentionTier `json:"hot"`                    // 热门币种
+	Minor                RetentionTier `json:"minor"`                  // 小币种
+	PurgeIntervalMinutes int           `json:"purge_interval_minutes"` // 清理检查间隔（分钟）
+	PurgeBatchMinutes    int           `json:"purge_batch_minutes"`    // 每批删除的时间跨度（分钟，即每批最多删除的K线数量）
+	PurgePauseMs         int           `json:"purge_pause_ms"`         // 每批之间的停顿（毫秒），避免长时间占用数据库
+}
+
+// ValidationConfig K线校验配置（保存前检查可疑的K线，可疑的K线写入隔离表而不保存）
+type ValidationConfig struct {
+	Enabled         bool    `json:"enabled"`           // 是否启用校验
+	MaxDeviationBps float64 `json:"max_deviation_bps"` // 与参考交易所同一分钟收盘价的最大偏差（基点）
+	SpikeBps        float64 `json:"spike_bps"`         // 开盘价、收盘价相对前后K线收盘价中位数的最大偏差（基点）
+	SpikeWindow     int     `json:"spike_window"`      // 尖刺检测时前后各取的K线数量
+}
+
+// ExchangeConfig 交易所接口配置
+type ExchangeConfig struct {
+	BaseURL string `json:"base_url"`         // 接口地址，为空时使用官方地址（可指向本地的回放服务用于调试）
+	WSURL   string `json:"ws_url,omitempty"` // WebSocket 地址，为空时使用官方地址
+}
+
+// NetworkConfig 访问交易所接口的网络配置（代理、DNS、证书和超时），对所有 HTTP 请求和 WebSocket 连接生效
+// 用于网络受限的环境：DNS 被污染时配置 dns_servers、doh_url 或 hosts，需要翻墙时配置 proxy
+type NetworkConfig struct {
+	Proxy                      string            `json:"proxy"`                         // 代理地址（http://、https://、socks5://，可带用户名密码），为空时使用 HTTP_PROXY/HTTPS_PROXY 环境变量
+	DNSServers                 []string          `json:"dns_servers"`                   // 自定义 DNS 服务器（如 8.8.8.8、1.1.1.1:53），为空时使用系统 DNS
+	DoHURL                     string            `json:"doh_url"`                       // DNS over HTTPS 地址（如 https://1.1.1.1/dns-query），配置后优先于 dns_servers
+	Hosts                      map[string]string `json:"hosts"`                         // 域名 → IP，优先于 DNS 解析（相当于 hosts 文件，只对本程序生效）
+	CAFiles                    []string          `json:"ca_files"`                      // 额外信任的 CA 证书文件（PEM 格式，如公司网关的根证书），与系统证书一起使用
+	TimeoutSeconds             int               `json:"timeout_seconds"`               // 单个请求的超时（秒）
+	DialTimeoutSeconds         int               `json:"dial_timeout_seconds"`          // 建立连接的超时（秒）
+	TLSHandshakeTimeoutSeconds int               `json:"tls_handshake_timeout_seconds"` // TLS 握手的超时（秒）
+}
+
+// SymbolsConfig 币种配置文件结构
+type SymbolsConfig struct {
+	HotSymbols   []SymbolConfig            `json:"hot_symbols"`
+	MinorSymbols []SymbolConfig            `json:"minor_symbols"`
+	SyncConfig   SyncConfig                `json:"sync_config"`
+	Retention    RetentionConfig           `json:"retention"`
+	Validation   ValidationConfig          `json:"validation"`
+	Exchanges    map[string]ExchangeConfig `json:"exchanges"` // key: 交易所名称（gateio、binance_futures 等）
+	// SymbolAliases 更名的交易对: 旧名称 → 新名称（存储名，合约带 _PERP 后缀），旧名称的历史数据合并到新名称下
+	SymbolAliases map[string]string `json:"symbol_aliases"`
+	Network       NetworkConfig     `json:"network"`
+}
+
+var symbolsConfig *SymbolsConfig
+
+// LoadSymbolsConfig 加载币种配置
+func LoadSymbolsConfig() (*SymbolsConfig, error) {
+	if symbolsConfig != nil {
+		return symbolsConfig, nil
+	}
+
+	// 默认配置文件路径
+	configPath := "config/symbols.json"
+	if path := os.Getenv("SYMBOLS_CONFIG_PATH"); path != "" {
+		configPath = path
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	// 显式配置为 0 有意义的字段在解析前设置默认值（只有配置文件中没有该字段时才保留默认值）
+	config := SymbolsConfig{SyncConfig: SyncConfig{UpsertRecentMinutes: DefaultUpsertRecentMinutes}}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	// 设置默认值
+	if config.SyncConfig.BatchSize == 0 {
+		config.SyncConfig.BatchSize = 1000
+	}
+	if config.SyncConfig.RequestIntervalMs == 0 {
+		config.SyncConfig.RequestIntervalMs = 200
+	}
+	if config.SyncConfig.PriorityRecentDays == 0 {
+		config.SyncConfig.PriorityRecentDays = 1
+	}
+	if config.SyncConfig.HistoricalStartYear == 0 {
+		config.SyncConfig.HistoricalStartYear = 2020
+	}
+	if config.SyncConfig.IdleCheckIntervalSeconds == 0 {
+		config.SyncConfig.IdleCheckIntervalSeconds = 60
+	}
+	if config.SyncConfig.InsertChunkSize == 0 {
+		config.SyncConfig.InsertChunkSize = 500
+	}
+	if config.SyncConfig.MaxConcurrentSyncs <= 0 {
+		config.SyncConfig.MaxConcurrentSyncs = 4
+	}
+	if config.SyncConfig.RetryMaxAttempts <= 0 {
+		config.SyncConfig.RetryMaxAttempts = 4
+	}
+	if config.Validation.MaxDeviationBps <= 0 {
+		config.Validation.MaxDeviationBps = 100
+	}
+	if config.Validation.SpikeBps <= 0 {
+		config.Validation.SpikeBps = 1000
+	}
+	if config.Validation.SpikeWindow <= 0 {
+		config.Validation.SpikeWindow = 3
+	}
+	if config.Network.TimeoutSeconds <= 0 {
+		config.Network.TimeoutSeconds = 30
+	}
+	if config.Network.DialTimeoutSeconds <= 0 {
+		config.Network.DialTimeoutSeconds = 10
+	}
+	if config.Network.TLSHandshakeTimeoutSeconds <= 0 {
+		config.Network.TLSHandshakeTimeoutSeconds = 10
+	}
+	if config.Retention.PurgeIntervalMinutes == 0 {
+		config.Retention.PurgeIntervalMinutes = 60
+	}
+	if config.Retention.PurgeBatchMinutes == 0 {
+		config.Retention.PurgeBatchMinutes = 1440
+	}
+	if config.Retention.PurgePauseMs == 0 {
+		config.Retention.PurgePauseMs = 200
+	}
+
+	for _, list := range [][]SymbolConfig{config.HotSymbols, config.MinorSymbols} {
+		for i := range list {
+			if err := list[i].applyMarketType(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	symbolsConfig = &config
+	return symbolsConfig, nil
+}
+
+// GetAllEnabledSymbols 获取所有启用的币种（按优先级排序）
+func GetAllEnabledSymbols() ([]SymbolConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var allSymbols []SymbolConfig
+	allSymbols = append(allSymbols, config.HotSymbols...)
+	allSymbols = append(allSymbols, config.MinorSymbols...)
+
+	// 过滤启用的币种
+	var enabled []SymbolConfig
+	for _, s := range allSymbols {
+		if s.Enabled {
+			enabled = append(enabled, s)
+		}
+	}
+
+	// 按优先级排序
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].Priority < enabled[j].Priority
+	})
+
+	return enabled, nil
+}
+
+// GetHotSymbols 获取热门币种（按优先级排序）
+func GetHotSymbols() ([]SymbolConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var enabled []SymbolConfig
+	for _, s := range config.HotSymbols {
+		if s.Enabled {
+			enabled = append(enabled, s)
+		}
+	}
+
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].Priority < enabled[j].Priority
+	})
+
+	return enabled, nil
+}
+
+// GetMinorSymbols 获取小币种（按优先级排序）
+func GetMinorSymbols() ([]SymbolConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var enabled []SymbolConfig
+	for _, s := range config.MinorSymbols {
+		if s.Enabled {
+			enabled = append(enabled, s)
+		}
+	}
+
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].Priority < enabled[j].Priority
+	})
+
+	return enabled, nil
+}
+
+// GetSyncConfig 获取同步配置
+func GetSyncConfig() (*SyncConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &config.SyncConfig, nil
+}
+
+// GetRetentionConfig 获取数据保留策略
+func GetRetentionConfig() (*RetentionConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &config.Retention, nil
+}
+
+// GetValidationConfig 获取K线校验配置
+func GetValidationConfig() (*ValidationConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &config.Validation, nil
+}
+
+// GetNetworkConfig 获取网络配置
+func GetNetworkConfig() (*NetworkConfig, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &config.Network, nil
+}
+
+// GetKeep1mDays 获取指定币种1分钟K线的保留天数（未启用保留策略或不在配置中的币种返回 0，即永久保留）
+func GetKeep1mDays(symbol string) (int, error) {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return 0, err
+	}
+	if !config.Retention.Enabled {
+		return 0, nil
+	}
+
+	for _, s := range config.HotSymbols {
+		if s.Symbol == symbol {
+			return config.Retention.Hot.Keep1mDays, nil
+		}
+	}
+	for _, s := range config.MinorSymbols {
+		if s.Symbol == symbol {
+			return config.Retention.Minor.Keep1mDays, nil
+		}
+	}
+	return 0, nil
+}
+
+// GetSymbolExchange 获取币种配置的交易所（未配置或不在配置中的币种返回 DefaultExchange）
+func GetSymbolExchange(symbol string) string {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return DefaultExchange
+	}
+
+	for _, list := range [][]SymbolConfig{config.HotSymbols, config.MinorSymbols} {
+		for _, s := range list {
+			if s.Symbol == symbol && s.Exchange != "" {
+				return s.Exchange
+			}
+		}
+	}
+	return DefaultExchange
+}
+
+// GetSymbolReferenceExchange 获取币种交叉校验的参考交易所（未配置时返回空字符串）
+func GetSymbolReferenceExchange(symbol string) string {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return ""
+	}
+
+	for _, list := range [][]SymbolConfig{config.HotSymbols, config.MinorSymbols} {
+		for _, s := range list {
+			if s.Symbol == symbol && s.ReferenceExchange != "" {
+				return s.ReferenceExchange
+			}
+		}
+	}
+	return ""
+}
+
+// GetSymbolAliases 获取更名的交易对（旧名称 → 新名称）
+func GetSymbolAliases() map[string]string {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return nil
+	}
+	return config.SymbolAliases
+}
+
+// ResolveSymbolAlias 更名的交易对返回新名称，其他交易对原样返回
+func ResolveSymbolAlias(symbol string) string {
+	if renamed, ok := GetSymbolAliases()[symbol]; ok && renamed != "" {
+		return renamed
+	}
+	return symbol
+}
+
+// GetExchangeBaseURL 获取交易所的接口地址（未配置时返回 defaultURL）
+func GetExchangeBaseURL(exchange, defaultURL string) string {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return defaultURL
+	}
+	if c, ok := config.Exchanges[exchange]; ok && c.BaseURL != "" {
+		return strings.TrimRight(c.BaseURL, "/")
+	}
+	return defaultURL
+}
+
+// GetExchangeWSURL 获取交易所的 WebSocket 地址（未配置时返回 defaultURL）
+func GetExchangeWSURL(exchange, defaultURL string) string {
+	config, err := LoadSymbolsConfig()
+	if err != nil {
+		return defaultURL
+	}
+	if c, ok := config.Exchanges[exchange]; ok && c.WSURL != "" {
+		return c.WSURL
+	}
+	return defaultURL
+}
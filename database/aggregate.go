@@ -0,0 +1,395 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// ErrAggregateNotSupported 当前存储后端不支持聚合K线表（如内存存储）
+var ErrAggregateNotSupported = errors.New("当前存储后端不支持聚合K线表")
+
+// aggregateLevel 聚合周期定义：每个周期由上一级周期聚合而来，避免每次都从1分钟数据重新计算
+type aggregateLevel struct {
+	Interval       string // 周期，如 5m
+	Minutes        int    // 周期分钟数
+	SourceInterval string // 数据来源周期（1m 表示1分钟原始表）
+}
+
+// aggregateLevels 物化的聚合周期（按从小到大排列，后一级依赖前一级）
+var aggregateLevels = []aggregateLevel{
+	{Interval: "5m", Minutes: 5, SourceInterval: "1m"},
+	{Interval: "15m", Minutes: 15, SourceInterval: "5m"},
+	{Interval: "1h", Minutes: 60, SourceInterval: "15m"},
+	{Interval: "4h", Minutes: 240, SourceInterval: "1h"},
+	{Interval: "1d", Minutes: 1440, SourceInterval: "1h"},
+}
+
+// AggregateIntervals 返回所有物化的聚合周期及其分钟数（key: 周期，value: 分钟数）
+func AggregateIntervals() map[string]int {
+	result := make(map[string]int, len(aggregateLevels))
+	for _, level := range aggregateLevels {
+		result[level.Interval] = level.Minutes
+	}
+	return result
+}
+
+// AggregateKLineStore 支持聚合K线表的存储后端（可选能力，MySQL、SQLite 实现）
+// 聚合表结构与1分钟表相同，表名格式: klines_<周期>_<币种>，如 klines_1h_BTC_USDT
+type AggregateKLineStore interface {
+	// CreateAggregateTable 为指定币种创建聚合K线表
+	CreateAggregateTable(symbol, interval string) error
+	// SaveAggregateKLines 保存聚合K线（open_time 已存在时覆盖）
+	SaveAggregateKLines(symbol, interval string, klines []KLine1m) error
+	// GetAggregateKLines 按时间范围查询聚合K线（按开盘时间升序）
+	GetAggregateKLines(symbol, interval string, startTime, endTime int64, limit int) ([]KLine1m, error)
+	// GetAggregateKLinesByCount 获取最近N根聚合K线（按开盘时间升序）
+	GetAggregateKLinesByCount(symbol, interval string, count int) ([]KLine1m, error)
+	// GetEarliestKLineTime 获取1分钟表中最早一根K线的开盘时间，没有数据时返回 0
+	GetEarliestKLineTime(symbol string) (int64, error)
+}
+
+// GetAggregateTableName 根据symbol和周期获取聚合表名
+func GetAggregateTableName(symbol, interval string) string {
+	return strings.Replace(GetTableName(symbol), "klines_1m_", "klines_"+interval+"_", 1)
+}
+
+// aggregateStore 获取当前支持聚合表的存储后端
+func aggregateStore() (AggregateKLineStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	agg, ok := s.(AggregateKLineStore)
+	if !ok {
+		return nil, ErrAggregateNotSupported
+	}
+	return agg, nil
+}
+
+// SupportsAggregates 当前存储后端是否支持聚合K线表
+func SupportsAggregates() bool {
+	_, err := aggregateStore()
+	return err == nil
+}
+
+// GetAggregateKLinesByCount 获取最近N根聚合K线
+func GetAggregateKLinesByCount(symbol, interval string, count int) ([]KLine1m, error) {
+	agg, err := aggregateStore()
+	if err != nil {
+		return nil, err
+	}
+	return agg.GetAggregateKLinesByCount(symbol, interval, count)
+}
+
+// GetAggregateKLines 按时间范围获取聚合K线
+func GetAggregateKLines(symbol, interval string, startTime, endTime int64, limit int) ([]KLine1m, error) {
+	agg, err := aggregateStore()
+	if err != nil {
+		return nil, err
+	}
+	return agg.GetAggregateKLines(symbol, interval, startTime, endTime, limit)
+}
+
+// AggregateCovers 检查最近 count 根聚合K线（GetAggregateKLinesByCount 的结果）是否覆盖1分钟表的对应范围
+// 聚合表在迁移前保存的历史数据没有重建时只包含之后增量更新的周期，直接使用会丢失更早的K线：
+//   - 最新一根1分钟K线所在的周期必须已经聚合（该币种有等待中的后台更新时除外，只是更新稍有延迟）
+//   - 聚合K线不足 count 根时，1分钟表中不能有更早的数据
+//
+// 1分钟数据按保留策略清理后聚合表比1分钟表更长，这种情况视为覆盖
+func AggregateCovers(symbol, interval string, klines []KLine1m, count int) (bool, error) {
+	if len(klines) == 0 {
+		return false, nil
+	}
+	agg, err := aggregateStore()
+	if err != nil {
+		return false, err
+	}
+	minutes, ok := AggregateIntervals()[interval]
+	if !ok {
+		return false, fmt.Errorf("未知的聚合周期: %s", interval)
+	}
+	intervalMs := int64(minutes) * 60 * 1000
+
+	latest, err := GetLatestKLine1m(symbol)
+	if err != nil {
+		return false, fmt.Errorf("获取最新K线失败: %w", err)
+	}
+	if latest != nil && klines[len(klines)-1].OpenTime < (latest.OpenTime/intervalMs)*intervalMs && !aggregateUpdates.pendingFor(symbol) {
+		return false, nil
+	}
+
+	if len(klines) < count {
+		earliest, err := agg.GetEarliestKLineTime(symbol)
+		if err != nil {
+			return false, fmt.Errorf("获取最早K线时间失败: %w", err)
+		}
+		if earliest != 0 && earliest < klines[0].OpenTime {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// aggregateLocks 每个币种一把锁，避免并发重算同一周期时旧结果覆盖新结果
+var aggregateLocks symbolLocks
+
+// aggregateChunkMs 增量更新时每次处理的时间跨度（1天，所有聚合周期都能整除）
+const aggregateChunkMs = int64(24 * 60 * 60 * 1000)
+
+// UpdateAggregates 重新计算指定时间范围（open_time）涉及的所有聚合K线
+// 时间范围会按周期边界扩展，未收盘的周期同样会被写入，并随后续数据不断覆盖
+func UpdateAggregates(symbol string, startTime, endTime int64) error {
+	agg, err := aggregateStore()
+	if err != nil {
+		return err
+	}
+	if endTime < startTime {
+		return nil
+	}
+
+	unlock := aggregateLocks.lock(symbol)
+	defer unlock()
+
+	// 按天分段处理，避免一次读取过多的1分钟数据
+	for chunkStart := (startTime / aggregateChunkMs) * aggregateChunkMs; chunkStart <= endTime; chunkStart += aggregateChunkMs {
+		chunkEnd := chunkStart + aggregateChunkMs - 1
+		from := max(startTime, chunkStart)
+		to := min(endTime, chunkEnd)
+		if err := updateAggregateChunk(agg, symbol, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateAggregateChunk 逐级更新一个时间段内的聚合K线
+func updateAggregateChunk(agg AggregateKLineStore, symbol string, startTime, endTime int64) error {
+	for _, level := range aggregateLevels {
+		intervalMs := int64(level.Minutes) * 60 * 1000
+		bucketStart := (startTime / intervalMs) * intervalMs
+		bucketEnd := (endTime/intervalMs)*intervalMs + intervalMs - 1
+
+		var source []KLine1m
+		var err error
+		if level.SourceInterval == "1m" {
+			source, err = GetKLines1m(symbol, bucketStart, bucketEnd, 0)
+		} else {
+			source, err = agg.GetAggregateKLines(symbol, level.SourceInterval, bucketStart, bucketEnd, 0)
+		}
+		if err != nil {
+			return fmt.Errorf("读取 %s 数据失败 [%s]: %w", level.SourceInterval, symbol, err)
+		}
+
+		if err := agg.SaveAggregateKLines(symbol, level.Interval, aggregateBuckets(source, intervalMs)); err != nil {
+			return err
+		}
+
+		// 下一级周期需要覆盖本级更新过的整个周期
+		startTime, endTime = bucketStart, bucketEnd
+	}
+	return nil
+}
+
+// aggregateBuckets 将按开盘时间升序的K线按周期聚合（周期按 UTC 对齐）
+func aggregateBuckets(klines []KLine1m, intervalMs int64) []KLine1m {
+	var result []KLine1m
+	for _, k := range klines {
+		bucketStart := (k.OpenTime / intervalMs) * intervalMs
+		if n := len(result); n > 0 && result[n-1].OpenTime == bucketStart {
+			last := &result[n-1]
+			if k.High > last.High {
+				last.High = k.High
+			}
+			if k.Low < last.Low {
+				last.Low = k.Low
+			}
+			last.Close = k.Close
+			last.Volume += k.Volume
+			last.QuoteVolume += k.QuoteVolume
+			last.TradeCount += k.TradeCount
+			continue
+		}
+		result = append(result, KLine1m{
+			Symbol:      k.Symbol,
+			OpenTime:    bucketStart,
+			Open:        k.Open,
+			High:        k.High,
+			Low:         k.Low,
+			Close:       k.Close,
+			Volume:      k.Volume,
+			QuoteVolume: k.QuoteVolume,
+			TradeCount:  k.TradeCount,
+			CloseTime:   bucketStart + intervalMs - 1,
+		})
+	}
+	return result
+}
+
+// RebuildAggregates 根据1分钟表的全部历史数据重建指定币种的聚合K线
+func RebuildAggregates(symbol string) error {
+	agg, err := aggregateStore()
+	if err != nil {
+		return err
+	}
+
+	earliest, err := agg.GetEarliestKLineTime(symbol)
+	if err != nil {
+		return fmt.Errorf("获取最早K线时间失败: %w", err)
+	}
+	latest, err := GetLatestKLine1m(symbol)
+	if err != nil {
+		return fmt.Errorf("获取最新K线失败: %w", err)
+	}
+	if earliest == 0 || latest == nil {
+		logger.Infof("[%s] 没有1分钟K线数据，跳过聚合重建", symbol)
+		return nil
+	}
+
+	for _, level := range aggregateLevels {
+		if err := agg.CreateAggregateTable(symbol, level.Interval); err != nil {
+			return err
+		}
+	}
+
+	// 每次处理7天，并打印进度
+	const rebuildChunkMs = 7 * aggregateChunkMs
+	start := (earliest / aggregateChunkMs) * aggregateChunkMs
+	total := latest.OpenTime - start + 1
+	for chunkStart := start; chunkStart <= latest.OpenTime; chunkStart += rebuildChunkMs {
+		chunkEnd := min(chunkStart+rebuildChunkMs-1, latest.OpenTime)
+		if err := UpdateAggregates(symbol, chunkStart, chunkEnd); err != nil {
+			return err
+		}
+		logger.Infof("[%s] 聚合重建进度: %s ~ %s (%.1f%%)", symbol,
+			time.UnixMilli(chunkStart).Format("2006-01-02"),
+			time.UnixMilli(chunkEnd).Format("2006-01-02"),
+			float64(chunkEnd-start+1)*100/float64(total))
+	}
+
+	logger.Infof("[%s] 聚合K线重建完成", symbol)
+	return nil
+}
+
+// RebuildAggregatesFromConfig 重建配置文件中所有启用币种的聚合K线，返回处理的币种数量
+func RebuildAggregatesFromConfig() (int, error) {
+	allSymbols, err := config.GetAllEnabledSymbols()
+	if err != nil {
+		return 0, fmt.Errorf("获取币种配置失败: %w", err)
+	}
+
+	for _, symbolConfig := range allSymbols {
+		if err := RebuildAggregates(symbolConfig.Symbol); err != nil {
+			return 0, fmt.Errorf("重建聚合K线失败 (币种: %s): %w", symbolConfig.Symbol, err)
+		}
+	}
+	return len(allSymbols), nil
+}
+
+// timeSpan 开盘时间范围 [start, end]
+type timeSpan struct{ start, end int64 }
+
+// aggregateUpdater 在后台按币种更新聚合表：保存1分钟K线时只登记时间范围，
+// 每个币种最多一个后台任务，任务执行期间登记的范围合并后在下一轮处理（一次同步任务的多次保存通常合并为一次更新）
+type aggregateUpdater struct {
+	mu      sync.Mutex
+	pending map[string][]timeSpan // 等待更新的时间范围（按币种）
+	running map[string]bool       // 正在运行后台任务的币种
+	wg      sync.WaitGroup
+}
+
+var aggregateUpdates = &aggregateUpdater{
+	pending: make(map[string][]timeSpan),
+	running: make(map[string]bool),
+}
+
+// schedule 登记需要更新的时间范围，该币种没有后台任务时启动一个
+// 与已登记范围重叠或相隔不到1天的合并，相隔较远的（如历史回填和实时同步）分别更新，避免读取中间大段的1分钟数据
+func (u *aggregateUpdater) schedule(symbol string, start, end int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	spans := u.pending[symbol]
+	merged := false
+	for i := range spans {
+		if start <= spans[i].end+aggregateChunkMs && end >= spans[i].start-aggregateChunkMs {
+			spans[i].start = min(spans[i].start, start)
+			spans[i].end = max(spans[i].end, end)
+			merged = true
+			break
+		}
+	}
+	if !merged {
+		spans = append(spans, timeSpan{start: start, end: end})
+	}
+	u.pending[symbol] = spans
+
+	if u.running[symbol] {
+		return
+	}
+	u.running[symbol] = true
+	u.wg.Add(1)
+	go u.run(symbol)
+}
+
+// run 处理币种登记的时间范围，直到没有新的登记
+func (u *aggregateUpdater) run(symbol string) {
+	defer u.wg.Done()
+	for {
+		u.mu.Lock()
+		spans := u.pending[symbol]
+		delete(u.pending, symbol)
+		if len(spans) == 0 {
+			delete(u.running, symbol)
+			u.mu.Unlock()
+			return
+		}
+		u.mu.Unlock()
+
+		for _, span := range spans {
+			if err := UpdateAggregates(symbol, span.start, span.end); err != nil {
+				logger.Warnf("[%s] 更新聚合K线失败: %v", symbol, err)
+			}
+		}
+	}
+}
+
+// pendingFor 币种是否有等待中或执行中的后台更新
+func (u *aggregateUpdater) pendingFor(symbol string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.running[symbol] || len(u.pending[symbol]) > 0
+}
+
+// WaitAggregateUpdates 等待后台的聚合表更新全部完成（关闭数据库前调用）
+func WaitAggregateUpdates() {
+	aggregateUpdates.wg.Wait()
+}
+
+// updateAggregatesAfterSave 保存1分钟K线后登记需要更新的聚合范围，由后台按币种异步更新
+// （不阻塞保存；失败只记录警告，不影响1分钟数据的保存）
+func updateAggregatesAfterSave(klines []KLine1m) {
+	if !SupportsAggregates() {
+		return
+	}
+
+	spans := make(map[string]*timeSpan)
+	for _, k := range klines {
+		span, ok := spans[k.Symbol]
+		if !ok {
+			spans[k.Symbol] = &timeSpan{start: k.OpenTime, end: k.OpenTime}
+			continue
+		}
+		span.start = min(span.start, k.OpenTime)
+		span.end = max(span.end, k.OpenTime)
+	}
+
+	for symbol, span := range spans {
+		aggregateUpdates.schedule(symbol, span.start, span.end)
+	}
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// useSQLiteStore 在临时目录中创建 SQLite 存储作为当前存储后端（支持聚合表），并创建币种的1分钟表和聚合表
+func useSQLiteStore(t *testing.T, symbol string) *sqlStore {
+	t.Helper()
+	db, s, err := OpenSQLiteStore(SQLiteDSNPrefix + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	previous := GetStore()
+	SetStore(s)
+	t.Cleanup(func() {
+		WaitAggregateUpdates()
+		SetStore(previous)
+		db.Close()
+	})
+
+	store := s.(*sqlStore)
+	if err := store.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateTableForSymbol(symbol); err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range aggregateLevels {
+		if err := store.CreateAggregateTable(symbol, level.Interval); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// variedKLines 第 from 到 to 分钟（含）的1分钟K线，价格和成交量按分钟变化，便于发现聚合时取错K线
+func variedKLines(symbol string, from, to int) []KLine1m {
+	var klines []KLine1m
+	for n := from; n <= to; n++ {
+		price := 100 + float64((n*37)%101)
+		k := testKLine(symbol, n, price)
+		k.Open = price - float64(n%7)
+		k.High = price + float64(n%5)
+		k.Low = k.Open - float64(n%3) - 1
+		k.Volume = float64(n%11) + 1
+		k.QuoteVolume = k.Volume * price
+		k.TradeCount = int64(n%13) + 1
+		klines = append(klines, k)
+	}
+	return klines
+}
+
+func TestAggregateRollups(t *testing.T) {
+	const symbol = "BTC_USDT"
+	store := useSQLiteStore(t, symbol)
+
+	// 两天零一小时的数据，分多批保存（每批触发一次增量更新）
+	klines := variedKLines(symbol, 0, 2*1440+59)
+	for start := 0; start < len(klines); start += 700 {
+		if _, err := SaveKLine1m(klines[start:min(start+700, len(klines))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	WaitAggregateUpdates()
+
+	// 逐级聚合（15m←5m、1h←15m、4h/1d←1h）的结果应与直接从1分钟数据聚合一致
+	for _, level := range aggregateLevels {
+		want := aggregateBuckets(klines, int64(level.Minutes)*60000)
+		got, err := store.GetAggregateKLines(symbol, level.Interval, 0, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertAggregates(t, level.Interval, got, want)
+	}
+
+	// 修改已有的一分钟后，包含它的各级周期都被重新计算
+	changed := klines[1500]
+	changed.High += 1000
+	changed.Close = changed.High
+	klines[1500] = changed
+	if _, err := UpsertKLine1m([]KLine1m{changed}); err != nil {
+		t.Fatal(err)
+	}
+	WaitAggregateUpdates()
+	for _, level := range aggregateLevels {
+		want := aggregateBuckets(klines, int64(level.Minutes)*60000)
+		got, err := store.GetAggregateKLines(symbol, level.Interval, 0, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertAggregates(t, level.Interval, got, want)
+	}
+}
+
+// assertAggregates 比较聚合K线（价格和成交额允许浮点累加误差）
+func assertAggregates(t *testing.T, interval string, got, want []KLine1m) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: 聚合K线数量 = %d，期望 %d", interval, len(got), len(want))
+	}
+	near := func(a, b float64) bool { return a-b < 1e-6 && b-a < 1e-6 }
+	for i := range got {
+		g, w := got[i], want[i]
+		if g.OpenTime != w.OpenTime || g.CloseTime != w.CloseTime || g.TradeCount != w.TradeCount ||
+			g.Open != w.Open || g.High != w.High || g.Low != w.Low || g.Close != w.Close ||
+			!near(g.Volume, w.Volume) || !near(g.QuoteVolume, w.QuoteVolume) {
+			t.Fatalf("%s: 第 %d 根聚合K线 = %+v，期望 %+v", interval, i, g, w)
+		}
+	}
+}
+
+func TestAggregateCovers(t *testing.T) {
+	const symbol = "ETH_USDT"
+	store := useSQLiteStore(t, symbol)
+
+	// 前一天的数据直接写入1分钟表（模拟升级前保存、没有重建聚合表的历史数据），之后的数据正常保存
+	if _, err := store.SaveKLine1m(variedKLines(symbol, 0, 1439)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveKLine1m(variedKLines(symbol, 1440, 1440+119)); err != nil {
+		t.Fatal(err)
+	}
+	WaitAggregateUpdates()
+
+	covers := func(interval string, count int) bool {
+		t.Helper()
+		klines, err := GetAggregateKLinesByCount(symbol, interval, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		covered, err := AggregateCovers(symbol, interval, klines, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return covered
+	}
+
+	// 最近2小时已经聚合
+	if !covers("5m", 24) || !covers("1h", 2) {
+		t.Errorf("最近的数据已经聚合，应视为覆盖")
+	}
+	// 需要更早的K线时，聚合表缺少升级前的历史数据
+	if covers("5m", 100) || covers("1h", 10) {
+		t.Errorf("聚合表缺少更早的数据，不应视为覆盖")
+	}
+
+	// 新的一分钟写入1分钟表但还没有聚合（没有等待中的更新）：最新周期缺失
+	if _, err := store.SaveKLine1m(variedKLines(symbol, 1440+120, 1440+120)); err != nil {
+		t.Fatal(err)
+	}
+	if covers("5m", 24) {
+		t.Errorf("最新一分钟所在的周期没有聚合，不应视为覆盖")
+	}
+
+	// 有等待中的后台更新时只是更新延迟，视为覆盖
+	aggregateUpdates.mu.Lock()
+	aggregateUpdates.pending[symbol] = []timeSpan{{start: minuteAt(1440 + 120), end: minuteAt(1440 + 120)}}
+	aggregateUpdates.mu.Unlock()
+	covered := covers("5m", 24)
+	aggregateUpdates.mu.Lock()
+	delete(aggregateUpdates.pending, symbol)
+	aggregateUpdates.mu.Unlock()
+	if !covered {
+		t.Errorf("有等待中的聚合更新时应视为覆盖")
+	}
+
+	// 重建后全部覆盖
+	if err := RebuildAggregates(symbol); err != nil {
+		t.Fatal(err)
+	}
+	if !covers("5m", 100) || !covers("1h", 10) || !covers("1d", 5) {
+		t.Errorf("重建聚合表后应视为覆盖")
+	}
+
+	// 1分钟数据按保留策略清理后聚合表更长，仍视为覆盖
+	if _, err := store.DeleteKLines1mRange(symbol, 0, minuteAt(1440)-1); err != nil {
+		t.Fatal(err)
+	}
+	if !covers("1h", 30) {
+		t.Errorf("1分钟数据清理后聚合表更长，应视为覆盖")
+	}
+
+	if covered, err := AggregateCovers(symbol, "5m", nil, 10); err != nil || covered {
+		t.Errorf("没有聚合K线时不应视为覆盖: %v, %v", covered, err)
+	}
+}
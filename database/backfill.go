@@ -0,0 +1,83 @@
+package database
+
+import "errors"
+
+// ErrBackfillNotSupported 当前存储后端不支持保存历史回填进度
+var ErrBackfillNotSupported = errors.New("当前存储后端不支持保存历史回填进度")
+
+// 历史回填的状态
+const (
+	BackfillRunning   = "running"   // 回填中（或等待下一轮调度）
+	BackfillPaused    = "paused"    // 已暂停（前端手动暂停）
+	BackfillCompleted = "completed" // 已回填到目标开始时间
+	BackfillFailed    = "failed"    // 无法继续（如交易对不存在），恢复后重新尝试
+)
+
+// BackfillDirectionBackward 从新到旧回填
+const BackfillDirectionBackward = "backward"
+
+// BackfillCursor 币种的历史回填进度
+// 回填从 TargetEnd 向 TargetStart 倒推，[NextStart, NextEnd] 为下一个待同步的时间段，
+// 更晚的部分 (NextEnd, TargetEnd] 已处理完成
+type BackfillCursor struct {
+	Symbol      string `json:"symbol"`
+	Direction   string `json:"direction"`
+	Status      string `json:"status"`
+	TargetStart int64  `json:"targetStart"`
+	TargetEnd   int64  `json:"targetEnd"`
+	NextStart   int64  `json:"nextStart"`
+	NextEnd     int64  `json:"nextEnd"`
+	Attempts    int    `json:"attempts"`  // 下一个时间段已失败的次数
+	LastError   string `json:"lastError"` // 最后一次的错误信息
+	UpdatedAt   int64  `json:"updatedAt"` // 更新时间（毫秒时间戳）
+}
+
+// BackfillCursorStore 保存历史回填进度的存储后端（可选能力，MySQL、SQLite、内存存储实现）
+type BackfillCursorStore interface {
+	// GetBackfillCursor 获取币种的历史回填进度（没有记录时返回 nil）
+	GetBackfillCursor(symbol string) (*BackfillCursor, error)
+	// GetBackfillCursors 获取所有币种的历史回填进度
+	GetBackfillCursors() ([]BackfillCursor, error)
+	// SaveBackfillCursor 保存历史回填进度（已存在时覆盖）
+	SaveBackfillCursor(cursor BackfillCursor) error
+}
+
+// backfillCursorStore 获取当前支持保存回填进度的存储后端
+func backfillCursorStore() (BackfillCursorStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	bs, ok := s.(BackfillCursorStore)
+	if !ok {
+		return nil, ErrBackfillNotSupported
+	}
+	return bs, nil
+}
+
+// GetBackfillCursor 获取币种的历史回填进度（没有记录时返回 nil）
+func GetBackfillCursor(symbol string) (*BackfillCursor, error) {
+	bs, err := backfillCursorStore()
+	if err != nil {
+		return nil, err
+	}
+	return bs.GetBackfillCursor(symbol)
+}
+
+// GetBackfillCursors 获取所有币种的历史回填进度
+func GetBackfillCursors() ([]BackfillCursor, error) {
+	bs, err := backfillCursorStore()
+	if err != nil {
+		return nil, err
+	}
+	return bs.GetBackfillCursors()
+}
+
+// SaveBackfillCursor 保存历史回填进度
+func SaveBackfillCursor(cursor BackfillCursor) error {
+	bs, err := backfillCursorStore()
+	if err != nil {
+		return err
+	}
+	return bs.SaveBackfillCursor(cursor)
+}
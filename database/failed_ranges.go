@@ -0,0 +1,83 @@
+package database
+
+import "errors"
+
+// ErrFailedRangesNotSupported 当前存储后端不支持记录同步失败的时间段
+var ErrFailedRangesNotSupported = errors.New("当前存储后端不支持记录同步失败的时间段")
+
+// maxErrorMessageLen 失败时间段保存的错误信息最大长度（字节）
+const maxErrorMessageLen = 500
+
+// FailedRange 同步失败、等待重试的时间段
+type FailedRange struct {
+	Symbol        string `json:"symbol"`
+	StartTime     int64  `json:"startTime"`
+	EndTime       int64  `json:"endTime"`
+	ErrorKind     string `json:"errorKind"`     // 错误类型（rate_limited、network、bad_payload 等）
+	ErrorMessage  string `json:"errorMessage"`  // 最后一次的错误信息
+	Attempts      int    `json:"attempts"`      // 失败次数
+	FirstFailedAt int64  `json:"firstFailedAt"` // 第一次失败时间（毫秒时间戳）
+	LastFailedAt  int64  `json:"lastFailedAt"`  // 最后一次失败时间（毫秒时间戳）
+}
+
+// FailedRangeStore 记录同步失败时间段的存储后端（可选能力，MySQL、SQLite、内存存储实现）
+type FailedRangeStore interface {
+	// RecordFailedRange 记录同步失败的时间段（同一时间段再次失败时累加失败次数）
+	RecordFailedRange(symbol string, startTime, endTime int64, errorKind, errorMessage string) error
+	// GetFailedRanges 获取同步失败的时间段（symbol 为空时返回所有币种，按最后失败时间升序）
+	GetFailedRanges(symbol string) ([]FailedRange, error)
+	// DeleteFailedRanges 删除完全落在 [startTime, endTime] 内的失败时间段，返回删除的数量
+	DeleteFailedRanges(symbol string, startTime, endTime int64) (int, error)
+}
+
+// failedRangeStore 获取当前支持记录失败时间段的存储后端
+func failedRangeStore() (FailedRangeStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	fs, ok := s.(FailedRangeStore)
+	if !ok {
+		return nil, ErrFailedRangesNotSupported
+	}
+	return fs, nil
+}
+
+// RecordFailedRange 记录同步失败的时间段，同一时间段再次失败时累加失败次数
+func RecordFailedRange(symbol string, startTime, endTime int64, errorKind, errorMessage string) error {
+	fs, err := failedRangeStore()
+	if err != nil {
+		return err
+	}
+	return fs.RecordFailedRange(symbol, startTime, endTime, errorKind, errorMessage)
+}
+
+// GetFailedRanges 获取同步失败的时间段（symbol 为空时返回所有币种）
+func GetFailedRanges(symbol string) ([]FailedRange, error) {
+	fs, err := failedRangeStore()
+	if err != nil {
+		return nil, err
+	}
+	return fs.GetFailedRanges(symbol)
+}
+
+// DeleteFailedRanges 删除完全落在 [startTime, endTime] 内的失败时间段（该时间段已同步成功）
+func DeleteFailedRanges(symbol string, startTime, endTime int64) (int, error) {
+	fs, err := failedRangeStore()
+	if err != nil {
+		return 0, err
+	}
+	return fs.DeleteFailedRanges(symbol, startTime, endTime)
+}
+
+// truncateMessage 截断过长的错误信息（按字节截断，不截断多字节字符）
+func truncateMessage(message string) string {
+	if len(message) <= maxErrorMessageLen {
+		return message
+	}
+	cut := maxErrorMessageLen
+	for cut > 0 && message[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return message[:cut]
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInactiveSymbolsNotSupported 当前存储后端不支持保存停止同步的交易对
+var ErrInactiveSymbolsNotSupported = errors.New("当前存储后端不支持保存停止同步的交易对")
+
+// 交易对停止同步的原因
+const (
+	SymbolDelisted  = "delisted"  // 交易对不存在或已下架
+	SymbolSuspended = "suspended" // 交易所暂停交易
+	SymbolRenamed   = "renamed"   // 已更名，历史数据已合并到新名称
+)
+
+// InactiveSymbol 停止同步的交易对
+type InactiveSymbol struct {
+	Symbol     string `json:"symbol"`
+	Status     string `json:"status"`     // delisted、suspended、renamed
+	Reason     string `json:"reason"`     // 原因（交易所的错误信息、交易状态或新名称）
+	DetectedAt int64  `json:"detectedAt"` // 发现时间（毫秒时间戳）
+}
+
+// InactiveSymbolStore 保存停止同步的交易对的存储后端（可选能力，MySQL、SQLite、内存存储实现）
+type InactiveSymbolStore interface {
+	// SaveInactiveSymbol 保存停止同步的交易对（已存在时覆盖）
+	SaveInactiveSymbol(symbol InactiveSymbol) error
+	// DeleteInactiveSymbol 删除停止同步的记录（恢复同步），返回是否存在记录
+	DeleteInactiveSymbol(symbol string) (bool, error)
+	// GetInactiveSymbols 获取所有停止同步的交易对（按交易对排序）
+	GetInactiveSymbols() ([]InactiveSymbol, error)
+}
+
+// inactiveSymbolStore 获取当前支持保存停止同步的交易对的存储后端
+func inactiveSymbolStore() (InactiveSymbolStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	is, ok := s.(InactiveSymbolStore)
+	if !ok {
+		return nil, ErrInactiveSymbolsNotSupported
+	}
+	return is, nil
+}
+
+// inactiveSymbols 停止同步的交易对的内存索引（调度器每次提交任务时查询，不每次访问数据库）
+var inactiveSymbols = struct {
+	mu      sync.RWMutex
+	symbols map[string]InactiveSymbol
+}{
+	symbols: make(map[string]InactiveSymbol),
+}
+
+// LoadInactiveSymbols 从数据库加载停止同步的交易对到内存索引，返回加载的数量
+func LoadInactiveSymbols() (int, error) {
+	symbols, err := GetInactiveSymbols()
+	if err != nil {
+		return 0, err
+	}
+	inactiveSymbols.mu.Lock()
+	defer inactiveSymbols.mu.Unlock()
+	inactiveSymbols.symbols = make(map[string]InactiveSymbol, len(symbols))
+	for _, symbol := range symbols {
+		inactiveSymbols.symbols[symbol.Symbol] = symbol
+	}
+	return len(symbols), nil
+}
+
+// GetInactiveSymbols 获取数据库中所有停止同步的交易对
+func GetInactiveSymbols() ([]InactiveSymbol, error) {
+	is, err := inactiveSymbolStore()
+	if err != nil {
+		return nil, err
+	}
+	return is.GetInactiveSymbols()
+}
+
+// LookupInactiveSymbol 查询交易对是否已停止同步
+func LookupInactiveSymbol(symbol string) (InactiveSymbol, bool) {
+	inactiveSymbols.mu.RLock()
+	defer inactiveSymbols.mu.RUnlock()
+	inactive, ok := inactiveSymbols.symbols[symbol]
+	return inactive, ok
+}
+
+// MarkSymbolInactive 标记交易对停止同步，并更新内存索引
+func MarkSymbolInactive(symbol InactiveSymbol) error {
+	is, err := inactiveSymbolStore()
+	if err != nil {
+		return err
+	}
+	if err := is.SaveInactiveSymbol(symbol); err != nil {
+		return err
+	}
+	inactiveSymbols.mu.Lock()
+	inactiveSymbols.symbols[symbol.Symbol] = symbol
+	inactiveSymbols.mu.Unlock()
+	return nil
+}
+
+// ReactivateSymbol 恢复交易对的同步，返回之前是否已停止同步
+func ReactivateSymbol(symbol string) (bool, error) {
+	is, err := inactiveSymbolStore()
+	if err != nil {
+		return false, err
+	}
+	deleted, err := is.DeleteInactiveSymbol(symbol)
+	if err != nil {
+		return false, err
+	}
+	inactiveSymbols.mu.Lock()
+	delete(inactiveSymbols.symbols, symbol)
+	inactiveSymbols.mu.Unlock()
+	return deleted, nil
+}
+
+// mergeBatchSize 合并历史数据时每次读取的K线数量
+const mergeBatchSize = 5000
+
+// MergeSymbolHistory 把旧名称的1分钟K线和已同步时间段复制到新名称下（已存在的K线不覆盖），返回新插入的K线数量
+// 新名称的聚合K线随保存自动更新；旧名称的数据保留不删除
+func MergeSymbolHistory(from, to string) (int, error) {
+	if err := CreateTableForSymbol(to); err != nil {
+		return 0, fmt.Errorf("创建 %s 的K线表失败: %w", to, err)
+	}
+
+	inserted := 0
+	startTime := int64(0)
+	for {
+		klines, err := GetKLines1m(from, startTime, 0, mergeBatchSize)
+		if err != nil {
+			return inserted, fmt.Errorf("读取 %s 的K线失败: %w", from, err)
+		}
+		if len(klines) == 0 {
+			break
+		}
+		for i := range klines {
+			klines[i].Symbol = to
+		}
+		result, err := SaveKLine1m(klines)
+		if err != nil {
+			return inserted, fmt.Errorf("保存 %s 的K线失败: %w", to, err)
+		}
+		inserted += result.InsertedCount
+		if len(klines) < mergeBatchSize {
+			break
+		}
+		startTime = klines[len(klines)-1].OpenTime + 1
+	}
+
+	ranges, err := GetSyncTimeRanges(from)
+	if err != nil {
+		return inserted, fmt.Errorf("读取 %s 的已同步时间段失败: %w", from, err)
+	}
+	for _, r := range ranges {
+		if err := AddSyncTimeRange(to, r.StartTime, r.EndTime); err != nil {
+			return inserted, fmt.Errorf("记录 %s 的已同步时间段失败: %w", to, err)
+		}
+	}
+	return inserted, nil
+}
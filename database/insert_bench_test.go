@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// benchInsertRows 每次写入的K线数量（与一次同步批次的规模相当）
+const benchInsertRows = 2000
+
+// openBenchSQLiteStore 在临时目录中创建 SQLite 存储和基准测试使用的K线表
+func openBenchSQLiteStore(b *testing.B, symbol string) *sqlStore {
+	b.Helper()
+	db, s, err := OpenSQLiteStore(SQLiteDSNPrefix + filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	store := s.(*sqlStore)
+	if err := store.CreateTableForSymbol(symbol); err != nil {
+		b.Fatalf("创建K线表失败: %v", err)
+	}
+	return store
+}
+
+// benchKLines 生成从 base 开始的连续1分钟K线
+func benchKLines(symbol string, base int64, rows int) []KLine1m {
+	klines := make([]KLine1m, rows)
+	for i := range klines {
+		openTime := base + int64(i)*60000
+		price := 100 + float64(i%100)/10
+		klines[i] = KLine1m{
+			Symbol: symbol, OpenTime: openTime,
+			Open: price, High: price + 1, Low: price - 1, Close: price + 0.5,
+			Volume: 1, QuoteVolume: price, TradeCount: 10,
+			CloseTime: openTime + 59999,
+		}
+	}
+	return klines
+}
+
+// benchmarkInsert 每次迭代在一个事务中写入 benchInsertRows 根新的K线（open_time 不重复，全部实际插入）
+func benchmarkInsert(b *testing.B, insert func(s *sqlStore, tx *sql.Tx, tableName string, klines []KLine1m) (inserted int, err error)) {
+	const symbol = "BENCH_USDT"
+	s := openBenchSQLiteStore(b, symbol)
+	tableName := GetTableName(symbol)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		klines := benchKLines(symbol, int64(i)*benchInsertRows*60000, benchInsertRows)
+		b.StartTimer()
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			b.Fatal(err)
+		}
+		inserted, err := insert(s, tx, tableName, klines)
+		if err != nil {
+			tx.Rollback()
+			b.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+		if inserted != benchInsertRows {
+			b.Fatalf("插入 %d 行，期望 %d 行", inserted, benchInsertRows)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchInsertRows)/b.Elapsed().Seconds(), "rows/s")
+}
+
+// BenchmarkInsertKLines 对比逐行插入和多行批量插入（SaveKLine1m 使用的 insertKLinesChunked）
+//
+//	go test ./database/ -run '^$' -bench InsertKLines
+func BenchmarkInsertKLines(b *testing.B) {
+	b.Run("OneByOne", func(b *testing.B) {
+		benchmarkInsert(b, func(s *sqlStore, tx *sql.Tx, tableName string, klines []KLine1m) (int, error) {
+			inserted, _, _ := s.insertKLinesOneByOne(tx, tableName, klines)
+			return inserted, nil
+		})
+	})
+	b.Run("Chunked", func(b *testing.B) {
+		benchmarkInsert(b, func(s *sqlStore, tx *sql.Tx, tableName string, klines []KLine1m) (int, error) {
+			inserted, _, _, err := s.insertKLinesChunked(tx, tableName, klines)
+			return inserted, err
+		})
+	})
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// minuteMs 1分钟的毫秒数
+const minuteMs = int64(60 * 1000)
+
+// integrityScanBatch 完整性扫描每次读取的K线数量
+const integrityScanBatch = 10000
+
+// maxIntegrityIssues 报告中保留的问题明细数量上限（计数不受影响）
+const maxIntegrityIssues = 200
+
+// 完整性问题类型
+const (
+	IssueDuplicate    = "duplicate"      // 同一分钟内有多条K线
+	IssueMisaligned   = "misaligned"     // open_time 不是整分钟，或 close_time 不等于 open_time + 59999
+	IssueOHLC         = "ohlc_violation" // low > open/close 或 high < open/close
+	IssueInvalidPrice = "invalid_price"  // 价格为0或负数，成交量为负数
+)
+
+// IntegrityIssue 单条K线的问题
+type IntegrityIssue struct {
+	OpenTime int64  `json:"openTime"`
+	Type     string `json:"type"`
+	Detail   string `json:"detail"`
+}
+
+// IntegrityReport 单个币种的完整性扫描报告
+type IntegrityReport struct {
+	Symbol    string `json:"symbol"`
+	TableName string `json:"tableName"`
+	StartTime int64  `json:"startTime"` // 扫描到的第一根K线开盘时间
+	EndTime   int64  `json:"endTime"`   // 扫描到的最后一根K线开盘时间
+	TotalRows int    `json:"totalRows"`
+
+	MissingMinutes int             `json:"missingMinutes"`
+	MissingRanges  []SyncTimeRange `json:"missingRanges"`
+	// MissingInSyncedRanges 缺失但被 sync_time_ranges 记录为已同步的分钟数（不会被自动补齐）
+	MissingInSyncedRanges int `json:"missingInSyncedRanges"`
+
+	IssueCounts map[string]int   `json:"issueCounts"`
+	Issues      []IntegrityIssue `json:"issues"` // 最多保留 maxIntegrityIssues 条
+
+	// invalidOpenTimes 需要重新拉取的问题K线（OHLC 异常、价格异常、时间未对齐、重复）
+	invalidOpenTimes []int64
+}
+
+// Healthy 是否没有发现任何问题
+func (r *IntegrityReport) Healthy() bool {
+	return r.MissingMinutes == 0 && len(r.invalidOpenTimes) == 0
+}
+
+func (r *IntegrityReport) addIssue(k KLine1m, issueType, detail string) {
+	r.IssueCounts[issueType]++
+	if len(r.Issues) < maxIntegrityIssues {
+		r.Issues = append(r.Issues, IntegrityIssue{OpenTime: k.OpenTime, Type: issueType, Detail: detail})
+	}
+}
+
+// addMissing 记录缺失的分钟（firstMinute、lastMinute 为第一个和最后一个缺失分钟的开盘时间）
+func (r *IntegrityReport) addMissing(firstMinute, lastMinute int64) {
+	if firstMinute > lastMinute {
+		return
+	}
+	r.MissingRanges = append(r.MissingRanges, SyncTimeRange{StartTime: firstMinute, EndTime: lastMinute + minuteMs - 1})
+	r.MissingMinutes += int((lastMinute-firstMinute)/minuteMs) + 1
+}
+
+// ScanIntegrity 扫描指定币种的1分钟K线表，检查缺失的分钟、重复或未对齐的时间、OHLC 异常和价格异常
+// startTime、endTime 为 0 时扫描整张表；指定了边界时，边界与第一根、最后一根K线之间缺失的分钟也会报告
+// （早于保留策略清理边界、以及尚未收盘的分钟除外）
+func ScanIntegrity(symbol string, startTime, endTime int64) (*IntegrityReport, error) {
+	report := &IntegrityReport{
+		Symbol:      symbol,
+		TableName:   GetTableName(symbol),
+		IssueCounts: make(map[string]int),
+	}
+
+	// 需要检查的第一个和最后一个分钟（0 表示不限）
+	var firstMinute, lastMinute int64
+	if startTime > 0 {
+		purgedBefore, err := GetPurgedBefore(symbol)
+		if err != nil {
+			return nil, err
+		}
+		from := max(startTime, purgedBefore)
+		firstMinute = (from + minuteMs - 1) / minuteMs * minuteMs
+	}
+	if endTime > 0 {
+		currentMinute := time.Now().UnixMilli() / minuteMs * minuteMs
+		lastMinute = min(endTime/minuteMs*minuteMs, currentMinute-minuteMs)
+	}
+
+	var prev *KLine1m
+	from := startTime
+	for {
+		klines, err := GetKLines1m(symbol, from, endTime, integrityScanBatch)
+		if err != nil {
+			return nil, fmt.Errorf("读取K线失败: %w", err)
+		}
+
+		for i := range klines {
+			k := klines[i]
+			if report.TotalRows == 0 {
+				report.StartTime = k.OpenTime
+				if firstMinute > 0 {
+					report.addMissing(firstMinute, k.OpenTime/minuteMs*minuteMs-minuteMs)
+				}
+			}
+			report.TotalRows++
+			report.EndTime = k.OpenTime
+			checkKLine(report, prev, k)
+			prev = &klines[i]
+		}
+
+		if len(klines) < integrityScanBatch {
+			break
+		}
+		from = klines[len(klines)-1].OpenTime + 1
+	}
+
+	if lastMinute > 0 {
+		switch {
+		case report.TotalRows > 0:
+			report.addMissing(prev.OpenTime/minuteMs*minuteMs+minuteMs, lastMinute)
+		case firstMinute > 0:
+			report.addMissing(firstMinute, lastMinute)
+		}
+	}
+
+	// 统计缺失的分钟中有多少被记录为已同步
+	if len(report.MissingRanges) > 0 {
+		synced, err := GetSyncTimeRanges(symbol)
+		if err != nil {
+			return nil, err
+		}
+		for _, missing := range report.MissingRanges {
+			for _, r := range synced {
+				overlapStart := max(missing.StartTime, r.StartTime)
+				overlapEnd := min(missing.EndTime, r.EndTime)
+				if overlapStart <= overlapEnd {
+					report.MissingInSyncedRanges += int((overlapEnd-overlapStart)/minuteMs) + 1
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// checkKLine 检查单根K线，以及与上一根K线之间的连续性
+func checkKLine(report *IntegrityReport, prev *KLine1m, k KLine1m) {
+	invalid := false
+
+	if k.OpenTime%minuteMs != 0 {
+		report.addIssue(k, IssueMisaligned, fmt.Sprintf("open_time=%d 不是整分钟", k.OpenTime))
+		invalid = true
+	} else if k.CloseTime != k.OpenTime+minuteMs-1 {
+		report.addIssue(k, IssueMisaligned, fmt.Sprintf("close_time=%d，应为 %d", k.CloseTime, k.OpenTime+minuteMs-1))
+		invalid = true
+	}
+
+	if k.Open <= 0 || k.High <= 0 || k.Low <= 0 || k.Close <= 0 || k.Volume < 0 {
+		report.addIssue(k, IssueInvalidPrice, fmt.Sprintf("open=%.8f, high=%.8f, low=%.8f, close=%.8f, volume=%.8f",
+			k.Open, k.High, k.Low, k.Close, k.Volume))
+		invalid = true
+	} else if k.Low > min(k.Open, k.Close) || k.High < max(k.Open, k.Close) || k.Low > k.High {
+		report.addIssue(k, IssueOHLC, fmt.Sprintf("open=%.8f, high=%.8f, low=%.8f, close=%.8f",
+			k.Open, k.High, k.Low, k.Close))
+		invalid = true
+	}
+
+	if prev != nil {
+		prevMinute := prev.OpenTime / minuteMs * minuteMs
+		minute := k.OpenTime / minuteMs * minuteMs
+		switch {
+		case minute == prevMinute:
+			report.addIssue(k, IssueDuplicate, fmt.Sprintf("与 open_time=%d 属于同一分钟", prev.OpenTime))
+			invalid = true
+		case minute > prevMinute+minuteMs:
+			report.addMissing(prevMinute+minuteMs, minute-minuteMs)
+		}
+	}
+
+	if invalid {
+		report.invalidOpenTimes = append(report.invalidOpenTimes, k.OpenTime)
+	}
+}
+
+// IntegrityRepairResult 修复结果
+type IntegrityRepairResult struct {
+	ReopenedRanges int `json:"reopenedRanges"` // 从 sync_time_ranges 中移除的时间段数量
+	DeletedRows    int `json:"deletedRows"`    // 删除的问题K线数量
+}
+
+// RepairIntegrity 把扫描发现的问题时间段从 sync_time_ranges 中移除，下次同步时会重新拉取
+// deleteInvalid 为 true 时同时删除问题K线（否则已存在的 open_time 不会被重新拉取的数据覆盖）
+func RepairIntegrity(report *IntegrityReport, deleteInvalid bool) (*IntegrityRepairResult, error) {
+	result := &IntegrityRepairResult{}
+
+	reopen := append([]SyncTimeRange(nil), report.MissingRanges...)
+	for _, openTime := range report.invalidOpenTimes {
+		minute := openTime / minuteMs * minuteMs
+		reopen = append(reopen, SyncTimeRange{StartTime: minute, EndTime: minute + minuteMs - 1})
+	}
+	if len(reopen) == 0 {
+		return result, nil
+	}
+
+	if deleteInvalid && len(report.invalidOpenTimes) > 0 {
+		s, err := currentStore()
+		if err != nil {
+			return result, err
+		}
+		deleted, err := s.DeleteKLines1m(report.Symbol, report.invalidOpenTimes)
+		if err != nil {
+			return result, fmt.Errorf("删除问题K线失败: %w", err)
+		}
+		result.DeletedRows = deleted
+	}
+
+	if err := ReopenSyncTimeRanges(report.Symbol, reopen); err != nil {
+		return result, err
+	}
+	result.ReopenedRanges = len(reopen)
+
+	logger.Infof("[%s] 完整性修复: 重新打开 %d 个时间段, 删除 %d 条问题K线",
+		report.Symbol, result.ReopenedRanges, result.DeletedRows)
+	return result, nil
+}
+
+// ScanIntegrityFromConfig 扫描配置文件中所有启用的币种
+func ScanIntegrityFromConfig(startTime, endTime int64) ([]*IntegrityReport, error) {
+	allSymbols, err := config.GetAllEnabledSymbols()
+	if err != nil {
+		return nil, fmt.Errorf("获取币种配置失败: %w", err)
+	}
+
+	reports := make([]*IntegrityReport, 0, len(allSymbols))
+	for _, symbolConfig := range allSymbols {
+		started := time.Now()
+		report, err := ScanIntegrity(symbolConfig.Symbol, startTime, endTime)
+		if err != nil {
+			return reports, fmt.Errorf("扫描失败 (币种: %s): %w", symbolConfig.Symbol, err)
+		}
+		logger.Infof("[%s] 完整性扫描完成: 行数=%d, 缺失分钟=%d, 问题K线=%d, 耗时=%s",
+			symbolConfig.Symbol, report.TotalRows, report.MissingMinutes, len(report.invalidOpenTimes),
+			time.Since(started).Round(time.Millisecond))
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
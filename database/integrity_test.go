@@ -0,0 +1,261 @@
+package database
+
+import (
+	"testing"
+)
+
+// minuteSpan 第 from 到 to 分钟（含）的时间段
+func minuteSpan(from, to int) SyncTimeRange {
+	return SyncTimeRange{StartTime: minuteAt(from), EndTime: minuteAt(to) + minuteMs - 1}
+}
+
+func TestScanIntegrityIssues(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+
+	klines := append(testKLines(symbol, 0, 2), testKLines(symbol, 5, 9)...) // 缺失第 3、4 分钟
+	klines[3].CloseTime = minuteAt(5) + 1000                                // 第 5 分钟 close_time 不对
+	klines[4].Low = klines[4].Close + 1                                     // 第 6 分钟 low > close
+	klines[5].Open = 0                                                      // 第 7 分钟价格为 0
+	misaligned := testKLine(symbol, 9, 109)
+	misaligned.OpenTime += 5000 // 与第 9 分钟重复，且不是整分钟
+	klines = append(klines, misaligned)
+	if _, err := SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ScanIntegrity(symbol, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalRows != 9 || report.StartTime != minuteAt(0) || report.EndTime != misaligned.OpenTime {
+		t.Errorf("TotalRows/StartTime/EndTime = %d/%d/%d", report.TotalRows, report.StartTime, report.EndTime)
+	}
+	if report.MissingMinutes != 2 {
+		t.Errorf("MissingMinutes = %d，期望 2", report.MissingMinutes)
+	}
+	assertRanges(t, report.MissingRanges, []SyncTimeRange{minuteSpan(3, 4)})
+
+	wantCounts := map[string]int{IssueMisaligned: 2, IssueOHLC: 1, IssueInvalidPrice: 1, IssueDuplicate: 1}
+	if len(report.IssueCounts) != len(wantCounts) {
+		t.Errorf("IssueCounts = %v，期望 %v", report.IssueCounts, wantCounts)
+	}
+	for issue, want := range wantCounts {
+		if report.IssueCounts[issue] != want {
+			t.Errorf("IssueCounts[%s] = %d，期望 %d", issue, report.IssueCounts[issue], want)
+		}
+	}
+	// 同一根K线的多个问题只需要重新拉取一次
+	wantInvalid := []int64{minuteAt(5), minuteAt(6), minuteAt(7), misaligned.OpenTime}
+	if len(report.invalidOpenTimes) != len(wantInvalid) {
+		t.Fatalf("invalidOpenTimes = %v，期望 %v", report.invalidOpenTimes, wantInvalid)
+	}
+	for i, want := range wantInvalid {
+		if report.invalidOpenTimes[i] != want {
+			t.Fatalf("invalidOpenTimes = %v，期望 %v", report.invalidOpenTimes, wantInvalid)
+		}
+	}
+	if report.Healthy() {
+		t.Error("有问题的报告不应是 Healthy")
+	}
+}
+
+func TestScanIntegrityBounds(t *testing.T) {
+	const symbol = "BTC_USDT"
+	tests := []struct {
+		name         string
+		klines       []KLine1m
+		purgedBefore int64
+		start, end   int64
+		want         []SyncTimeRange
+	}{
+		{
+			name:   "不限范围时不检查两端",
+			klines: testKLines(symbol, 5, 9),
+		},
+		{
+			name:   "开始边界到第一根K线、最后一根K线到结束边界",
+			klines: testKLines(symbol, 5, 9),
+			start:  minuteAt(0), end: minuteAt(14) + 59999,
+			want: []SyncTimeRange{minuteSpan(0, 4), minuteSpan(10, 14)},
+		},
+		{
+			name:   "两端和中间都缺失",
+			klines: append(testKLines(symbol, 5, 6), testKLines(symbol, 9, 9)...),
+			start:  minuteAt(3), end: minuteAt(10),
+			want: []SyncTimeRange{minuteSpan(3, 4), minuteSpan(7, 8), minuteSpan(10, 10)},
+		},
+		{
+			name:   "非整分钟的边界只检查完整包含的分钟",
+			klines: testKLines(symbol, 5, 9),
+			start:  minuteAt(2) + 1, end: minuteAt(12) - 1,
+			want: []SyncTimeRange{minuteSpan(3, 4), minuteSpan(10, 11)},
+		},
+		{
+			name:   "边界与数据对齐时没有缺失",
+			klines: testKLines(symbol, 5, 9),
+			start:  minuteAt(5), end: minuteAt(9),
+		},
+		{
+			name:   "只指定开始时间",
+			klines: testKLines(symbol, 5, 9),
+			start:  minuteAt(0),
+			want:   []SyncTimeRange{minuteSpan(0, 4)},
+		},
+		{
+			name:   "只指定结束时间",
+			klines: testKLines(symbol, 5, 9),
+			end:    minuteAt(11),
+			want:   []SyncTimeRange{minuteSpan(10, 11)},
+		},
+		{
+			name:         "已按保留策略清理的分钟不算缺失",
+			klines:       testKLines(symbol, 5, 9),
+			purgedBefore: minuteAt(3),
+			start:        minuteAt(0), end: minuteAt(9),
+			want: []SyncTimeRange{minuteSpan(3, 4)},
+		},
+		{
+			name:  "范围内没有数据",
+			start: minuteAt(0), end: minuteAt(9),
+			want: []SyncTimeRange{minuteSpan(0, 9)},
+		},
+		{
+			name:  "范围内没有数据且只指定开始时间",
+			start: minuteAt(0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := useMemoryStore(t)
+			if len(tt.klines) > 0 {
+				if _, err := SaveKLine1m(tt.klines); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.purgedBefore > 0 {
+				m.SetPurgedBefore(symbol, tt.purgedBefore)
+			}
+
+			report, err := ScanIntegrity(symbol, tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRanges(t, report.MissingRanges, tt.want)
+			wantMinutes := 0
+			for _, r := range tt.want {
+				wantMinutes += int((r.EndTime - r.StartTime + 1) / minuteMs)
+			}
+			if report.MissingMinutes != wantMinutes {
+				t.Errorf("MissingMinutes = %d，期望 %d", report.MissingMinutes, wantMinutes)
+			}
+		})
+	}
+}
+
+func TestScanIntegrityMissingInSyncedRanges(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+	if _, err := SaveKLine1m(append(testKLines(symbol, 0, 2), testKLines(symbol, 8, 9)...)); err != nil {
+		t.Fatal(err)
+	}
+	// 第 3-7 分钟缺失，其中第 3-5 分钟被记录为已同步
+	if err := AddSyncTimeRange(symbol, minuteAt(0), minuteAt(6)-1); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ScanIntegrity(symbol, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MissingMinutes != 5 || report.MissingInSyncedRanges != 3 {
+		t.Errorf("MissingMinutes/MissingInSyncedRanges = %d/%d，期望 5/3", report.MissingMinutes, report.MissingInSyncedRanges)
+	}
+}
+
+func TestRepairIntegrity(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+
+	klines := testKLines(symbol, 5, 9)
+	klines[2].High = klines[2].Close - 1 // 第 7 分钟 high < close
+	if _, err := SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSyncTimeRange(symbol, minuteAt(0), minuteAt(15)-1); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ScanIntegrity(symbol, minuteAt(0), minuteAt(14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MissingInSyncedRanges != 10 {
+		t.Errorf("MissingInSyncedRanges = %d，期望 10", report.MissingInSyncedRanges)
+	}
+
+	result, err := RepairIntegrity(report, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两端缺失的分钟和问题K线所在的分钟都被重新打开
+	if result.ReopenedRanges != 3 || result.DeletedRows != 1 {
+		t.Errorf("ReopenedRanges/DeletedRows = %d/%d，期望 3/1", result.ReopenedRanges, result.DeletedRows)
+	}
+	ranges, err := GetSyncTimeRanges(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRanges(t, ranges, []SyncTimeRange{minuteSpan(5, 6), minuteSpan(8, 9)})
+
+	remaining, err := GetKLines1m(symbol, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 4 {
+		t.Errorf("删除后剩余 %d 根K线，期望 4", len(remaining))
+	}
+	for _, k := range remaining {
+		if k.OpenTime == minuteAt(7) {
+			t.Error("问题K线应被删除")
+		}
+	}
+
+	// 缺失的分钟会被重新同步
+	missing, err := FindMissingRanges(symbol, minuteAt(0), minuteAt(15)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRanges(t, missing, []SyncTimeRange{minuteSpan(0, 4), minuteSpan(7, 7), minuteSpan(10, 14)})
+}
+
+func TestRepairIntegrityKeepsRowsWithoutDelete(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+
+	klines := testKLines(symbol, 0, 2)
+	klines[1].Low = klines[1].Open + 1
+	if _, err := SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSyncTimeRange(symbol, minuteAt(0), minuteAt(3)-1); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ScanIntegrity(symbol, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := RepairIntegrity(report, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ReopenedRanges != 1 || result.DeletedRows != 0 {
+		t.Errorf("ReopenedRanges/DeletedRows = %d/%d，期望 1/0", result.ReopenedRanges, result.DeletedRows)
+	}
+	ranges, _ := GetSyncTimeRanges(symbol)
+	assertRanges(t, ranges, []SyncTimeRange{minuteSpan(0, 0), minuteSpan(2, 2)})
+	if remaining, _ := GetKLines1m(symbol, 0, 0, 0); len(remaining) != 3 {
+		t.Errorf("不删除时应保留全部 %d 根K线，实际 %d 根", 3, len(remaining))
+	}
+}
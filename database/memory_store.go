@@ -0,0 +1,563 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDSN 内存存储的 DSN
+const MemoryDSN = "memory://"
+
+// memorySyncStatus 内存中的同步状态
+type memorySyncStatus struct {
+	lastSyncTime  int64
+	lastKlineTime int64
+	syncCount     int
+	purgedBefore  int64
+}
+
+// MemoryStore 内存K线存储
+// 数据只保存在进程内，重启后丢失；用于无数据库的演示模式和测试
+type MemoryStore struct {
+	mu         sync.RWMutex
+	klines     map[string][]KLine1m // key: 表名，value: 按 open_time 升序排列的K线
+	syncStatus map[string]memorySyncStatus
+	timeRanges map[string][]SyncTimeRange
+	failed     map[string][]FailedRange // key: symbol
+	backfill   map[string]BackfillCursor
+	quarantine map[string]map[int64]QuarantinedKLine // key: symbol, open_time
+	symbolMeta map[string]SymbolMeta                 // key: symbol
+	inactive   map[string]InactiveSymbol             // key: symbol
+}
+
+// NewMemoryStore 创建内存K线存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		klines:     make(map[string][]KLine1m),
+		syncStatus: make(map[string]memorySyncStatus),
+		timeRanges: make(map[string][]SyncTimeRange),
+		failed:     make(map[string][]FailedRange),
+		backfill:   make(map[string]BackfillCursor),
+		quarantine: make(map[string]map[int64]QuarantinedKLine),
+		symbolMeta: make(map[string]SymbolMeta),
+		inactive:   make(map[string]InactiveSymbol),
+	}
+}
+
+// IsMemoryDSN 判断 DSN 是否指向内存存储
+func IsMemoryDSN(dsn string) bool {
+	return dsn == MemoryDSN
+}
+
+// InitSchema 内存存储无需建表
+func (m *MemoryStore) InitSchema() error {
+	return nil
+}
+
+// CreateTableForSymbol 为指定币种预留存储空间
+func (m *MemoryStore) CreateTableForSymbol(symbol string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tableName := GetTableName(symbol)
+	if _, ok := m.klines[tableName]; !ok {
+		m.klines[tableName] = nil
+	}
+	return nil
+}
+
+// SaveKLine1m 保存1分钟K线数据（忽略 open_time 已存在的数据）
+func (m *MemoryStore) SaveKLine1m(klines []KLine1m) (*SaveKLine1mResult, error) {
+	result := &SaveKLine1mResult{}
+	if len(klines) == 0 {
+		return result, nil
+	}
+
+	// 按表名（币种）分组K线数据
+	klinesByTable := make(map[string][]KLine1m)
+	for _, k := range klines {
+		tableName := GetTableName(k.Symbol)
+		klinesByTable[tableName] = append(klinesByTable[tableName], k)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tableName, tableKLines := range klinesByTable {
+		insertedCount := 0
+		skippedCount := 0
+		stored := m.klines[tableName]
+
+		for _, k := range tableKLines {
+			// 大部分情况下新数据都在末尾，直接追加
+			if len(stored) == 0 || k.OpenTime > stored[len(stored)-1].OpenTime {
+				stored = append(stored, k)
+				insertedCount++
+				continue
+			}
+
+			idx := sort.Search(len(stored), func(i int) bool {
+				return stored[i].OpenTime >= k.OpenTime
+			})
+			if idx < len(stored) && stored[idx].OpenTime == k.OpenTime {
+				skippedCount++ // 数据已存在，被忽略
+				continue
+			}
+			stored = append(stored, KLine1m{})
+			copy(stored[idx+1:], stored[idx:])
+			stored[idx] = k
+			insertedCount++
+		}
+
+		m.klines[tableName] = stored
+		result.InsertedCount += insertedCount
+		result.SkippedCount += skippedCount
+
+		logBatchStats(tableName, tableKLines, insertedCount, skippedCount, 0)
+	}
+
+	return result, nil
+}
+
+// UpsertKLine1m 保存1分钟K线数据（open_time 已存在且 OHLCV 不同时覆盖）
+func (m *MemoryStore) UpsertKLine1m(klines []KLine1m) (*SaveKLine1mResult, error) {
+	result := &SaveKLine1mResult{}
+	if len(klines) == 0 {
+		return result, nil
+	}
+
+	klinesByTable := make(map[string][]KLine1m)
+	for _, k := range klines {
+		tableName := GetTableName(k.Symbol)
+		klinesByTable[tableName] = append(klinesByTable[tableName], k)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tableName, tableKLines := range klinesByTable {
+		insertedCount, updatedCount, skippedCount := 0, 0, 0
+		stored := m.klines[tableName]
+
+		for _, k := range tableKLines {
+			idx := sort.Search(len(stored), func(i int) bool {
+				return stored[i].OpenTime >= k.OpenTime
+			})
+			if idx < len(stored) && stored[idx].OpenTime == k.OpenTime {
+				if klineChanged(stored[idx], k) {
+					stored[idx] = k
+					updatedCount++
+				} else {
+					skippedCount++
+				}
+				continue
+			}
+			stored = append(stored, KLine1m{})
+			copy(stored[idx+1:], stored[idx:])
+			stored[idx] = k
+			insertedCount++
+		}
+
+		m.klines[tableName] = stored
+		result.InsertedCount += insertedCount
+		result.UpdatedCount += updatedCount
+		result.SkippedCount += skippedCount
+
+		logUpsertStats(tableName, len(tableKLines), insertedCount, updatedCount, skippedCount, 0)
+	}
+
+	return result, nil
+}
+
+// DeleteKLines1m 按开盘时间删除1分钟K线
+func (m *MemoryStore) DeleteKLines1m(symbol string, openTimes []int64) (int, error) {
+	remove := make(map[int64]bool, len(openTimes))
+	for _, t := range openTimes {
+		remove[t] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tableName := GetTableName(symbol)
+	stored := m.klines[tableName]
+	kept := stored[:0]
+	for _, k := range stored {
+		if !remove[k.OpenTime] {
+			kept = append(kept, k)
+		}
+	}
+	m.klines[tableName] = kept
+	return len(stored) - len(kept), nil
+}
+
+// DeleteKLines1mRange 删除开盘时间在 [startTime, endTime] 范围内的1分钟K线
+func (m *MemoryStore) DeleteKLines1mRange(symbol string, startTime, endTime int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tableName := GetTableName(symbol)
+	stored := m.klines[tableName]
+	kept := stored[:0]
+	for _, k := range stored {
+		if k.OpenTime < startTime || k.OpenTime > endTime {
+			kept = append(kept, k)
+		}
+	}
+	m.klines[tableName] = kept
+	return len(stored) - len(kept), nil
+}
+
+// withSymbol 复制K线并设置币种（与 SQL 实现一致，使用查询时传入的 symbol）
+func withSymbol(klines []KLine1m, symbol string) []KLine1m {
+	result := make([]KLine1m, len(klines))
+	for i, k := range klines {
+		k.Symbol = symbol
+		result[i] = k
+	}
+	return result
+}
+
+// GetKLines1m 获取1分钟K线数据（按时间范围）
+func (m *MemoryStore) GetKLines1m(symbol string, startTime, endTime int64, limit int) ([]KLine1m, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.klines[GetTableName(symbol)]
+	from := 0
+	if startTime > 0 {
+		from = sort.Search(len(stored), func(i int) bool {
+			return stored[i].OpenTime >= startTime
+		})
+	}
+	to := len(stored)
+	if endTime > 0 {
+		to = sort.Search(len(stored), func(i int) bool {
+			return stored[i].OpenTime > endTime
+		})
+	}
+	if from >= to {
+		return []KLine1m{}, nil
+	}
+	if limit > 0 && to-from > limit {
+		to = from + limit
+	}
+
+	return withSymbol(stored[from:to], symbol), nil
+}
+
+// GetKLines1mByCount 获取最近N根1分钟K线
+func (m *MemoryStore) GetKLines1mByCount(symbol string, count int) ([]KLine1m, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if count <= 0 {
+		return []KLine1m{}, nil
+	}
+	stored := m.klines[GetTableName(symbol)]
+	if count < len(stored) {
+		stored = stored[len(stored)-count:]
+	}
+	return withSymbol(stored, symbol), nil
+}
+
+// GetLatestKLine1m 获取最新的1分钟K线数据（单条）
+func (m *MemoryStore) GetLatestKLine1m(symbol string) (*KLine1m, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.klines[GetTableName(symbol)]
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	k := stored[len(stored)-1]
+	k.Symbol = symbol
+	return &k, nil
+}
+
+// GetLatestKLineTime 获取指定交易对的最新K线时间
+func (m *MemoryStore) GetLatestKLineTime(symbol string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored := m.klines[GetTableName(symbol)]
+	if len(stored) == 0 {
+		return 0, nil
+	}
+	return stored[len(stored)-1].CloseTime, nil
+}
+
+// UpdateSyncStatus 更新同步状态
+func (m *MemoryStore) UpdateSyncStatus(symbol string, lastSyncTime, lastKlineTime int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.syncStatus[symbol]
+	status.lastSyncTime = lastSyncTime
+	status.lastKlineTime = lastKlineTime
+	status.syncCount++
+	m.syncStatus[symbol] = status
+	return nil
+}
+
+// GetSyncStatus 获取同步状态
+func (m *MemoryStore) GetSyncStatus(symbol string) (lastSyncTime, lastKlineTime int64, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.syncStatus[symbol]
+	return status.lastSyncTime, status.lastKlineTime, nil
+}
+
+// SetPurgedBefore 记录清理边界（只会增大）
+func (m *MemoryStore) SetPurgedBefore(symbol string, purgedBefore int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.syncStatus[symbol]
+	status.purgedBefore = max(status.purgedBefore, purgedBefore)
+	m.syncStatus[symbol] = status
+	return nil
+}
+
+// GetPurgedBefore 获取清理边界
+func (m *MemoryStore) GetPurgedBefore(symbol string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.syncStatus[symbol].purgedBefore, nil
+}
+
+// AddSyncTimeRange 添加已同步的时间段
+func (m *MemoryStore) AddSyncTimeRange(symbol string, startTime, endTime int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ranges := append(m.timeRanges[symbol], SyncTimeRange{StartTime: startTime, EndTime: endTime})
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].StartTime < ranges[j].StartTime
+	})
+	m.timeRanges[symbol] = ranges
+	return nil
+}
+
+// GetSyncTimeRanges 获取指定币种的所有已同步时间段（按开始时间排序）
+func (m *MemoryStore) GetSyncTimeRanges(symbol string) ([]SyncTimeRange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ranges := m.timeRanges[symbol]
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	return append([]SyncTimeRange(nil), ranges...), nil
+}
+
+// ReplaceSyncTimeRanges 替换指定币种的全部同步时间段
+func (m *MemoryStore) ReplaceSyncTimeRanges(symbol string, ranges []SyncTimeRange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replaced := append([]SyncTimeRange(nil), ranges...)
+	sort.SliceStable(replaced, func(i, j int) bool {
+		return replaced[i].StartTime < replaced[j].StartTime
+	})
+	m.timeRanges[symbol] = replaced
+	return nil
+}
+
+// RecordFailedRange 记录同步失败的时间段
+func (m *MemoryStore) RecordFailedRange(symbol string, startTime, endTime int64, errorKind, errorMessage string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for i, r := range m.failed[symbol] {
+		if r.StartTime == startTime && r.EndTime == endTime {
+			r.ErrorKind = errorKind
+			r.ErrorMessage = truncateMessage(errorMessage)
+			r.Attempts++
+			r.LastFailedAt = now
+			m.failed[symbol][i] = r
+			return nil
+		}
+	}
+	m.failed[symbol] = append(m.failed[symbol], FailedRange{
+		Symbol:        symbol,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		ErrorKind:     errorKind,
+		ErrorMessage:  truncateMessage(errorMessage),
+		Attempts:      1,
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	})
+	return nil
+}
+
+// GetFailedRanges 获取同步失败的时间段（按最后失败时间排序）
+func (m *MemoryStore) GetFailedRanges(symbol string) ([]FailedRange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ranges []FailedRange
+	for s, list := range m.failed {
+		if symbol == "" || s == symbol {
+			ranges = append(ranges, list...)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].LastFailedAt < ranges[j].LastFailedAt
+	})
+	return ranges, nil
+}
+
+// DeleteFailedRanges 删除完全落在 [startTime, endTime] 内的失败时间段
+func (m *MemoryStore) DeleteFailedRanges(symbol string, startTime, endTime int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.failed[symbol][:0]
+	deleted := 0
+	for _, r := range m.failed[symbol] {
+		if r.StartTime >= startTime && r.EndTime <= endTime {
+			deleted++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	m.failed[symbol] = kept
+	return deleted, nil
+}
+
+// GetBackfillCursor 获取币种的回填进度
+func (m *MemoryStore) GetBackfillCursor(symbol string) (*BackfillCursor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cursor, ok := m.backfill[symbol]
+	if !ok {
+		return nil, nil
+	}
+	return &cursor, nil
+}
+
+// GetBackfillCursors 获取所有币种的回填进度（按币种排序）
+func (m *MemoryStore) GetBackfillCursors() ([]BackfillCursor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cursors := make([]BackfillCursor, 0, len(m.backfill))
+	for _, cursor := range m.backfill {
+		cursors = append(cursors, cursor)
+	}
+	sort.Slice(cursors, func(i, j int) bool {
+		return cursors[i].Symbol < cursors[j].Symbol
+	})
+	return cursors, nil
+}
+
+// SaveBackfillCursor 保存回填进度
+func (m *MemoryStore) SaveBackfillCursor(cursor BackfillCursor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cursor.LastError = truncateMessage(cursor.LastError)
+	m.backfill[cursor.Symbol] = cursor
+	return nil
+}
+
+// QuarantineKLines 把可疑的K线写入隔离表
+func (m *MemoryStore) QuarantineKLines(rows []QuarantinedKLine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range rows {
+		if m.quarantine[r.Symbol] == nil {
+			m.quarantine[r.Symbol] = make(map[int64]QuarantinedKLine)
+		}
+		m.quarantine[r.Symbol][r.OpenTime] = r
+	}
+	return nil
+}
+
+// GetQuarantinedKLines 查询隔离的K线（按币种、开盘时间排序）
+func (m *MemoryStore) GetQuarantinedKLines(symbol string, startTime, endTime int64) ([]QuarantinedKLine, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []QuarantinedKLine
+	for s, rows := range m.quarantine {
+		if symbol != "" && s != symbol {
+			continue
+		}
+		for openTime, r := range rows {
+			if openTime >= startTime && (endTime <= 0 || openTime <= endTime) {
+				result = append(result, r)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Symbol != result[j].Symbol {
+			return result[i].Symbol < result[j].Symbol
+		}
+		return result[i].OpenTime < result[j].OpenTime
+	})
+	return result, nil
+}
+
+// SaveSymbolMetadata 批量保存交易对元数据
+func (m *MemoryStore) SaveSymbolMetadata(metas []SymbolMeta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, meta := range metas {
+		m.symbolMeta[meta.Symbol] = meta
+	}
+	return nil
+}
+
+// GetSymbolMetadata 获取所有交易对元数据（按交易对排序）
+func (m *MemoryStore) GetSymbolMetadata() ([]SymbolMeta, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	metas := make([]SymbolMeta, 0, len(m.symbolMeta))
+	for _, meta := range m.symbolMeta {
+		metas = append(metas, meta)
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].Symbol < metas[j].Symbol
+	})
+	return metas, nil
+}
+
+// SaveInactiveSymbol 保存停止同步的交易对
+func (m *MemoryStore) SaveInactiveSymbol(symbol InactiveSymbol) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	symbol.Reason = truncateMessage(symbol.Reason)
+	m.inactive[symbol.Symbol] = symbol
+	return nil
+}
+
+// DeleteInactiveSymbol 删除停止同步的记录
+func (m *MemoryStore) DeleteInactiveSymbol(symbol string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.inactive[symbol]
+	delete(m.inactive, symbol)
+	return ok, nil
+}
+
+// GetInactiveSymbols 获取所有停止同步的交易对（按交易对排序）
+func (m *MemoryStore) GetInactiveSymbols() ([]InactiveSymbol, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	symbols := make([]InactiveSymbol, 0, len(m.inactive))
+	for _, symbol := range m.inactive {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Symbol < symbols[j].Symbol
+	})
+	return symbols, nil
+}
+
+// Close 内存存储无需释放资源
+func (m *MemoryStore) Close() error {
+	return nil
+}
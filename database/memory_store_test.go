@@ -0,0 +1,220 @@
+package database
+
+import (
+	"testing"
+)
+
+// testBase 测试数据的起始时间（按天对齐，2023-11-15 00:00 UTC）
+const testBase = int64(1700006400000)
+
+// minuteAt 从 testBase 开始的第 n 分钟的开盘时间
+func minuteAt(n int) int64 {
+	return testBase + int64(n)*60000
+}
+
+// useMemoryStore 使用新的内存存储作为当前存储后端，测试结束后恢复
+func useMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	previous := GetStore()
+	m := NewMemoryStore()
+	SetStore(m)
+	t.Cleanup(func() { SetStore(previous) })
+	return m
+}
+
+// testKLine 第 n 分钟的1分钟K线，收盘价为 close
+func testKLine(symbol string, n int, close float64) KLine1m {
+	openTime := minuteAt(n)
+	return KLine1m{
+		Symbol: symbol, OpenTime: openTime,
+		Open: close, High: close + 1, Low: close - 1, Close: close,
+		Volume: 1, QuoteVolume: close, TradeCount: 1,
+		CloseTime: openTime + 59999,
+	}
+}
+
+// testKLines 第 from 到 to 分钟（含）的连续1分钟K线，收盘价为 100 + 分钟序号
+func testKLines(symbol string, from, to int) []KLine1m {
+	var klines []KLine1m
+	for n := from; n <= to; n++ {
+		klines = append(klines, testKLine(symbol, n, 100+float64(n)))
+	}
+	return klines
+}
+
+func TestMemoryStoreSaveKLine1mDedupe(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+
+	result, err := SaveKLine1m(testKLines(symbol, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InsertedCount != 3 || result.SkippedCount != 0 {
+		t.Fatalf("第一次保存: %+v，期望插入 3 条", result)
+	}
+
+	// 已存在的 open_time 被忽略（即使数据不同），批次内重复的只保存第一条，乱序的按时间插入
+	batch := []KLine1m{testKLine(symbol, 1, 999), testKLine(symbol, 2, 102), testKLine(symbol, 5, 105), testKLine(symbol, 4, 104), testKLine(symbol, 5, 555)}
+	result, err = SaveKLine1m(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InsertedCount != 2 || result.SkippedCount != 3 {
+		t.Fatalf("第二次保存: %+v，期望插入 2 条、跳过 3 条", result)
+	}
+
+	klines, err := GetKLines1m(symbol, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCloses := map[int64]float64{minuteAt(0): 100, minuteAt(1): 101, minuteAt(2): 102, minuteAt(4): 104, minuteAt(5): 105}
+	if len(klines) != len(wantCloses) {
+		t.Fatalf("K线数量 = %d，期望 %d", len(klines), len(wantCloses))
+	}
+	for i, k := range klines {
+		if i > 0 && k.OpenTime <= klines[i-1].OpenTime {
+			t.Errorf("K线没有按开盘时间升序排列: %d 在 %d 之后", k.OpenTime, klines[i-1].OpenTime)
+		}
+		if want, ok := wantCloses[k.OpenTime]; !ok || k.Close != want {
+			t.Errorf("%d 的收盘价 = %v，期望 %v", k.OpenTime, k.Close, want)
+		}
+	}
+}
+
+func TestMemoryStoreGetKLines1mByCount(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "ETH_USDT"
+	if _, err := SaveKLine1m(testKLines(symbol, 0, 9)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		count     int
+		wantFirst int // 第一根K线的分钟序号
+		wantLen   int
+	}{
+		{count: 3, wantFirst: 7, wantLen: 3},
+		{count: 10, wantFirst: 0, wantLen: 10},
+		{count: 100, wantFirst: 0, wantLen: 10},
+		{count: 0, wantLen: 0},
+		{count: -1, wantLen: 0},
+	}
+	for _, tt := range tests {
+		klines, err := GetKLines1mByCount(symbol, tt.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(klines) != tt.wantLen {
+			t.Errorf("count=%d: K线数量 = %d，期望 %d", tt.count, len(klines), tt.wantLen)
+			continue
+		}
+		for i, k := range klines {
+			if k.OpenTime != minuteAt(tt.wantFirst+i) || k.Symbol != symbol {
+				t.Errorf("count=%d: 第 %d 根K线 = %d (%s)，期望 %d", tt.count, i, k.OpenTime, k.Symbol, minuteAt(tt.wantFirst+i))
+			}
+		}
+	}
+
+	if klines, err := GetKLines1mByCount("NONE_USDT", 5); err != nil || len(klines) != 0 {
+		t.Errorf("没有数据的币种: %v, %v", klines, err)
+	}
+}
+
+func TestFindMissingRanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		synced       []SyncTimeRange
+		purgedBefore int64
+		start, end   int64
+		want         []SyncTimeRange
+	}{
+		{
+			name:  "没有同步记录",
+			start: 0, end: 999,
+			want: []SyncTimeRange{{0, 999}},
+		},
+		{
+			name:   "完全覆盖",
+			synced: []SyncTimeRange{{0, 999}},
+			start:  100, end: 200,
+		},
+		{
+			name:   "中间和两端缺失",
+			synced: []SyncTimeRange{{100, 199}, {300, 399}},
+			start:  0, end: 999,
+			want: []SyncTimeRange{{0, 99}, {200, 299}, {400, 999}},
+		},
+		{
+			name:   "重叠的同步记录",
+			synced: []SyncTimeRange{{0, 500}, {100, 200}, {450, 600}},
+			start:  0, end: 999,
+			want: []SyncTimeRange{{601, 999}},
+		},
+		{
+			name:   "同步记录在目标范围之外",
+			synced: []SyncTimeRange{{2000, 3000}},
+			start:  0, end: 999,
+			want: []SyncTimeRange{{0, 999}},
+		},
+		{
+			name:         "清理边界之前的不再拉取",
+			synced:       []SyncTimeRange{{600, 699}},
+			purgedBefore: 500,
+			start:        0, end: 999,
+			want: []SyncTimeRange{{500, 599}, {700, 999}},
+		},
+		{
+			name:         "目标范围全部已清理",
+			purgedBefore: 5000,
+			start:        0, end: 999,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := useMemoryStore(t)
+			const symbol = "BTC_USDT"
+			for _, r := range tt.synced {
+				m.AddSyncTimeRange(symbol, r.StartTime, r.EndTime)
+			}
+			m.SetPurgedBefore(symbol, tt.purgedBefore)
+
+			got, err := FindMissingRanges(symbol, tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRanges(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergeAdjacentRanges(t *testing.T) {
+	m := useMemoryStore(t)
+	const symbol = "BTC_USDT"
+	// 相连（下一段从上一段结束后 1ms 开始）和重叠的合并，相隔 2ms 的不合并
+	for _, r := range []SyncTimeRange{{300, 399}, {0, 99}, {100, 199}, {150, 250}, {401, 500}} {
+		m.AddSyncTimeRange(symbol, r.StartTime, r.EndTime)
+	}
+
+	mergeAdjacentRanges(m, symbol)
+
+	got, err := GetSyncTimeRanges(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRanges(t, got, []SyncTimeRange{{0, 250}, {300, 399}, {401, 500}})
+}
+
+// assertRanges 比较时间段列表
+func assertRanges(t *testing.T, got, want []SyncTimeRange) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("时间段 = %v，期望 %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("时间段 = %v，期望 %v", got, want)
+		}
+	}
+}
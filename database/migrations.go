@@ -0,0 +1,337 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// ErrMigrationNotSupported 当前存储后端不支持数据库迁移（如内存存储）
+var ErrMigrationNotSupported = errors.New("当前存储后端不支持数据库迁移")
+
+// migration 一个数据库迁移（只支持升级）
+// 建表语句（schemaSQL、createKLineTableSQL）始终保持最新结构，迁移负责把已有的旧表升级到相同结构，
+// 因此迁移步骤必须是幂等的（如 addColumn 会先检查列是否存在），新建的表执行迁移时不会出错
+type migration struct {
+	Version int
+	Name    string
+	// Global 对全局表执行（可为 nil）
+	Global func(m *migrator) error
+	// KLine 对每张K线表执行，包括 klines_1m_* 及其聚合表（可为 nil），symbol 为表所属的币种
+	KLine func(m *migrator, symbol, tableName string) error
+}
+
+// migrations 所有迁移（按版本号升序排列，新增迁移只能追加到末尾）
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		// 基线版本：当前建表语句的结构，无需变更
+	},
+	{
+		Version: 2,
+		Name:    "sync_status_purged_before",
+		Global: func(m *migrator) error {
+			definition := "BIGINT NOT NULL DEFAULT 0 COMMENT '早于该时间的1分钟K线已清理（毫秒时间戳）'"
+			if m.dialect.name == "sqlite" {
+				definition = "INTEGER NOT NULL DEFAULT 0"
+			}
+			return m.addColumn("sync_status", "purged_before", definition)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "kline_quote_volume_trade_count",
+		// 已有的行成交额和成交笔数为 0，Gate.io 币种的旧数据由迁移 4 修正
+		KLine: func(m *migrator, symbol, tableName string) error {
+			quoteVolume := "DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '成交额（计价币种）'"
+			tradeCount := "BIGINT NOT NULL DEFAULT 0 COMMENT '成交笔数'"
+			if m.dialect.name == "sqlite" {
+				quoteVolume = "REAL NOT NULL DEFAULT 0"
+				tradeCount = "INTEGER NOT NULL DEFAULT 0"
+			}
+			if err := m.addColumn(tableName, "quote_volume", quoteVolume); err != nil {
+				return err
+			}
+			return m.addColumn(tableName, "trade_count", tradeCount)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "gateio_legacy_quote_volume",
+		// 迁移 3 之前 Gate.io（现货和合约）的 volume 列保存的是成交额（计价币种），之后保存成交量（基础币种），
+		// 旧数据移到 quote_volume，成交量置为 0（与交易所不提供成交量时一致），避免同一张表中 volume 有两种含义；
+		// 新数据的成交额总是大于 0（没有成交时成交量也为 0），不会被误改。其他交易所一直保存成交量，不需要修正
+		KLine: func(m *migrator, symbol, tableName string) error {
+			if config.GetSymbolExchange(symbol) != config.DefaultExchange {
+				return nil
+			}
+			return m.exec(fmt.Sprintf("UPDATE %s SET quote_volume = volume, volume = 0 WHERE quote_volume = 0 AND volume <> 0", tableName))
+		},
+	},
+	{
+		Version: 5,
+		Name:    "quarantine_quote_volume_trade_count",
+		// 隔离表与K线表保持相同的字段，隔离的K线重新保存时不丢失成交额和成交笔数
+		Global: func(m *migrator) error {
+			quoteVolume := "DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '成交额（计价币种）'"
+			tradeCount := "BIGINT NOT NULL DEFAULT 0 COMMENT '成交笔数'"
+			if m.dialect.name == "sqlite" {
+				quoteVolume = "REAL NOT NULL DEFAULT 0"
+				tradeCount = "INTEGER NOT NULL DEFAULT 0"
+			}
+			if err := m.addColumn("klines_quarantine", "quote_volume", quoteVolume); err != nil {
+				return err
+			}
+			return m.addColumn("klines_quarantine", "trade_count", tradeCount)
+		},
+	},
+}
+
+// LatestMigrationVersion 最新的迁移版本号
+func LatestMigrationVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationStatus 单个迁移的执行状态
+type MigrationStatus struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt int64  `json:"appliedAt,omitempty"` // 执行时间（毫秒时间戳）
+}
+
+// MigrationReport 迁移状态报告
+type MigrationReport struct {
+	Dialect        string            `json:"dialect"`
+	CurrentVersion int               `json:"currentVersion"`
+	LatestVersion  int               `json:"latestVersion"`
+	KLineTables    []string          `json:"klineTables"`
+	Migrations     []MigrationStatus `json:"migrations"`
+	DryRun         bool              `json:"dryRun"`
+	Statements     []string          `json:"statements"` // 已执行（或 dry-run 时将要执行）的语句
+
+	tableSymbols map[string]string // K线表 → 所属币种
+}
+
+// Pending 返回尚未执行的迁移
+func (r *MigrationReport) Pending() []MigrationStatus {
+	var pending []MigrationStatus
+	for _, m := range r.Migrations {
+		if !m.Applied {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// MigratableStore 支持数据库迁移的存储后端（可选能力，MySQL、SQLite 实现）
+type MigratableStore interface {
+	// Migrate 执行所有未执行的迁移，dryRun 为 true 时只生成报告不修改数据库
+	Migrate(dryRun bool) (*MigrationReport, error)
+	// MigrationStatus 获取迁移状态
+	MigrationStatus() (*MigrationReport, error)
+}
+
+// migratableStore 获取当前支持迁移的存储后端
+func migratableStore() (MigratableStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	ms, ok := s.(MigratableStore)
+	if !ok {
+		return nil, ErrMigrationNotSupported
+	}
+	return ms, nil
+}
+
+// Migrate 执行所有未执行的迁移
+func Migrate(dryRun bool) (*MigrationReport, error) {
+	ms, err := migratableStore()
+	if err != nil {
+		return nil, err
+	}
+	return ms.Migrate(dryRun)
+}
+
+// GetMigrationStatus 获取迁移状态
+func GetMigrationStatus() (*MigrationReport, error) {
+	ms, err := migratableStore()
+	if err != nil {
+		return nil, err
+	}
+	return ms.MigrationStatus()
+}
+
+// migrator 迁移执行器，提供幂等的变更操作（迁移中可通过 dialect.name 区分 mysql / sqlite 的列定义）
+type migrator struct {
+	db         *sql.DB
+	dialect    *sqlDialect
+	dryRun     bool
+	statements []string
+}
+
+// exec 执行一条语句（dry-run 时只记录）
+func (m *migrator) exec(stmt string) error {
+	m.statements = append(m.statements, stmt)
+	if m.dryRun {
+		return nil
+	}
+	if _, err := m.db.Exec(stmt); err != nil {
+		return fmt.Errorf("执行语句失败 (%s): %w", stmt, err)
+	}
+	return nil
+}
+
+// columnExists 检查列是否存在
+func (m *migrator) columnExists(tableName, column string) (bool, error) {
+	var exists bool
+	if err := m.db.QueryRow(m.dialect.columnExistsSQL, tableName, column).Scan(&exists); err != nil {
+		return false, fmt.Errorf("检查列 %s.%s 失败: %w", tableName, column, err)
+	}
+	return exists, nil
+}
+
+// addColumn 添加列（列已存在时跳过）
+func (m *migrator) addColumn(tableName, column, definition string) error {
+	exists, err := m.columnExists(tableName, column)
+	if err != nil || exists {
+		return err
+	}
+	return m.exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, column, definition))
+}
+
+// appliedMigrations 读取已执行的迁移（key: 版本号，value: 执行时间）
+func (s *sqlStore) appliedMigrations() (map[int]int64, error) {
+	rows, err := s.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]int64)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// klineTables 列出所有K线表：klines_1m_* 以及已存在的聚合表，同时返回每张表所属的币种
+func (s *sqlStore) klineTables() ([]string, map[string]string, error) {
+	symbols, err := (&ShardedDB{db: s.db, dialect: s.dialect}).ListSymbolTables()
+	if err != nil {
+		return nil, nil, fmt.Errorf("列出K线表失败: %w", err)
+	}
+
+	var tables []string
+	tableSymbols := make(map[string]string)
+	for _, symbol := range symbols {
+		tables = append(tables, GetTableName(symbol))
+		tableSymbols[GetTableName(symbol)] = symbol
+		for _, level := range aggregateLevels {
+			tableName := GetAggregateTableName(symbol, level.Interval)
+			exists, err := s.tableExists(tableName)
+			if err != nil {
+				return nil, nil, err
+			}
+			if exists {
+				tables = append(tables, tableName)
+				tableSymbols[tableName] = symbol
+			}
+		}
+	}
+	return tables, tableSymbols, nil
+}
+
+// MigrationStatus 获取迁移状态
+func (s *sqlStore) MigrationStatus() (*MigrationReport, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	tables, tableSymbols, err := s.klineTables()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{
+		Dialect:       s.dialect.name,
+		LatestVersion: LatestMigrationVersion(),
+		KLineTables:   tables,
+		tableSymbols:  tableSymbols,
+	}
+	for _, mg := range migrations {
+		appliedAt, ok := applied[mg.Version]
+		report.Migrations = append(report.Migrations, MigrationStatus{
+			Version:   mg.Version,
+			Name:      mg.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+		if ok && mg.Version > report.CurrentVersion {
+			report.CurrentVersion = mg.Version
+		}
+	}
+	return report, nil
+}
+
+// Migrate 按版本顺序执行所有未执行的迁移
+// 每个迁移先处理全局表，再处理每张K线表，全部成功后才写入 schema_migrations；
+// 中途失败时可以直接重新执行（迁移步骤是幂等的）
+func (s *sqlStore) Migrate(dryRun bool) (*MigrationReport, error) {
+	report, err := s.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = dryRun
+
+	m := &migrator{db: s.db, dialect: s.dialect, dryRun: dryRun}
+	for i, mg := range migrations {
+		if report.Migrations[i].Applied {
+			continue
+		}
+
+		if mg.Global != nil {
+			if err := mg.Global(m); err != nil {
+				return report, fmt.Errorf("迁移 %d (%s) 失败: %w", mg.Version, mg.Name, err)
+			}
+		}
+		if mg.KLine != nil {
+			for _, tableName := range report.KLineTables {
+				if err := mg.KLine(m, report.tableSymbols[tableName], tableName); err != nil {
+					return report, fmt.Errorf("迁移 %d (%s) 失败 [表 %s]: %w", mg.Version, mg.Name, tableName, err)
+				}
+			}
+		}
+
+		appliedAt := time.Now().UnixMilli()
+		if !dryRun {
+			if _, err := s.db.Exec(`
+				INSERT INTO schema_migrations (version, name, applied_at)
+				VALUES (?, ?, ?)
+			`, mg.Version, mg.Name, appliedAt); err != nil {
+				return report, fmt.Errorf("记录迁移 %d 失败: %w", mg.Version, err)
+			}
+			report.Migrations[i].Applied = true
+			report.Migrations[i].AppliedAt = appliedAt
+			report.CurrentVersion = mg.Version
+			logger.Infof("数据库迁移完成: version=%d, name=%s", mg.Version, mg.Name)
+		}
+	}
+
+	report.Statements = m.statements
+	return report, nil
+}
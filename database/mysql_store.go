@@ -0,0 +1,273 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// mysqlDialect MySQL 语法
+var mysqlDialect = &sqlDialect{
+	name: "mysql",
+	schemaSQL: []string{
+		// sync_status 表（全局状态表，不需要分表）
+		`
+		CREATE TABLE IF NOT EXISTS sync_status (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			symbol VARCHAR(20) NOT NULL COMMENT '交易对',
+			last_sync_time BIGINT NOT NULL DEFAULT 0 COMMENT '最后同步时间（毫秒时间戳）',
+			last_kline_time BIGINT NOT NULL DEFAULT 0 COMMENT '最后一条K线时间（毫秒时间戳）',
+			sync_count INT NOT NULL DEFAULT 0 COMMENT '同步次数',
+			purged_before BIGINT NOT NULL DEFAULT 0 COMMENT '早于该时间的1分钟K线已清理（毫秒时间戳）',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY uk_symbol (symbol)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据同步状态表';
+		`,
+		// sync_time_ranges 表（记录每个币种已同步的时间段）
+		`
+		CREATE TABLE IF NOT EXISTS sync_time_ranges (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			symbol VARCHAR(20) NOT NULL COMMENT '交易对',
+			start_time BIGINT NOT NULL COMMENT '时间段开始时间（毫秒时间戳）',
+			end_time BIGINT NOT NULL COMMENT '时间段结束时间（毫秒时间戳）',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+			INDEX idx_symbol_time (symbol, start_time, end_time),
+			INDEX idx_symbol_start (symbol, start_time),
+			INDEX idx_symbol_end (symbol, end_time)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据同步时间段记录表';
+		`,
+		// sync_failed_ranges 表（同步失败、等待重试的时间段）
+		`
+		CREATE TABLE IF NOT EXISTS sync_failed_ranges (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			symbol VARCHAR(20) NOT NULL COMMENT '交易对',
+			start_time BIGINT NOT NULL COMMENT '时间段开始时间（毫秒时间戳）',
+			end_time BIGINT NOT NULL COMMENT '时间段结束时间（毫秒时间戳）',
+			error_kind VARCHAR(20) NOT NULL DEFAULT '' COMMENT '错误类型',
+			error_message VARCHAR(500) NOT NULL DEFAULT '' COMMENT '最后一次的错误信息',
+			attempts INT NOT NULL DEFAULT 1 COMMENT '失败次数',
+			first_failed_at BIGINT NOT NULL COMMENT '第一次失败时间（毫秒时间戳）',
+			last_failed_at BIGINT NOT NULL COMMENT '最后一次失败时间（毫秒时间戳）',
+			UNIQUE KEY uk_symbol_range (symbol, start_time, end_time)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='同步失败时间段表';
+		`,
+		// sync_backfill_cursors 表（每个币种历史回填的进度）
+		`
+		CREATE TABLE IF NOT EXISTS sync_backfill_cursors (
+			symbol VARCHAR(20) NOT NULL PRIMARY KEY COMMENT '交易对',
+			direction VARCHAR(10) NOT NULL DEFAULT 'backward' COMMENT '回填方向',
+			status VARCHAR(20) NOT NULL DEFAULT 'running' COMMENT '状态: running/paused/completed/failed',
+			target_start BIGINT NOT NULL DEFAULT 0 COMMENT '回填目标的开始时间（毫秒时间戳）',
+			target_end BIGINT NOT NULL DEFAULT 0 COMMENT '回填目标的结束时间（毫秒时间戳）',
+			next_start BIGINT NOT NULL DEFAULT 0 COMMENT '下一个待同步时间段的开始时间（毫秒时间戳）',
+			next_end BIGINT NOT NULL DEFAULT 0 COMMENT '下一个待同步时间段的结束时间（毫秒时间戳）',
+			attempts INT NOT NULL DEFAULT 0 COMMENT '下一个时间段已失败的次数',
+			last_error VARCHAR(500) NOT NULL DEFAULT '' COMMENT '最后一次的错误信息',
+			updated_at BIGINT NOT NULL DEFAULT 0 COMMENT '更新时间（毫秒时间戳）'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='历史回填进度表';
+		`,
+		// klines_quarantine 表（校验未通过、没有保存的可疑K线）
+		`
+		CREATE TABLE IF NOT EXISTS klines_quarantine (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			symbol VARCHAR(20) NOT NULL COMMENT '交易对',
+			open_time BIGINT NOT NULL COMMENT 'K线开盘时间（毫秒时间戳）',
+			open DECIMAL(20, 8) NOT NULL COMMENT '开盘价',
+			high DECIMAL(20, 8) NOT NULL COMMENT '最高价',
+			low DECIMAL(20, 8) NOT NULL COMMENT '最低价',
+			close DECIMAL(20, 8) NOT NULL COMMENT '收盘价',
+			volume DECIMAL(20, 8) NOT NULL COMMENT '成交量',
+			quote_volume DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '成交额（计价币种）',
+			trade_count BIGINT NOT NULL DEFAULT 0 COMMENT '成交笔数',
+			close_time BIGINT NOT NULL COMMENT 'K线收盘时间（毫秒时间戳）',
+			reason VARCHAR(20) NOT NULL COMMENT '隔离原因: cross_exchange/spike',
+			reference VARCHAR(30) NOT NULL DEFAULT '' COMMENT '比较的对象（参考交易所名称，或 neighbors）',
+			reference_price DECIMAL(20, 8) NOT NULL DEFAULT 0 COMMENT '参考价格',
+			deviation_bps DOUBLE NOT NULL DEFAULT 0 COMMENT '偏差（基点）',
+			detected_at BIGINT NOT NULL COMMENT '发现时间（毫秒时间戳）',
+			UNIQUE KEY uk_symbol_open_time (symbol, open_time),
+			INDEX idx_detected_at (detected_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='可疑K线隔离表';
+		`,
+		// symbol_metadata 表（交易所的交易对信息：币种、精度、最小下单量、上线时间）
+		`
+		CREATE TABLE IF NOT EXISTS symbol_metadata (
+			symbol VARCHAR(20) NOT NULL PRIMARY KEY COMMENT '交易对',
+			exchange VARCHAR(30) NOT NULL DEFAULT '' COMMENT '数据来源的交易所',
+			base VARCHAR(20) NOT NULL DEFAULT '' COMMENT '基础币种',
+			quote VARCHAR(10) NOT NULL DEFAULT '' COMMENT '计价币种',
+			price_precision INT NOT NULL DEFAULT 0 COMMENT '价格小数位数',
+			amount_precision INT NOT NULL DEFAULT 0 COMMENT '数量小数位数',
+			min_base_amount DOUBLE NOT NULL DEFAULT 0 COMMENT '最小下单数量（基础币种）',
+			min_quote_amount DOUBLE NOT NULL DEFAULT 0 COMMENT '最小下单金额（计价币种）',
+			listed_at BIGINT NOT NULL DEFAULT 0 COMMENT '上线时间（毫秒时间戳，0 表示未知）',
+			trade_status VARCHAR(20) NOT NULL DEFAULT '' COMMENT '交易状态',
+			updated_at BIGINT NOT NULL DEFAULT 0 COMMENT '更新时间（毫秒时间戳）'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='交易对元数据表';
+		`,
+		// inactive_symbols 表（已下架、暂停交易或已更名、停止同步的交易对）
+		`
+		CREATE TABLE IF NOT EXISTS inactive_symbols (
+			symbol VARCHAR(20) NOT NULL PRIMARY KEY COMMENT '交易对',
+			status VARCHAR(20) NOT NULL COMMENT '状态: delisted/suspended/renamed',
+			reason VARCHAR(500) NOT NULL DEFAULT '' COMMENT '原因（交易所的错误信息、交易状态或新名称）',
+			detected_at BIGINT NOT NULL COMMENT '发现时间（毫秒时间戳）'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='停止同步的交易对表';
+		`,
+		// schema_migrations 表（记录已执行的数据库迁移）
+		`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT NOT NULL PRIMARY KEY COMMENT '迁移版本号',
+			name VARCHAR(100) NOT NULL COMMENT '迁移名称',
+			applied_at BIGINT NOT NULL COMMENT '执行时间（毫秒时间戳）'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据库迁移记录表';
+		`,
+	},
+	createKLineTableSQL: func(tableName, comment string) []string {
+		return []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			symbol VARCHAR(20) NOT NULL COMMENT '交易对，如 BTC_USDT',
+			open_time BIGINT NOT NULL COMMENT 'K线开盘时间（毫秒时间戳）',
+			open DECIMAL(20, 8) NOT NULL COMMENT '开盘价',
+			high DECIMAL(20, 8) NOT NULL COMMENT '最高价',
+			low DECIMAL(20, 8) NOT NULL COMMENT '最低价',
+			close DECIMAL(20, 8) NOT NULL COMMENT '收盘价',
+			volume DECIMAL(20, 8) NOT NULL COMMENT '成交量（基础币种）',
+			quote_volume DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '成交额（计价币种）',
+			trade_count BIGINT NOT NULL DEFAULT 0 COMMENT '成交笔数',
+			close_time BIGINT NOT NULL COMMENT 'K线收盘时间（毫秒时间戳）',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+			UNIQUE KEY uk_open_time (open_time) COMMENT '唯一索引：防止重复数据',
+			INDEX idx_close_time (close_time) COMMENT '索引：按收盘时间查询'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='%s';
+	`, tableName, comment)}
+	},
+	// 使用 INSERT IGNORE 避免重复数据（基于 UNIQUE KEY uk_open_time）
+	insertIgnoreSQL: "INSERT IGNORE INTO",
+	upsertKLineClause: `
+		ON DUPLICATE KEY UPDATE
+			open = VALUES(open),
+			high = VALUES(high),
+			low = VALUES(low),
+			close = VALUES(close),
+			volume = VALUES(volume),
+			quote_volume = VALUES(quote_volume),
+			trade_count = VALUES(trade_count),
+			close_time = VALUES(close_time)
+	`,
+	upsertSyncStatusSQL: `
+		INSERT INTO sync_status (symbol, last_sync_time, last_kline_time, sync_count)
+		VALUES (?, ?, ?, 1)
+		ON DUPLICATE KEY UPDATE
+			last_sync_time = ?,
+			last_kline_time = ?,
+			sync_count = sync_count + 1
+	`,
+	upsertPurgedBeforeSQL: `
+		INSERT INTO sync_status (symbol, purged_before)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE
+			purged_before = GREATEST(purged_before, VALUES(purged_before))
+	`,
+	upsertFailedRangeSQL: `
+		INSERT INTO sync_failed_ranges (symbol, start_time, end_time, error_kind, error_message, attempts, first_failed_at, last_failed_at)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			error_kind = VALUES(error_kind),
+			error_message = VALUES(error_message),
+			attempts = attempts + 1,
+			last_failed_at = VALUES(last_failed_at)
+	`,
+	upsertBackfillCursorSQL: `
+		INSERT INTO sync_backfill_cursors (symbol, direction, status, target_start, target_end, next_start, next_end, attempts, last_error, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			direction = VALUES(direction),
+			status = VALUES(status),
+			target_start = VALUES(target_start),
+			target_end = VALUES(target_end),
+			next_start = VALUES(next_start),
+			next_end = VALUES(next_end),
+			attempts = VALUES(attempts),
+			last_error = VALUES(last_error),
+			updated_at = VALUES(updated_at)
+	`,
+	upsertQuarantineSQL: `
+		INSERT INTO klines_quarantine (symbol, open_time, open, high, low, close, volume, quote_volume, trade_count, close_time, reason, reference, reference_price, deviation_bps, detected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			open = VALUES(open), high = VALUES(high), low = VALUES(low), close = VALUES(close),
+			volume = VALUES(volume), quote_volume = VALUES(quote_volume), trade_count = VALUES(trade_count),
+			close_time = VALUES(close_time), reason = VALUES(reason), reference = VALUES(reference), reference_price = VALUES(reference_price),
+			deviation_bps = VALUES(deviation_bps), detected_at = VALUES(detected_at)
+	`,
+	upsertSymbolMetaSQL: `
+		INSERT INTO symbol_metadata (symbol, exchange, base, quote, price_precision, amount_precision, min_base_amount, min_quote_amount, listed_at, trade_status, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			exchange = VALUES(exchange), base = VALUES(base), quote = VALUES(quote),
+			price_precision = VALUES(price_precision), amount_precision = VALUES(amount_precision),
+			min_base_amount = VALUES(min_base_amount), min_quote_amount = VALUES(min_quote_amount),
+			listed_at = VALUES(listed_at), trade_status = VALUES(trade_status), updated_at = VALUES(updated_at)
+	`,
+	upsertInactiveSymbolSQL: `
+		INSERT INTO inactive_symbols (symbol, status, reason, detected_at)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			status = VALUES(status), reason = VALUES(reason), detected_at = VALUES(detected_at)
+	`,
+	tableExistsSQL: `
+		SELECT COUNT(*) > 0
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+		  AND table_name = ?
+	`,
+	listTablesSQL: `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+		  AND table_name LIKE ?
+		ORDER BY table_name
+	`,
+	columnExistsSQL: `
+		SELECT COUNT(*) > 0
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE()
+		  AND table_name = ?
+		  AND column_name = ?
+	`,
+	isMissingTable: func(err error) bool {
+		return strings.Contains(err.Error(), "doesn't exist")
+	},
+}
+
+// NewMySQLStore 基于已打开的 MySQL 连接创建K线存储
+func NewMySQLStore(db *sql.DB) KLineStore {
+	return &sqlStore{db: db, dialect: mysqlDialect}
+}
+
+// OpenMySQLStore 打开 MySQL 连接并创建K线存储
+func OpenMySQLStore(dsn string) (*sql.DB, KLineStore, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开数据库连接失败: %w", err)
+	}
+
+	// 设置连接池参数
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("数据库连接测试失败: %w", err)
+	}
+
+	return db, NewMySQLStore(db), nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import "errors"
+
+// ErrQuarantineNotSupported 当前存储后端不支持隔离表
+var ErrQuarantineNotSupported = errors.New("当前存储后端不支持隔离表")
+
+// 隔离原因
+const (
+	QuarantineCrossExchange = "cross_exchange" // 与参考交易所同一分钟的收盘价偏差过大
+	QuarantineSpike         = "spike"          // 相对前后K线的价格突变
+)
+
+// QuarantinedKLine 校验未通过、没有保存到K线表的可疑K线
+type QuarantinedKLine struct {
+	Symbol         string  `json:"symbol"`
+	OpenTime       int64   `json:"openTime"`
+	Open           float64 `json:"open"`
+	High           float64 `json:"high"`
+	Low            float64 `json:"low"`
+	Close          float64 `json:"close"`
+	Volume         float64 `json:"volume"`
+	QuoteVolume    float64 `json:"quoteVolume"`
+	TradeCount     int64   `json:"tradeCount"`
+	CloseTime      int64   `json:"closeTime"`
+	Reason         string  `json:"reason"`         // 隔离原因（cross_exchange、spike）
+	Reference      string  `json:"reference"`      // 比较的对象（参考交易所名称，或 neighbors 表示前后K线）
+	ReferencePrice float64 `json:"referencePrice"` // 参考价格
+	DeviationBps   float64 `json:"deviationBps"`   // 偏差（基点）
+	DetectedAt     int64   `json:"detectedAt"`     // 发现时间（毫秒时间戳）
+}
+
+// ValidationReport 一段时间内的校验结果汇总
+type ValidationReport struct {
+	Symbol       string             `json:"symbol"`
+	StartTime    int64              `json:"startTime"`
+	EndTime      int64              `json:"endTime"`
+	Total        int                `json:"total"`
+	ReasonCounts map[string]int     `json:"reasonCounts"`
+	MaxDeviation float64            `json:"maxDeviationBps"`
+	Rows         []QuarantinedKLine `json:"rows"`
+}
+
+// QuarantineStore 支持隔离表的存储后端（可选能力，MySQL、SQLite、内存存储实现）
+type QuarantineStore interface {
+	// QuarantineKLines 把校验未通过的K线写入隔离表（同一币种同一分钟已存在时覆盖）
+	QuarantineKLines(rows []QuarantinedKLine) error
+	// GetQuarantinedKLines 查询隔离的K线（symbol 为空时查询所有币种，startTime/endTime 为 0 时不限制）
+	GetQuarantinedKLines(symbol string, startTime, endTime int64) ([]QuarantinedKLine, error)
+}
+
+// quarantineStore 获取当前支持隔离表的存储后端
+func quarantineStore() (QuarantineStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	qs, ok := s.(QuarantineStore)
+	if !ok {
+		return nil, ErrQuarantineNotSupported
+	}
+	return qs, nil
+}
+
+// QuarantineKLines 把校验未通过的K线写入隔离表
+func QuarantineKLines(rows []QuarantinedKLine) error {
+	qs, err := quarantineStore()
+	if err != nil {
+		return err
+	}
+	return qs.QuarantineKLines(rows)
+}
+
+// GetQuarantinedKLines 查询隔离的K线（symbol 为空时查询所有币种，startTime/endTime 为 0 时不限制）
+func GetQuarantinedKLines(symbol string, startTime, endTime int64) ([]QuarantinedKLine, error) {
+	qs, err := quarantineStore()
+	if err != nil {
+		return nil, err
+	}
+	return qs.GetQuarantinedKLines(symbol, startTime, endTime)
+}
+
+// GetValidationReport 汇总一段时间内隔离的K线（按原因计数，并给出最大偏差）
+func GetValidationReport(symbol string, startTime, endTime int64) (*ValidationReport, error) {
+	rows, err := GetQuarantinedKLines(symbol, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{
+		Symbol:       symbol,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Total:        len(rows),
+		ReasonCounts: make(map[string]int),
+		Rows:         rows,
+	}
+	for _, r := range rows {
+		report.ReasonCounts[r.Reason]++
+		if r.DeviationBps > report.MaxDeviation {
+			report.MaxDeviation = r.DeviationBps
+		}
+	}
+	if report.Rows == nil {
+		report.Rows = []QuarantinedKLine{}
+	}
+	return report, nil
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// hourMs 1小时的毫秒数（清理批次按小时对齐，保证每个聚合周期的1分钟数据在同一批次内完成降采样）
+const hourMs = 60 * minuteMs
+
+// dayMs 1天的毫秒数
+const dayMs = 24 * hourMs
+
+// RetentionCutoff 计算保留最近 keepDays 天数据时的清理边界（按 UTC 零点对齐），keepDays <= 0 时返回 0（不清理）
+func RetentionCutoff(keepDays int, now time.Time) int64 {
+	if keepDays <= 0 {
+		return 0
+	}
+	return (now.UnixMilli()/dayMs - int64(keepDays)) * dayMs
+}
+
+// PurgeOptions 清理参数
+type PurgeOptions struct {
+	BatchMinutes int             // 每批删除的时间跨度（分钟），按小时向下取整，最少1小时
+	Pause        time.Duration   // 每批之间的停顿
+	Stop         <-chan struct{} // 关闭时在当前批次完成后中止（可为 nil）
+}
+
+// PurgeResult 单个币种的清理结果
+type PurgeResult struct {
+	Symbol      string `json:"symbol"`
+	Cutoff      int64  `json:"cutoff"` // 早于该时间的1分钟K线被删除
+	DeletedRows int    `json:"deletedRows"`
+	Batches     int    `json:"batches"`
+	Stopped     bool   `json:"stopped"` // 是否被中止（下次清理时继续）
+}
+
+// PurgeKLines1mBefore 分批删除开盘时间早于 cutoff 的1分钟K线
+// 每批删除前先更新该时间段的聚合K线（降采样），聚合表的数据永久保留；每批是一条独立的 DELETE 语句，
+// 批次之间停顿，避免长时间锁表。清理边界会先写入 sync_status，同步任务不会重新拉取被清理的数据
+func PurgeKLines1mBefore(symbol string, cutoff int64, opts PurgeOptions) (*PurgeResult, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	result := &PurgeResult{Symbol: symbol, Cutoff: cutoff}
+	if cutoff <= 0 {
+		return result, nil
+	}
+
+	// 先记录清理边界，清理过程中同步任务就不会重新拉取正在删除的数据
+	if err := s.SetPurgedBefore(symbol, cutoff); err != nil {
+		return nil, err
+	}
+
+	batchMs := int64(max(opts.BatchMinutes/60, 1)) * hourMs
+	for {
+		oldest, err := s.GetKLines1m(symbol, 0, cutoff-1, 1)
+		if err != nil {
+			return result, fmt.Errorf("读取K线失败: %w", err)
+		}
+		if len(oldest) == 0 {
+			break
+		}
+
+		// 从最早的一根K线开始（跳过没有数据的时间段）
+		from := oldest[0].OpenTime / hourMs * hourMs
+		to := min(from+batchMs, cutoff) - 1
+
+		if SupportsAggregates() {
+			if err := UpdateAggregates(symbol, from, to); err != nil {
+				return result, fmt.Errorf("清理前更新聚合K线失败: %w", err)
+			}
+		}
+		deleted, err := s.DeleteKLines1mRange(symbol, from, to)
+		if err != nil {
+			return result, fmt.Errorf("删除K线失败: %w", err)
+		}
+		result.DeletedRows += deleted
+		result.Batches++
+
+		select {
+		case <-opts.Stop:
+			result.Stopped = true
+		case <-time.After(opts.Pause):
+		}
+		if result.Stopped {
+			break
+		}
+	}
+
+	if err := markPurgedRanges(symbol, cutoff); err != nil {
+		return result, err
+	}
+
+	if result.DeletedRows > 0 || result.Stopped {
+		logger.Infof("[%s] 清理过期1分钟K线: 早于 %s, 删除 %d 条, 批次 %d, 中止=%v", symbol,
+			time.UnixMilli(cutoff).UTC().Format("2006-01-02"), result.DeletedRows, result.Batches, result.Stopped)
+	}
+	return result, nil
+}
+
+// markPurgedRanges 把清理边界之前的已同步时间段合并为一个，避免清理后残留大量零碎的时间段
+func markPurgedRanges(symbol string, cutoff int64) error {
+	s, err := currentStore()
+	if err != nil {
+		return err
+	}
+
+	unlock := syncRangeLocks.lock(symbol)
+	defer unlock()
+
+	ranges, err := s.GetSyncTimeRanges(symbol)
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 || ranges[0].StartTime >= cutoff {
+		return nil
+	}
+
+	purged := SyncTimeRange{StartTime: ranges[0].StartTime, EndTime: cutoff - 1}
+	remaining := subtractRanges(ranges, []SyncTimeRange{purged})
+	collapsed := mergeRanges(append([]SyncTimeRange{purged}, remaining...))
+	if len(collapsed) == len(ranges) {
+		return nil
+	}
+	if err := s.ReplaceSyncTimeRanges(symbol, collapsed); err != nil {
+		return fmt.Errorf("更新同步时间段失败: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredFromConfig 按 symbols.json 中的保留策略清理所有启用币种的过期1分钟K线
+// 保留策略未启用或币种分组的保留天数为 0 时跳过
+func PurgeExpiredFromConfig(stop <-chan struct{}) ([]*PurgeResult, error) {
+	retention, err := config.GetRetentionConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取保留策略失败: %w", err)
+	}
+	if !retention.Enabled {
+		return nil, nil
+	}
+	allSymbols, err := config.GetAllEnabledSymbols()
+	if err != nil {
+		return nil, fmt.Errorf("获取币种配置失败: %w", err)
+	}
+
+	opts := PurgeOptions{
+		BatchMinutes: retention.PurgeBatchMinutes,
+		Pause:        time.Duration(retention.PurgePauseMs) * time.Millisecond,
+		Stop:         stop,
+	}
+
+	var results []*PurgeResult
+	for _, symbolConfig := range allSymbols {
+		keepDays, err := config.GetKeep1mDays(symbolConfig.Symbol)
+		if err != nil {
+			return results, err
+		}
+		cutoff := RetentionCutoff(keepDays, time.Now())
+		if cutoff == 0 {
+			continue
+		}
+
+		result, err := PurgeKLines1mBefore(symbolConfig.Symbol, cutoff, opts)
+		if err != nil {
+			return results, fmt.Errorf("清理过期K线失败 (币种: %s): %w", symbolConfig.Symbol, err)
+		}
+		results = append(results, result)
+		if result.Stopped {
+			break
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionCutoff(t *testing.T) {
+	now := time.Date(2023, 11, 15, 13, 45, 30, 0, time.UTC)
+	tests := []struct {
+		keepDays int
+		now      time.Time
+		want     int64
+	}{
+		{0, now, 0},
+		{-1, now, 0},
+		{1, now, time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC).UnixMilli()},
+		{30, now, time.Date(2023, 10, 16, 0, 0, 0, 0, time.UTC).UnixMilli()},
+		// 按 UTC 零点对齐，与 now 所在的时区无关
+		{1, now.In(time.FixedZone("UTC+8", 8*3600)), time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC).UnixMilli()},
+		{1, time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC).UnixMilli()},
+	}
+	for _, tt := range tests {
+		if got := RetentionCutoff(tt.keepDays, tt.now); got != tt.want {
+			t.Errorf("RetentionCutoff(%d, %s) = %d，期望 %d", tt.keepDays, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestMarkPurgedRanges(t *testing.T) {
+	const symbol = "BTC_USDT"
+	cutoff := minuteAt(100)
+	tests := []struct {
+		name   string
+		ranges []SyncTimeRange
+		want   []SyncTimeRange
+	}{
+		{
+			name: "没有同步记录",
+		},
+		{
+			name:   "全部在清理边界之后",
+			ranges: []SyncTimeRange{minuteSpan(100, 120), minuteSpan(130, 140)},
+			want:   []SyncTimeRange{minuteSpan(100, 120), minuteSpan(130, 140)},
+		},
+		{
+			name:   "边界之前零碎的时间段合并为一个",
+			ranges: []SyncTimeRange{minuteSpan(0, 9), minuteSpan(20, 29), minuteSpan(50, 59), minuteSpan(110, 120)},
+			want:   []SyncTimeRange{minuteSpan(0, 99), minuteSpan(110, 120)},
+		},
+		{
+			name:   "跨越边界的时间段与合并结果相连",
+			ranges: []SyncTimeRange{minuteSpan(0, 9), minuteSpan(50, 150)},
+			want:   []SyncTimeRange{minuteSpan(0, 150)},
+		},
+		{
+			name:   "从边界开始的时间段与合并结果相连",
+			ranges: []SyncTimeRange{minuteSpan(10, 19), minuteSpan(100, 150)},
+			want:   []SyncTimeRange{minuteSpan(10, 150)},
+		},
+		{
+			name:   "已经合并时保持不变",
+			ranges: []SyncTimeRange{minuteSpan(5, 99), minuteSpan(120, 130)},
+			want:   []SyncTimeRange{minuteSpan(5, 99), minuteSpan(120, 130)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := useMemoryStore(t)
+			if err := m.ReplaceSyncTimeRanges(symbol, tt.ranges); err != nil {
+				t.Fatal(err)
+			}
+			if err := markPurgedRanges(symbol, cutoff); err != nil {
+				t.Fatal(err)
+			}
+			ranges, err := GetSyncTimeRanges(symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRanges(t, ranges, tt.want)
+		})
+	}
+}
+
+func TestPurgeKLines1mBefore(t *testing.T) {
+	const symbol = "BTC_USDT"
+	tests := []struct {
+		name         string
+		klines       []KLine1m
+		cutoff       int64
+		batchMinutes int
+		wantDeleted  int
+		wantBatches  int
+	}{
+		{
+			name:         "按小时分批",
+			klines:       testKLines(symbol, 0, 200),
+			cutoff:       minuteAt(120),
+			batchMinutes: 60,
+			wantDeleted:  120,
+			wantBatches:  2,
+		},
+		{
+			name:         "批次跨度按小时向下取整",
+			klines:       testKLines(symbol, 0, 200),
+			cutoff:       minuteAt(180),
+			batchMinutes: 150,
+			wantDeleted:  180,
+			wantBatches:  2, // 2小时 + 1小时
+		},
+		{
+			name:         "批次跨度最少1小时",
+			klines:       testKLines(symbol, 0, 200),
+			cutoff:       minuteAt(120),
+			batchMinutes: 0,
+			wantDeleted:  120,
+			wantBatches:  2,
+		},
+		{
+			name:         "跳过没有数据的小时，批次从最早一根K线所在的整点开始",
+			klines:       append(testKLines(symbol, 30, 40), testKLines(symbol, 150, 200)...),
+			cutoff:       minuteAt(180),
+			batchMinutes: 60,
+			wantDeleted:  11 + 30,
+			wantBatches:  2,
+		},
+		{
+			name:         "没有需要清理的数据",
+			klines:       testKLines(symbol, 120, 200),
+			cutoff:       minuteAt(120),
+			batchMinutes: 60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryStore(t)
+			if _, err := SaveKLine1m(tt.klines); err != nil {
+				t.Fatal(err)
+			}
+			if err := AddSyncTimeRange(symbol, tt.klines[0].OpenTime, tt.klines[len(tt.klines)-1].CloseTime); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := PurgeKLines1mBefore(symbol, tt.cutoff, PurgeOptions{BatchMinutes: tt.batchMinutes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.DeletedRows != tt.wantDeleted || result.Batches != tt.wantBatches || result.Stopped {
+				t.Errorf("结果 = %+v，期望删除 %d 条、%d 批", result, tt.wantDeleted, tt.wantBatches)
+			}
+
+			remaining, err := GetKLines1m(symbol, 0, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(remaining) != len(tt.klines)-tt.wantDeleted || remaining[0].OpenTime < tt.cutoff {
+				t.Errorf("剩余 %d 根K线（第一根 %d），期望 %d 根且不早于 %d",
+					len(remaining), remaining[0].OpenTime, len(tt.klines)-tt.wantDeleted, tt.cutoff)
+			}
+			if purgedBefore, _ := GetPurgedBefore(symbol); purgedBefore != tt.cutoff {
+				t.Errorf("purgedBefore = %d，期望 %d", purgedBefore, tt.cutoff)
+			}
+			// 被清理的分钟不会被重新拉取
+			if missing, _ := FindMissingRanges(symbol, minuteAt(0), minuteAt(201)-1); len(missing) != 0 {
+				t.Errorf("清理后不应有需要同步的时间段: %v", missing)
+			}
+		})
+	}
+}
+
+func TestPurgeKLines1mBeforeNoCutoff(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+	if _, err := SaveKLine1m(testKLines(symbol, 0, 9)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := PurgeKLines1mBefore(symbol, 0, PurgeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DeletedRows != 0 || result.Batches != 0 {
+		t.Errorf("cutoff 为 0 时不应清理: %+v", result)
+	}
+	if purgedBefore, _ := GetPurgedBefore(symbol); purgedBefore != 0 {
+		t.Errorf("cutoff 为 0 时不应记录清理边界: %d", purgedBefore)
+	}
+}
+
+func TestPurgeKLines1mBeforeStopped(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+	cutoff := minuteAt(180)
+	if _, err := SaveKLine1m(testKLines(symbol, 0, 200)); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSyncTimeRange(symbol, minuteAt(0), minuteAt(201)-1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 中止信号在第一批完成后生效
+	stop := make(chan struct{})
+	close(stop)
+	result, err := PurgeKLines1mBefore(symbol, cutoff, PurgeOptions{BatchMinutes: 60, Pause: time.Hour, Stop: stop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Stopped || result.Batches != 1 || result.DeletedRows != 60 {
+		t.Fatalf("结果 = %+v，期望第一批后中止", result)
+	}
+
+	// 清理边界已经记录：剩余的过期数据保留到下次清理，期间不会重新拉取已删除的分钟
+	if purgedBefore, _ := GetPurgedBefore(symbol); purgedBefore != cutoff {
+		t.Errorf("purgedBefore = %d，期望 %d", purgedBefore, cutoff)
+	}
+	if remaining, _ := GetKLines1m(symbol, 0, cutoff-1, 0); len(remaining) != 120 || remaining[0].OpenTime != minuteAt(60) {
+		t.Errorf("中止后剩余 %d 根过期K线，期望从第 60 分钟开始的 120 根", len(remaining))
+	}
+	if missing, _ := FindMissingRanges(symbol, minuteAt(0), minuteAt(201)-1); len(missing) != 0 {
+		t.Errorf("中止后不应有需要同步的时间段: %v", missing)
+	}
+	ranges, _ := GetSyncTimeRanges(symbol)
+	assertRanges(t, ranges, []SyncTimeRange{minuteSpan(0, 200)})
+
+	// 下次清理从剩余的最早一根K线继续
+	result, err = PurgeKLines1mBefore(symbol, cutoff, PurgeOptions{BatchMinutes: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stopped || result.Batches != 2 || result.DeletedRows != 120 {
+		t.Errorf("继续清理的结果 = %+v，期望 2 批删除 120 条", result)
+	}
+	if remaining, _ := GetKLines1m(symbol, 0, 0, 0); len(remaining) != 21 || remaining[0].OpenTime != cutoff {
+		t.Errorf("清理完成后剩余 %d 根K线，期望从清理边界开始的 21 根", len(remaining))
+	}
+}
+
+func TestPurgeKLines1mBeforeUpdatesAggregates(t *testing.T) {
+	const symbol = "BTC_USDT"
+	store := useSQLiteStore(t, symbol)
+
+	// 直接写入1分钟表，聚合表为空：聚合数据只能来自清理前的更新
+	klines := variedKLines(symbol, 0, 1440+59)
+	if _, err := store.SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	cutoff := minuteAt(1440)
+	result, err := PurgeKLines1mBefore(symbol, cutoff, PurgeOptions{BatchMinutes: 60, Pause: time.Hour, Stop: stop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Stopped || result.Batches != 1 {
+		t.Fatalf("结果 = %+v，期望第一批后中止", result)
+	}
+	// 已删除的第一个小时在删除前完成了降采样
+	firstHour := klines[:60]
+	for _, level := range aggregateLevels[:3] { // 5m、15m、1h
+		got, err := store.GetAggregateKLines(symbol, level.Interval, 0, minuteAt(60)-1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertAggregates(t, level.Interval, got, aggregateBuckets(firstHour, int64(level.Minutes)*60000))
+	}
+
+	// 继续清理：每一批删除前更新聚合表，4h、1d 由逐小时更新的 1h 数据累积得到
+	if _, err := PurgeKLines1mBefore(symbol, cutoff, PurgeOptions{BatchMinutes: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _ := store.GetKLines1m(symbol, 0, cutoff-1, 0); len(remaining) != 0 {
+		t.Fatalf("清理边界之前还有 %d 根K线", len(remaining))
+	}
+	purged := klines[:1440]
+	for _, level := range aggregateLevels {
+		got, err := store.GetAggregateKLines(symbol, level.Interval, 0, cutoff-1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertAggregates(t, level.Interval, got, aggregateBuckets(purged, int64(level.Minutes)*60000))
+	}
+}
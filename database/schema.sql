@@ -7,7 +7,9 @@ CREATE TABLE IF NOT EXISTS klines_1m (
     high DECIMAL(20, 8) NOT NULL COMMENT '最高价',
     low DECIMAL(20, 8) NOT NULL COMMENT '最低价',
     close DECIMAL(20, 8) NOT NULL COMMENT '收盘价',
-    volume DECIMAL(20, 8) NOT NULL COMMENT '成交量',
+    volume DECIMAL(20, 8) NOT NULL COMMENT '成交量（基础币种）',
+    quote_volume DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '成交额（计价币种）',
+    trade_count BIGINT NOT NULL DEFAULT 0 COMMENT '成交笔数',
     close_time BIGINT NOT NULL COMMENT 'K线收盘时间（毫秒时间戳）',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
@@ -23,6 +25,7 @@ CREATE TABLE IF NOT EXISTS sync_status (
     last_sync_time BIGINT NOT NULL DEFAULT 0 COMMENT '最后同步时间（毫秒时间戳）',
     last_kline_time BIGINT NOT NULL DEFAULT 0 COMMENT '最后一条K线时间（毫秒时间戳）',
     sync_count INT NOT NULL DEFAULT 0 COMMENT '同步次数',
+    purged_before BIGINT NOT NULL DEFAULT 0 COMMENT '早于该时间的1分钟K线已清理（毫秒时间戳）',
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     UNIQUE KEY uk_symbol (symbol)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据同步状态表';
@@ -0,0 +1,1087 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"wails-contract-warn/logger"
+)
+
+// sqlDialect 不同 SQL 引擎之间的语法差异
+type sqlDialect struct {
+	name string
+
+	// 全局表建表语句（按顺序执行）
+	schemaSQL []string
+	// createKLineTableSQL 生成K线表的建表语句（按顺序执行），1分钟表和聚合表共用
+	createKLineTableSQL func(tableName, comment string) []string
+
+	// insertIgnoreSQL 忽略重复数据的插入语句前缀（如 INSERT IGNORE INTO）
+	insertIgnoreSQL string
+	// upsertKLineClause 插入K线时遇到 open_time 冲突则覆盖 OHLCV 的子句
+	upsertKLineClause string
+	// upsertSyncStatusSQL 插入或更新同步状态，参数: symbol, lastSyncTime, lastKlineTime, lastSyncTime, lastKlineTime
+	upsertSyncStatusSQL string
+	// upsertPurgedBeforeSQL 插入或增大清理边界，参数: symbol, purgedBefore
+	upsertPurgedBeforeSQL string
+	// upsertFailedRangeSQL 插入失败时间段或累加失败次数，参数: symbol, startTime, endTime, errorKind, errorMessage, failedAt, failedAt
+	upsertFailedRangeSQL string
+	// upsertBackfillCursorSQL 插入或覆盖回填进度，参数: symbol, direction, status, targetStart, targetEnd, nextStart, nextEnd, attempts, lastError, updatedAt
+	upsertBackfillCursorSQL string
+	// upsertQuarantineSQL 插入或覆盖隔离的K线，参数: symbol, openTime, open, high, low, close, volume, quoteVolume, tradeCount, closeTime, reason, reference, referencePrice, deviationBps, detectedAt
+	upsertQuarantineSQL string
+	// upsertSymbolMetaSQL 插入或覆盖交易对元数据，参数: symbol, exchange, base, quote, pricePrecision, amountPrecision, minBaseAmount, minQuoteAmount, listedAt, tradeStatus, updatedAt
+	upsertSymbolMetaSQL string
+	// upsertInactiveSymbolSQL 插入或覆盖停止同步的交易对，参数: symbol, status, reason, detectedAt
+	upsertInactiveSymbolSQL string
+	// tableExistsSQL 检查表是否存在，参数: tableName
+	tableExistsSQL string
+	// listTablesSQL 按名称模式列出表（按表名排序），参数: LIKE 模式
+	listTablesSQL string
+	// columnExistsSQL 检查列是否存在，参数: tableName, columnName
+	columnExistsSQL string
+	// maxInsertChunkSize 每条多行语句的K线数量上限（0 表示只受 InsertChunkSize 限制）
+	maxInsertChunkSize int
+
+	// isMissingTable 判断错误是否为“表不存在”
+	isMissingTable func(err error) bool
+}
+
+// sqlStore 基于 database/sql 的K线存储实现
+type sqlStore struct {
+	db      *sql.DB
+	dialect *sqlDialect
+}
+
+// InitSchema 初始化全局表结构
+func (s *sqlStore) InitSchema() error {
+	for _, stmt := range s.dialect.schemaSQL {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("创建全局表失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateTableForSymbol 为指定币种创建K线表（每个币种一张表）
+func (s *sqlStore) CreateTableForSymbol(symbol string) error {
+	tableName := GetTableName(symbol)
+	for _, stmt := range s.dialect.createKLineTableSQL(tableName, "1分钟K线数据表 - "+symbol) {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("创建表 %s 失败: %w", tableName, err)
+		}
+	}
+	return nil
+}
+
+// tableExists 检查表是否存在
+func (s *sqlStore) tableExists(tableName string) (bool, error) {
+	var exists bool
+	if err := s.db.QueryRow(s.dialect.tableExistsSQL, tableName).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// SaveKLine1m 保存1分钟K线数据（批量插入，忽略重复）
+func (s *sqlStore) SaveKLine1m(klines []KLine1m) (*SaveKLine1mResult, error) {
+	result := &SaveKLine1mResult{}
+	if len(klines) == 0 {
+		return result, nil
+	}
+
+	// 按表名（币种）分组K线数据
+	klinesByTable := make(map[string][]KLine1m)
+	for _, k := range klines {
+		tableName := GetTableName(k.Symbol)
+		klinesByTable[tableName] = append(klinesByTable[tableName], k)
+	}
+
+	// 为每个表批量插入数据
+	for tableName, tableKLines := range klinesByTable {
+		// 确保表存在（使用第一个K线的币种）
+		if err := s.CreateTableForSymbol(tableKLines[0].Symbol); err != nil {
+			return result, fmt.Errorf("创建表失败: %w", err)
+		}
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			return result, err
+		}
+
+		insertedCount, skippedCount, errorCount, err := s.insertKLinesChunked(tx, tableName, tableKLines)
+		if err != nil {
+			tx.Rollback()
+			return result, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return result, fmt.Errorf("提交事务失败: %w", err)
+		}
+
+		// 累加统计信息
+		result.InsertedCount += insertedCount
+		result.SkippedCount += skippedCount
+		result.ErrorCount += errorCount
+
+		logBatchStats(tableName, tableKLines, insertedCount, skippedCount, errorCount)
+	}
+
+	return result, nil
+}
+
+// DefaultInsertChunkSize 默认每条多行 INSERT 语句包含的K线数量
+const DefaultInsertChunkSize = 500
+
+// maxInsertChunkSize 每条语句的最大K线数量（10列 × 3000 行 = 30000 个参数，低于 SQLite 的 32766 上限）
+const maxInsertChunkSize = 3000
+
+var insertChunkSize = DefaultInsertChunkSize
+
+// SetInsertChunkSize 设置每条多行 INSERT 语句包含的K线数量（<=0 时使用默认值）
+func SetInsertChunkSize(size int) {
+	if size <= 0 {
+		size = DefaultInsertChunkSize
+	}
+	insertChunkSize = min(size, maxInsertChunkSize)
+}
+
+// InsertChunkSize 获取每条多行 INSERT 语句包含的K线数量
+func InsertChunkSize() int {
+	return insertChunkSize
+}
+
+// insertChunkSize 本存储每条多行语句包含的K线数量（InsertChunkSize 与方言上限中较小的一个）
+func (s *sqlStore) insertChunkSize() int {
+	if s.dialect.maxInsertChunkSize > 0 {
+		return min(InsertChunkSize(), s.dialect.maxInsertChunkSize)
+	}
+	return InsertChunkSize()
+}
+
+// klineInsertColumns 插入K线时使用的列（与 klineInsertArgs 的参数顺序一致）
+const klineInsertColumns = "(symbol, open_time, open, high, low, close, volume, quote_volume, trade_count, close_time)"
+
+// klineInsertPlaceholders 单行K线的占位符
+const klineInsertPlaceholders = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+// klineInsertArgCount 单行K线的参数数量
+const klineInsertArgCount = 10
+
+// klineInsertArgs 把K线追加为插入参数
+func klineInsertArgs(args []interface{}, k KLine1m) []interface{} {
+	return append(args, k.Symbol, k.OpenTime, k.Open, k.High, k.Low, k.Close, k.Volume, k.QuoteVolume, k.TradeCount, k.CloseTime)
+}
+
+// klineSelectColumns 查询K线时使用的列（与 scanKLines 的读取顺序一致）
+const klineSelectColumns = "open_time, open, high, low, close, volume, quote_volume, trade_count, close_time"
+
+// buildInsertSQL 生成一次插入 rows 行的语句，suffix 为附加子句（如 upsert 子句）
+func (s *sqlStore) buildInsertSQL(prefix, tableName string, rows int, suffix string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s VALUES ", prefix, tableName, klineInsertColumns)
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(klineInsertPlaceholders)
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// insertKLinesChunked 分块执行多行 INSERT IGNORE（忽略 open_time 已存在的数据）
+// 每块的影响行数即实际插入的行数，其余为已存在被忽略的行；
+// 某一块执行失败时回退为逐行插入，以便准确统计失败的行，不中断整个批次
+func (s *sqlStore) insertKLinesChunked(tx *sql.Tx, tableName string, klines []KLine1m) (inserted, skipped, failed int, err error) {
+	chunkSize := s.insertChunkSize()
+	var fullStmt *sql.Stmt
+	defer func() {
+		if fullStmt != nil {
+			fullStmt.Close()
+		}
+	}()
+
+	for start := 0; start < len(klines); start += chunkSize {
+		chunk := klines[start:min(start+chunkSize, len(klines))]
+
+		args := make([]interface{}, 0, len(chunk)*klineInsertArgCount)
+		for _, k := range chunk {
+			args = klineInsertArgs(args, k)
+		}
+
+		// 完整的块复用同一个预编译语句，最后不足一块的部分单独生成
+		var execResult sql.Result
+		var execErr error
+		if len(chunk) == chunkSize {
+			if fullStmt == nil {
+				fullStmt, err = tx.Prepare(s.buildInsertSQL(s.dialect.insertIgnoreSQL, tableName, chunkSize, ""))
+				if err != nil {
+					return inserted, skipped, failed, err
+				}
+			}
+			execResult, execErr = fullStmt.Exec(args...)
+		} else {
+			execResult, execErr = tx.Exec(s.buildInsertSQL(s.dialect.insertIgnoreSQL, tableName, len(chunk), ""), args...)
+		}
+
+		if execErr != nil {
+			logger.Warnf("批量插入失败，改为逐行插入 [%s]: rows=%d, error=%v", tableName, len(chunk), execErr)
+			i, sk, f := s.insertKLinesOneByOne(tx, tableName, chunk)
+			inserted += i
+			skipped += sk
+			failed += f
+			continue
+		}
+
+		rowsAffected, _ := execResult.RowsAffected()
+		inserted += int(rowsAffected)
+		skipped += len(chunk) - int(rowsAffected) // 数据已存在，被忽略
+	}
+
+	return inserted, skipped, failed, nil
+}
+
+// insertKLinesOneByOne 逐行插入K线（批量插入失败时使用）
+func (s *sqlStore) insertKLinesOneByOne(tx *sql.Tx, tableName string, klines []KLine1m) (inserted, skipped, failed int) {
+	query := s.buildInsertSQL(s.dialect.insertIgnoreSQL, tableName, 1, "")
+	for _, k := range klines {
+		execResult, err := tx.Exec(query, klineInsertArgs(nil, k)...)
+		if err != nil {
+			failed++
+			logger.Errorf("插入数据失败 [%s]: open_time=%d, error=%v", k.Symbol, k.OpenTime, err)
+			// 继续处理下一条，不中断整个批次
+			continue
+		}
+
+		// 检查是否实际插入了数据
+		rowsAffected, _ := execResult.RowsAffected()
+		if rowsAffected > 0 {
+			inserted++
+		} else {
+			skipped++ // 数据已存在，被忽略
+		}
+	}
+	return inserted, skipped, failed
+}
+
+// UpsertKLine1m 保存1分钟K线数据（open_time 已存在且 OHLCV 不同时覆盖）
+// 先读取同一时间范围内已存在的K线并逐条比较，不依赖各数据库 upsert 影响行数的不同语义，
+// 从而准确区分新增、更新和未变化的数据
+func (s *sqlStore) UpsertKLine1m(klines []KLine1m) (*SaveKLine1mResult, error) {
+	result := &SaveKLine1mResult{}
+	if len(klines) == 0 {
+		return result, nil
+	}
+
+	klinesByTable := make(map[string][]KLine1m)
+	for _, k := range klines {
+		tableName := GetTableName(k.Symbol)
+		klinesByTable[tableName] = append(klinesByTable[tableName], k)
+	}
+
+	for tableName, tableKLines := range klinesByTable {
+		if err := s.CreateTableForSymbol(tableKLines[0].Symbol); err != nil {
+			return result, fmt.Errorf("创建表失败: %w", err)
+		}
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			return result, err
+		}
+
+		inserted, updated, skipped, failed, err := s.upsertKLines(tx, tableName, tableKLines)
+		if err != nil {
+			tx.Rollback()
+			return result, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return result, fmt.Errorf("提交事务失败: %w", err)
+		}
+
+		result.InsertedCount += inserted
+		result.UpdatedCount += updated
+		result.SkippedCount += skipped
+		result.ErrorCount += failed
+
+		logUpsertStats(tableName, len(tableKLines), inserted, updated, skipped, failed)
+	}
+
+	return result, nil
+}
+
+// upsertKLines 在事务内比较并写入一个表的K线
+func (s *sqlStore) upsertKLines(tx *sql.Tx, tableName string, klines []KLine1m) (inserted, updated, skipped, failed int, err error) {
+	minTime, maxTime := klines[0].OpenTime, klines[0].OpenTime
+	for _, k := range klines {
+		minTime = min(minTime, k.OpenTime)
+		maxTime = max(maxTime, k.OpenTime)
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE open_time >= ? AND open_time <= ?
+	`, klineSelectColumns, tableName), minTime, maxTime)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("查询已存在的K线失败: %w", err)
+	}
+	existingKLines, err := scanKLines(rows, "")
+	rows.Close()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	existing := make(map[int64]KLine1m, len(existingKLines))
+	for _, k := range existingKLines {
+		existing[k.OpenTime] = k
+	}
+
+	var newKLines, changedKLines []KLine1m
+	for _, k := range klines {
+		old, ok := existing[k.OpenTime]
+		switch {
+		case !ok:
+			newKLines = append(newKLines, k)
+			existing[k.OpenTime] = k // 同一批次中重复的 open_time 只插入一次
+		case klineChanged(old, k):
+			changedKLines = append(changedKLines, k)
+			existing[k.OpenTime] = k
+		default:
+			skipped++
+		}
+	}
+
+	if len(newKLines) > 0 {
+		i, sk, f, err := s.insertKLinesChunked(tx, tableName, newKLines)
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+		inserted += i
+		skipped += sk
+		failed += f
+	}
+
+	chunkSize := s.insertChunkSize()
+	for start := 0; start < len(changedKLines); start += chunkSize {
+		chunk := changedKLines[start:min(start+chunkSize, len(changedKLines))]
+		args := make([]interface{}, 0, len(chunk)*klineInsertArgCount)
+		for _, k := range chunk {
+			args = klineInsertArgs(args, k)
+		}
+		if _, err := tx.Exec(s.buildInsertSQL("INSERT INTO", tableName, len(chunk), s.dialect.upsertKLineClause), args...); err != nil {
+			logger.Errorf("更新K线失败 [%s]: rows=%d, error=%v", tableName, len(chunk), err)
+			failed += len(chunk)
+			continue
+		}
+		updated += len(chunk)
+	}
+
+	return inserted, updated, skipped, failed, nil
+}
+
+// klineChanged 判断两根K线的 OHLCV（包括计价成交额和成交笔数）是否不同（按数据库精度 DECIMAL(20, 8) 比较）
+func klineChanged(a, b KLine1m) bool {
+	return roundPrice(a.Open) != roundPrice(b.Open) ||
+		roundPrice(a.High) != roundPrice(b.High) ||
+		roundPrice(a.Low) != roundPrice(b.Low) ||
+		roundPrice(a.Close) != roundPrice(b.Close) ||
+		roundPrice(a.Volume) != roundPrice(b.Volume) ||
+		roundPrice(a.QuoteVolume) != roundPrice(b.QuoteVolume) ||
+		a.TradeCount != b.TradeCount ||
+		a.CloseTime != b.CloseTime
+}
+
+// roundPrice 按8位小数取整
+func roundPrice(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
+
+// logUpsertStats 打印 upsert 批次统计信息
+func logUpsertStats(tableName string, total, insertedCount, updatedCount, skippedCount, errorCount int) {
+	logger.Infof("表 %s 批次统计(upsert): 总数=%d, 新增=%d, 更新=%d, 未变化=%d, 失败=%d",
+		tableName, total, insertedCount, updatedCount, skippedCount, errorCount)
+}
+
+// DeleteKLines1m 按开盘时间删除1分钟K线
+func (s *sqlStore) DeleteKLines1m(symbol string, openTimes []int64) (int, error) {
+	if len(openTimes) == 0 {
+		return 0, nil
+	}
+	tableName := GetTableName(symbol)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	chunkSize := s.insertChunkSize()
+	for start := 0; start < len(openTimes); start += chunkSize {
+		chunk := openTimes[start:min(start+chunkSize, len(openTimes))]
+		args := make([]interface{}, len(chunk))
+		for i, t := range chunk {
+			args[i] = t
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ")
+		execResult, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE open_time IN (%s)", tableName, placeholders), args...)
+		if err != nil {
+			tx.Rollback()
+			if s.dialect.isMissingTable(err) {
+				return 0, nil
+			}
+			return 0, err
+		}
+		rowsAffected, _ := execResult.RowsAffected()
+		deleted += int(rowsAffected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteKLines1mRange 删除开盘时间在 [startTime, endTime] 范围内的1分钟K线
+// 单条语句自动提交，调用方应控制时间跨度，避免长时间锁表
+func (s *sqlStore) DeleteKLines1mRange(symbol string, startTime, endTime int64) (int, error) {
+	execResult, err := s.db.Exec(fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE open_time >= ? AND open_time <= ?
+	`, GetTableName(symbol)), startTime, endTime)
+	if err != nil {
+		if s.dialect.isMissingTable(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	rowsAffected, _ := execResult.RowsAffected()
+	return int(rowsAffected), nil
+}
+
+// logBatchStats 打印批次统计信息和第一条数据示例
+func logBatchStats(tableName string, tableKLines []KLine1m, insertedCount, skippedCount, errorCount int) {
+	logger.Infof("表 %s 批次统计: 总数=%d, 成功插入=%d, 跳过(已存在)=%d, 失败=%d",
+		tableName, len(tableKLines), insertedCount, skippedCount, errorCount)
+
+	if len(tableKLines) == 0 {
+		return
+	}
+	first := tableKLines[0]
+	openTimeStr := time.Unix(first.OpenTime/1000, 0).Format("2006-01-02 15:04:05")
+	closeTimeStr := time.Unix(first.CloseTime/1000, 0).Format("2006-01-02 15:04:05")
+	logger.Infof("第一条数据示例 [%s]: open_time=%s, close_time=%s, open=%.8f, high=%.8f, low=%.8f, close=%.8f, volume=%.8f",
+		first.Symbol, openTimeStr, closeTimeStr, first.Open, first.High, first.Low, first.Close, first.Volume)
+}
+
+// GetLatestKLineTime 获取指定交易对的最新K线时间
+func (s *sqlStore) GetLatestKLineTime(symbol string) (int64, error) {
+	tableName := GetTableName(symbol)
+
+	exists, err := s.tableExists(tableName)
+	if err != nil || !exists {
+		return 0, nil // 表不存在，返回0
+	}
+
+	var lastTime int64
+	// 注意：由于每个币种一张表，不需要 WHERE symbol = ? 条件
+	err = s.db.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(MAX(close_time), 0)
+		FROM %s
+	`, tableName)).Scan(&lastTime)
+
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	return lastTime, nil
+}
+
+// GetKLines1m 获取1分钟K线数据（按时间范围）
+func (s *sqlStore) GetKLines1m(symbol string, startTime, endTime int64, limit int) ([]KLine1m, error) {
+	return s.queryKLines(GetTableName(symbol), symbol, startTime, endTime, limit)
+}
+
+// queryKLines 按时间范围查询指定K线表（1分钟表和聚合表结构相同）
+func (s *sqlStore) queryKLines(tableName, symbol string, startTime, endTime int64, limit int) ([]KLine1m, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE 1=1
+	`, klineSelectColumns, tableName)
+	args := []interface{}{}
+
+	if startTime > 0 {
+		query += " AND open_time >= ?"
+		args = append(args, startTime)
+	}
+
+	if endTime > 0 {
+		query += " AND open_time <= ?"
+		args = append(args, endTime)
+	}
+
+	query += " ORDER BY open_time ASC"
+
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		// 如果表不存在，返回空数组
+		if s.dialect.isMissingTable(err) {
+			return []KLine1m{}, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanKLines(rows, symbol)
+}
+
+// GetLatestKLine1m 获取最新的1分钟K线数据（单条）
+func (s *sqlStore) GetLatestKLine1m(symbol string) (*KLine1m, error) {
+	tableName := GetTableName(symbol)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		ORDER BY open_time DESC
+		LIMIT 1
+	`, klineSelectColumns, tableName)
+
+	var k KLine1m
+	k.Symbol = symbol
+	err := s.db.QueryRow(query).Scan(
+		&k.OpenTime,
+		&k.Open,
+		&k.High,
+		&k.Low,
+		&k.Close,
+		&k.Volume,
+		&k.QuoteVolume,
+		&k.TradeCount,
+		&k.CloseTime,
+	)
+	if err != nil {
+		// 如果表不存在或没有数据，返回nil
+		if s.dialect.isMissingTable(err) || errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// GetKLines1mByCount 获取最近N根1分钟K线
+func (s *sqlStore) GetKLines1mByCount(symbol string, count int) ([]KLine1m, error) {
+	return s.queryKLinesByCount(GetTableName(symbol), symbol, count)
+}
+
+// queryKLinesByCount 查询指定K线表最近N根K线（按开盘时间升序返回）
+func (s *sqlStore) queryKLinesByCount(tableName, symbol string, count int) ([]KLine1m, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		ORDER BY open_time DESC
+		LIMIT ?
+	`, klineSelectColumns, tableName)
+
+	rows, err := s.db.Query(query, count)
+	if err != nil {
+		// 如果表不存在，返回空数组
+		if s.dialect.isMissingTable(err) {
+			return []KLine1m{}, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	klines, err := scanKLines(rows, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 反转顺序（从旧到新）
+	for i, j := 0, len(klines)-1; i < j; i, j = i+1, j-1 {
+		klines[i], klines[j] = klines[j], klines[i]
+	}
+
+	return klines, nil
+}
+
+// scanKLines 读取查询结果中的K线数据
+// 列顺序: klineSelectColumns
+func scanKLines(rows *sql.Rows, symbol string) ([]KLine1m, error) {
+	var klines []KLine1m
+	for rows.Next() {
+		var k KLine1m
+		k.Symbol = symbol
+		err := rows.Scan(
+			&k.OpenTime,
+			&k.Open,
+			&k.High,
+			&k.Low,
+			&k.Close,
+			&k.Volume,
+			&k.QuoteVolume,
+			&k.TradeCount,
+			&k.CloseTime,
+		)
+		if err != nil {
+			return nil, err
+		}
+		klines = append(klines, k)
+	}
+	return klines, rows.Err()
+}
+
+// UpdateSyncStatus 更新同步状态
+func (s *sqlStore) UpdateSyncStatus(symbol string, lastSyncTime, lastKlineTime int64) error {
+	_, err := s.db.Exec(s.dialect.upsertSyncStatusSQL,
+		symbol, lastSyncTime, lastKlineTime, lastSyncTime, lastKlineTime)
+	return err
+}
+
+// GetSyncStatus 获取同步状态
+func (s *sqlStore) GetSyncStatus(symbol string) (lastSyncTime, lastKlineTime int64, err error) {
+	err = s.db.QueryRow(`
+		SELECT last_sync_time, last_kline_time
+		FROM sync_status
+		WHERE symbol = ?
+	`, symbol).Scan(&lastSyncTime, &lastKlineTime)
+
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return
+}
+
+// SetPurgedBefore 记录清理边界
+func (s *sqlStore) SetPurgedBefore(symbol string, purgedBefore int64) error {
+	if _, err := s.db.Exec(s.dialect.upsertPurgedBeforeSQL, symbol, purgedBefore); err != nil {
+		return fmt.Errorf("记录清理边界失败: %w", err)
+	}
+	return nil
+}
+
+// GetPurgedBefore 获取清理边界
+func (s *sqlStore) GetPurgedBefore(symbol string) (int64, error) {
+	var purgedBefore int64
+	err := s.db.QueryRow(`
+		SELECT purged_before
+		FROM sync_status
+		WHERE symbol = ?
+	`, symbol).Scan(&purgedBefore)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return purgedBefore, err
+}
+
+// AddSyncTimeRange 添加已同步的时间段
+func (s *sqlStore) AddSyncTimeRange(symbol string, startTime, endTime int64) error {
+	_, err := s.db.Exec(`
+		INSERT INTO sync_time_ranges (symbol, start_time, end_time)
+		VALUES (?, ?, ?)
+	`, symbol, startTime, endTime)
+	if err != nil {
+		return fmt.Errorf("添加同步时间段失败: %w", err)
+	}
+	return nil
+}
+
+// GetSyncTimeRanges 获取指定币种的所有已同步时间段（按开始时间排序）
+func (s *sqlStore) GetSyncTimeRanges(symbol string) ([]SyncTimeRange, error) {
+	rows, err := s.db.Query(`
+		SELECT start_time, end_time
+		FROM sync_time_ranges
+		WHERE symbol = ?
+		ORDER BY start_time ASC
+	`, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("查询同步时间段失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []SyncTimeRange
+	for rows.Next() {
+		var r SyncTimeRange
+		if err := rows.Scan(&r.StartTime, &r.EndTime); err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ranges, rows.Err()
+}
+
+// RecordFailedRange 记录同步失败的时间段（已存在时累加失败次数并更新错误信息）
+func (s *sqlStore) RecordFailedRange(symbol string, startTime, endTime int64, errorKind, errorMessage string) error {
+	now := time.Now().UnixMilli()
+	if _, err := s.db.Exec(s.dialect.upsertFailedRangeSQL,
+		symbol, startTime, endTime, errorKind, truncateMessage(errorMessage), now, now); err != nil {
+		return fmt.Errorf("记录失败时间段失败: %w", err)
+	}
+	return nil
+}
+
+// GetFailedRanges 获取同步失败的时间段（symbol 为空时返回所有币种，按最后失败时间升序）
+func (s *sqlStore) GetFailedRanges(symbol string) ([]FailedRange, error) {
+	query := `
+		SELECT symbol, start_time, end_time, error_kind, error_message, attempts, first_failed_at, last_failed_at
+		FROM sync_failed_ranges
+	`
+	var args []interface{}
+	if symbol != "" {
+		query += ` WHERE symbol = ?`
+		args = append(args, symbol)
+	}
+	query += ` ORDER BY last_failed_at ASC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询失败时间段失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []FailedRange
+	for rows.Next() {
+		var r FailedRange
+		if err := rows.Scan(&r.Symbol, &r.StartTime, &r.EndTime, &r.ErrorKind, &r.ErrorMessage,
+			&r.Attempts, &r.FirstFailedAt, &r.LastFailedAt); err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
+
+// DeleteFailedRanges 删除完全落在 [startTime, endTime] 内的失败时间段，返回删除的数量
+func (s *sqlStore) DeleteFailedRanges(symbol string, startTime, endTime int64) (int, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM sync_failed_ranges
+		WHERE symbol = ? AND start_time >= ? AND end_time <= ?
+	`, symbol, startTime, endTime)
+	if err != nil {
+		return 0, fmt.Errorf("删除失败时间段失败: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// QuarantineKLines 把可疑的K线写入隔离表（同一分钟已存在时覆盖为最新的检测结果）
+func (s *sqlStore) QuarantineKLines(rows []QuarantinedKLine) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(s.dialect.upsertQuarantineSQL)
+	if err != nil {
+		return fmt.Errorf("准备隔离语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range rows {
+		if _, err := stmt.Exec(r.Symbol, r.OpenTime, r.Open, r.High, r.Low, r.Close, r.Volume, r.QuoteVolume, r.TradeCount, r.CloseTime,
+			r.Reason, r.Reference, r.ReferencePrice, r.DeviationBps, r.DetectedAt); err != nil {
+			return fmt.Errorf("写入隔离K线失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetQuarantinedKLines 查询隔离的K线（symbol 为空时查询所有币种，startTime/endTime 为 0 时不限制，按开盘时间升序）
+func (s *sqlStore) GetQuarantinedKLines(symbol string, startTime, endTime int64) ([]QuarantinedKLine, error) {
+	query := `
+		SELECT symbol, open_time, open, high, low, close, volume, quote_volume, trade_count, close_time,
+			reason, reference, reference_price, deviation_bps, detected_at
+		FROM klines_quarantine
+		WHERE open_time >= ?
+	`
+	args := []interface{}{startTime}
+	if endTime > 0 {
+		query += ` AND open_time <= ?`
+		args = append(args, endTime)
+	}
+	if symbol != "" {
+		query += ` AND symbol = ?`
+		args = append(args, symbol)
+	}
+	query += ` ORDER BY symbol, open_time ASC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询隔离K线失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []QuarantinedKLine
+	for rows.Next() {
+		var r QuarantinedKLine
+		if err := rows.Scan(&r.Symbol, &r.OpenTime, &r.Open, &r.High, &r.Low, &r.Close, &r.Volume, &r.QuoteVolume, &r.TradeCount, &r.CloseTime,
+			&r.Reason, &r.Reference, &r.ReferencePrice, &r.DeviationBps, &r.DetectedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SaveSymbolMetadata 批量保存交易对元数据（已存在时覆盖）
+func (s *sqlStore) SaveSymbolMetadata(metas []SymbolMeta) error {
+	if len(metas) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(s.dialect.upsertSymbolMetaSQL)
+	if err != nil {
+		return fmt.Errorf("准备交易对元数据语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, m := range metas {
+		if _, err := stmt.Exec(m.Symbol, m.Exchange, m.Base, m.Quote, m.PricePrecision, m.AmountPrecision,
+			m.MinBaseAmount, m.MinQuoteAmount, m.ListedAt, m.TradeStatus, m.UpdatedAt); err != nil {
+			return fmt.Errorf("保存交易对元数据失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSymbolMetadata 获取所有交易对元数据（按交易对排序）
+func (s *sqlStore) GetSymbolMetadata() ([]SymbolMeta, error) {
+	rows, err := s.db.Query(`
+		SELECT symbol, exchange, base, quote, price_precision, amount_precision,
+			min_base_amount, min_quote_amount, listed_at, trade_status, updated_at
+		FROM symbol_metadata
+		ORDER BY symbol
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易对元数据失败: %w", err)
+	}
+	defer rows.Close()
+
+	var metas []SymbolMeta
+	for rows.Next() {
+		var m SymbolMeta
+		if err := rows.Scan(&m.Symbol, &m.Exchange, &m.Base, &m.Quote, &m.PricePrecision, &m.AmountPrecision,
+			&m.MinBaseAmount, &m.MinQuoteAmount, &m.ListedAt, &m.TradeStatus, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		metas = append(metas, m)
+	}
+	return metas, rows.Err()
+}
+
+// SaveInactiveSymbol 保存停止同步的交易对（已存在时覆盖）
+func (s *sqlStore) SaveInactiveSymbol(symbol InactiveSymbol) error {
+	if _, err := s.db.Exec(s.dialect.upsertInactiveSymbolSQL,
+		symbol.Symbol, symbol.Status, truncateMessage(symbol.Reason), symbol.DetectedAt); err != nil {
+		return fmt.Errorf("保存交易对状态失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteInactiveSymbol 删除停止同步的记录（恢复同步），返回是否存在记录
+func (s *sqlStore) DeleteInactiveSymbol(symbol string) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM inactive_symbols WHERE symbol = ?`, symbol)
+	if err != nil {
+		return false, fmt.Errorf("删除交易对状态失败: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// GetInactiveSymbols 获取所有停止同步的交易对（按交易对排序）
+func (s *sqlStore) GetInactiveSymbols() ([]InactiveSymbol, error) {
+	rows, err := s.db.Query(`SELECT symbol, status, reason, detected_at FROM inactive_symbols ORDER BY symbol`)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易对状态失败: %w", err)
+	}
+	defer rows.Close()
+
+	var symbols []InactiveSymbol
+	for rows.Next() {
+		var r InactiveSymbol
+		if err := rows.Scan(&r.Symbol, &r.Status, &r.Reason, &r.DetectedAt); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, r)
+	}
+	return symbols, rows.Err()
+}
+
+// backfillCursorColumns 读取回填进度的列（与 scanBackfillCursor 的顺序一致）
+const backfillCursorColumns = `symbol, direction, status, target_start, target_end, next_start, next_end, attempts, last_error, updated_at`
+
+// scanBackfillCursor 读取一行回填进度
+func scanBackfillCursor(scan func(dest ...interface{}) error) (BackfillCursor, error) {
+	var c BackfillCursor
+	err := scan(&c.Symbol, &c.Direction, &c.Status, &c.TargetStart, &c.TargetEnd,
+		&c.NextStart, &c.NextEnd, &c.Attempts, &c.LastError, &c.UpdatedAt)
+	return c, err
+}
+
+// GetBackfillCursor 获取币种的回填进度（没有记录时返回 nil）
+func (s *sqlStore) GetBackfillCursor(symbol string) (*BackfillCursor, error) {
+	row := s.db.QueryRow(`SELECT `+backfillCursorColumns+` FROM sync_backfill_cursors WHERE symbol = ?`, symbol)
+	cursor, err := scanBackfillCursor(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询回填进度失败: %w", err)
+	}
+	return &cursor, nil
+}
+
+// GetBackfillCursors 获取所有币种的回填进度
+func (s *sqlStore) GetBackfillCursors() ([]BackfillCursor, error) {
+	rows, err := s.db.Query(`SELECT ` + backfillCursorColumns + ` FROM sync_backfill_cursors ORDER BY symbol`)
+	if err != nil {
+		return nil, fmt.Errorf("查询回填进度失败: %w", err)
+	}
+	defer rows.Close()
+
+	var cursors []BackfillCursor
+	for rows.Next() {
+		cursor, err := scanBackfillCursor(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		cursors = append(cursors, cursor)
+	}
+	return cursors, rows.Err()
+}
+
+// SaveBackfillCursor 保存回填进度（已存在时覆盖）
+func (s *sqlStore) SaveBackfillCursor(cursor BackfillCursor) error {
+	if _, err := s.db.Exec(s.dialect.upsertBackfillCursorSQL,
+		cursor.Symbol, cursor.Direction, cursor.Status, cursor.TargetStart, cursor.TargetEnd,
+		cursor.NextStart, cursor.NextEnd, cursor.Attempts, truncateMessage(cursor.LastError), cursor.UpdatedAt); err != nil {
+		return fmt.Errorf("保存回填进度失败: %w", err)
+	}
+	return nil
+}
+
+// ReplaceSyncTimeRanges 替换指定币种的全部同步时间段（在同一事务中先删除再插入）
+func (s *sqlStore) ReplaceSyncTimeRanges(symbol string, ranges []SyncTimeRange) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sync_time_ranges WHERE symbol = ?`, symbol); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除同步时间段失败: %w", err)
+	}
+	for _, r := range ranges {
+		if _, err := tx.Exec(`
+			INSERT INTO sync_time_ranges (symbol, start_time, end_time)
+			VALUES (?, ?, ?)
+		`, symbol, r.StartTime, r.EndTime); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("添加同步时间段失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭数据库连接
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// CreateAggregateTable 为指定币种创建聚合K线表（结构与1分钟表相同）
+func (s *sqlStore) CreateAggregateTable(symbol, interval string) error {
+	tableName := GetAggregateTableName(symbol, interval)
+	for _, stmt := range s.dialect.createKLineTableSQL(tableName, interval+" 聚合K线数据表 - "+symbol) {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("创建表 %s 失败: %w", tableName, err)
+		}
+	}
+	return nil
+}
+
+// SaveAggregateKLines 保存聚合K线（open_time 已存在时覆盖，未收盘的周期会随新数据不断更新）
+func (s *sqlStore) SaveAggregateKLines(symbol, interval string, klines []KLine1m) error {
+	if len(klines) == 0 {
+		return nil
+	}
+	if err := s.CreateAggregateTable(symbol, interval); err != nil {
+		return err
+	}
+	tableName := GetAggregateTableName(symbol, interval)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	chunkSize := s.insertChunkSize()
+	for start := 0; start < len(klines); start += chunkSize {
+		chunk := klines[start:min(start+chunkSize, len(klines))]
+		args := make([]interface{}, 0, len(chunk)*klineInsertArgCount)
+		for _, k := range chunk {
+			k.Symbol = symbol
+			args = klineInsertArgs(args, k)
+		}
+		if _, err := tx.Exec(s.buildInsertSQL("INSERT INTO", tableName, len(chunk), s.dialect.upsertKLineClause), args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("写入聚合K线失败 [%s %s]: %w", symbol, interval, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// GetAggregateKLines 按时间范围查询聚合K线（按开盘时间升序）
+func (s *sqlStore) GetAggregateKLines(symbol, interval string, startTime, endTime int64, limit int) ([]KLine1m, error) {
+	return s.queryKLines(GetAggregateTableName(symbol, interval), symbol, startTime, endTime, limit)
+}
+
+// GetAggregateKLinesByCount 获取最近N根聚合K线（按开盘时间升序）
+func (s *sqlStore) GetAggregateKLinesByCount(symbol, interval string, count int) ([]KLine1m, error) {
+	return s.queryKLinesByCount(GetAggregateTableName(symbol, interval), symbol, count)
+}
+
+// GetEarliestKLineTime 获取1分钟表中最早一根K线的开盘时间，没有数据时返回 0
+func (s *sqlStore) GetEarliestKLineTime(symbol string) (int64, error) {
+	var earliest int64
+	err := s.db.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(MIN(open_time), 0)
+		FROM %s
+	`, GetTableName(symbol))).Scan(&earliest)
+	if err != nil {
+		if s.dialect.isMissingTable(err) || errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return earliest, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// ErrNotInitialized 存储后端未初始化
+var ErrNotInitialized = errors.New("数据库连接未初始化")
+
+// KLineStore K线存储后端接口
+// 屏蔽具体数据库引擎的差异（MySQL、SQLite、内存等），包级函数（SaveKLine1m、GetKLines1m 等）都委托给当前存储后端，
+// 测试时可以通过 SetStore 注入自定义实现，无需真实的数据库服务
+// 同步失败的时间段、历史回填进度、隔离的K线、交易对元数据、停止同步的交易对是可选能力
+// （FailedRangeStore、BackfillCursorStore、QuarantineStore、SymbolMetadataStore、InactiveSymbolStore），
+// 与 AggregateKLineStore、MigratableStore 一样由包级函数按需检查，自定义实现只需要提供用到的能力
+type KLineStore interface {
+	// InitSchema 创建全局表（sync_status、sync_time_ranges，以及实现的可选能力使用的表）
+	InitSchema() error
+	// CreateTableForSymbol 为指定币种创建K线表
+	CreateTableForSymbol(symbol string) error
+
+	// SaveKLine1m 批量保存1分钟K线（忽略已存在的数据）
+	SaveKLine1m(klines []KLine1m) (*SaveKLine1mResult, error)
+	// UpsertKLine1m 批量保存1分钟K线（已存在且 OHLCV 不同时覆盖，统计 UpdatedCount）
+	UpsertKLine1m(klines []KLine1m) (*SaveKLine1mResult, error)
+	// DeleteKLines1m 按开盘时间删除1分钟K线，返回删除的数量
+	DeleteKLines1m(symbol string, openTimes []int64) (int, error)
+	// DeleteKLines1mRange 删除开盘时间在 [startTime, endTime] 范围内的1分钟K线，返回删除的数量
+	DeleteKLines1mRange(symbol string, startTime, endTime int64) (int, error)
+	// GetKLines1m 按时间范围查询1分钟K线（按开盘时间升序）
+	GetKLines1m(symbol string, startTime, endTime int64, limit int) ([]KLine1m, error)
+	// GetKLines1mByCount 获取最近N根1分钟K线（按开盘时间升序）
+	GetKLines1mByCount(symbol string, count int) ([]KLine1m, error)
+	// GetLatestKLine1m 获取最新一根1分钟K线，没有数据时返回 nil
+	GetLatestKLine1m(symbol string) (*KLine1m, error)
+	// GetLatestKLineTime 获取最新K线的收盘时间，没有数据时返回 0
+	GetLatestKLineTime(symbol string) (int64, error)
+
+	// UpdateSyncStatus 更新同步状态
+	UpdateSyncStatus(symbol string, lastSyncTime, lastKlineTime int64) error
+	// GetSyncStatus 获取同步状态，没有记录时返回 0
+	GetSyncStatus(symbol string) (lastSyncTime, lastKlineTime int64, err error)
+	// SetPurgedBefore 记录早于该时间的1分钟K线已按保留策略清理（只会增大，不会回退）
+	SetPurgedBefore(symbol string, purgedBefore int64) error
+	// GetPurgedBefore 获取清理边界，没有清理过时返回 0
+	GetPurgedBefore(symbol string) (int64, error)
+
+	// AddSyncTimeRange 记录已同步的时间段
+	AddSyncTimeRange(symbol string, startTime, endTime int64) error
+	// GetSyncTimeRanges 获取已同步的时间段（按开始时间升序）
+	GetSyncTimeRanges(symbol string) ([]SyncTimeRange, error)
+	// ReplaceSyncTimeRanges 用给定的时间段替换指定币种的全部同步记录
+	ReplaceSyncTimeRanges(symbol string, ranges []SyncTimeRange) error
+
+	// Close 释放底层资源
+	Close() error
+}
+
+// OpenStore 根据 DSN 选择并打开存储后端
+// memory:// 使用内存存储（返回的 *sql.DB 为 nil），sqlite:///path/to/file.db 使用 SQLite，其他 DSN 按 MySQL 处理
+func OpenStore(dsn string) (*sql.DB, KLineStore, error) {
+	if IsMemoryDSN(dsn) {
+		return nil, NewMemoryStore(), nil
+	}
+	if IsSQLiteDSN(dsn) {
+		return OpenSQLiteStore(dsn)
+	}
+	return OpenMySQLStore(dsn)
+}
+
+// store 当前使用的存储后端
+var store KLineStore
+
+// SetStore 设置当前使用的存储后端（传入 nil 表示未初始化）
+func SetStore(s KLineStore) {
+	store = s
+}
+
+// GetStore 获取当前使用的存储后端，未初始化时返回 nil
+func GetStore() KLineStore {
+	return store
+}
+
+// currentStore 获取当前存储后端，未初始化时返回 ErrNotInitialized
+func currentStore() (KLineStore, error) {
+	if store == nil {
+		return nil, ErrNotInitialized
+	}
+	return store, nil
+}
+
+// symbolLocks 按币种加锁（每个币种一把互斥锁，按需创建）
+type symbolLocks struct {
+	locks sync.Map
+}
+
+// lock 锁定指定币种，返回解锁函数
+func (l *symbolLocks) lock(symbol string) func() {
+	value, _ := l.locks.LoadOrStore(symbol, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
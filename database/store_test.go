@@ -0,0 +1,75 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+// coreStore 只实现 KLineStore 的存储后端（没有任何可选能力）
+type coreStore struct {
+	KLineStore
+}
+
+func TestStoreCapabilities(t *testing.T) {
+	stores := map[string]KLineStore{
+		"memory": NewMemoryStore(),
+		"sql":    &sqlStore{},
+	}
+	for name, s := range stores {
+		if _, ok := s.(FailedRangeStore); !ok {
+			t.Errorf("%s 应实现 FailedRangeStore", name)
+		}
+		if _, ok := s.(BackfillCursorStore); !ok {
+			t.Errorf("%s 应实现 BackfillCursorStore", name)
+		}
+		if _, ok := s.(QuarantineStore); !ok {
+			t.Errorf("%s 应实现 QuarantineStore", name)
+		}
+		if _, ok := s.(SymbolMetadataStore); !ok {
+			t.Errorf("%s 应实现 SymbolMetadataStore", name)
+		}
+		if _, ok := s.(InactiveSymbolStore); !ok {
+			t.Errorf("%s 应实现 InactiveSymbolStore", name)
+		}
+	}
+	if _, ok := stores["sql"].(AggregateKLineStore); !ok {
+		t.Error("sql 应实现 AggregateKLineStore")
+	}
+	if _, ok := stores["sql"].(MigratableStore); !ok {
+		t.Error("sql 应实现 MigratableStore")
+	}
+}
+
+func TestStoreWithoutCapabilities(t *testing.T) {
+	previous := GetStore()
+	SetStore(coreStore{KLineStore: NewMemoryStore()})
+	t.Cleanup(func() { SetStore(previous) })
+
+	checks := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"RecordFailedRange", RecordFailedRange("BTC_USDT", 0, 1, "network", ""), ErrFailedRangesNotSupported},
+		{"SaveBackfillCursor", SaveBackfillCursor(BackfillCursor{Symbol: "BTC_USDT"}), ErrBackfillNotSupported},
+		{"QuarantineKLines", QuarantineKLines(nil), ErrQuarantineNotSupported},
+		{"SaveSymbolMetadata", SaveSymbolMetadata(nil), ErrSymbolMetadataNotSupported},
+		{"MarkSymbolInactive", MarkSymbolInactive(InactiveSymbol{Symbol: "BTC_USDT"}), ErrInactiveSymbolsNotSupported},
+	}
+	for _, c := range checks {
+		if !errors.Is(c.err, c.want) {
+			t.Errorf("%s: err = %v，期望 %v", c.name, c.err, c.want)
+		}
+	}
+	if SupportsAggregates() {
+		t.Error("没有聚合能力的存储后端不应支持聚合表")
+	}
+
+	// 核心功能不受影响
+	if _, err := SaveKLine1m(testKLines("BTC_USDT", 0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if klines, err := GetKLines1m("BTC_USDT", 0, 0, 0); err != nil || len(klines) != 3 {
+		t.Errorf("GetKLines1m = %d 根, %v", len(klines), err)
+	}
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"wails-contract-warn/config"
+)
+
+// ErrSymbolMetadataNotSupported 当前存储后端不支持保存交易对元数据
+var ErrSymbolMetadataNotSupported = errors.New("当前存储后端不支持保存交易对元数据")
+
+// SymbolMeta 交易对元数据（来自交易所的交易对接口，缓存在 symbol_metadata 表）
+type SymbolMeta struct {
+	Symbol          string  `json:"symbol"`          // 交易对（BTC_USDT 格式）
+	Exchange        string  `json:"exchange"`        // 数据来源的交易所
+	Base            string  `json:"base"`            // 基础币种
+	Quote           string  `json:"quote"`           // 计价币种
+	PricePrecision  int     `json:"pricePrecision"`  // 价格小数位数
+	AmountPrecision int     `json:"amountPrecision"` // 数量小数位数
+	MinBaseAmount   float64 `json:"minBaseAmount"`   // 最小下单数量（基础币种，0 表示不限制）
+	MinQuoteAmount  float64 `json:"minQuoteAmount"`  // 最小下单金额（计价币种，0 表示不限制）
+	ListedAt        int64   `json:"listedAt"`        // 上线时间（毫秒时间戳，0 表示未知）
+	TradeStatus     string  `json:"tradeStatus"`     // 交易状态（如 tradable、untradable）
+	UpdatedAt       int64   `json:"updatedAt"`       // 更新时间（毫秒时间戳）
+}
+
+// symbolRegistry 交易对元数据的内存索引（规范化交易对、计算回填起点时频繁查询，不每次访问数据库）
+var symbolRegistry = struct {
+	mu        sync.RWMutex
+	bySymbol  map[string]SymbolMeta // key: BTC_USDT
+	byCompact map[string]string     // key: BTCUSDT，value: BTC_USDT
+	updatedAt int64                 // 最近一次更新的时间（毫秒时间戳）
+}{
+	bySymbol:  make(map[string]SymbolMeta),
+	byCompact: make(map[string]string),
+}
+
+// SymbolMetadataStore 保存交易对元数据的存储后端（可选能力，MySQL、SQLite、内存存储实现）
+type SymbolMetadataStore interface {
+	// SaveSymbolMetadata 批量保存交易对元数据（同一交易对已存在时覆盖）
+	SaveSymbolMetadata(metas []SymbolMeta) error
+	// GetSymbolMetadata 获取所有交易对元数据（按交易对排序）
+	GetSymbolMetadata() ([]SymbolMeta, error)
+}
+
+// symbolMetadataStore 获取当前支持保存交易对元数据的存储后端
+func symbolMetadataStore() (SymbolMetadataStore, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	ms, ok := s.(SymbolMetadataStore)
+	if !ok {
+		return nil, ErrSymbolMetadataNotSupported
+	}
+	return ms, nil
+}
+
+// SaveSymbolMetadata 保存交易对元数据，并更新内存索引
+func SaveSymbolMetadata(metas []SymbolMeta) error {
+	ms, err := symbolMetadataStore()
+	if err != nil {
+		return err
+	}
+	if err := ms.SaveSymbolMetadata(metas); err != nil {
+		return err
+	}
+	indexSymbolMetadata(metas)
+	return nil
+}
+
+// GetSymbolMetadata 获取数据库中所有交易对元数据
+func GetSymbolMetadata() ([]SymbolMeta, error) {
+	ms, err := symbolMetadataStore()
+	if err != nil {
+		return nil, err
+	}
+	return ms.GetSymbolMetadata()
+}
+
+// LoadSymbolRegistry 从数据库加载交易对元数据到内存索引，返回加载的数量
+func LoadSymbolRegistry() (int, error) {
+	metas, err := GetSymbolMetadata()
+	if err != nil {
+		return 0, err
+	}
+	indexSymbolMetadata(metas)
+	return len(metas), nil
+}
+
+// indexSymbolMetadata 把元数据加入内存索引（同一交易对覆盖）
+func indexSymbolMetadata(metas []SymbolMeta) {
+	symbolRegistry.mu.Lock()
+	defer symbolRegistry.mu.Unlock()
+	for _, meta := range metas {
+		symbolRegistry.bySymbol[meta.Symbol] = meta
+		symbolRegistry.byCompact[strings.ReplaceAll(meta.Symbol, "_", "")] = meta.Symbol
+		if meta.UpdatedAt > symbolRegistry.updatedAt {
+			symbolRegistry.updatedAt = meta.UpdatedAt
+		}
+	}
+}
+
+// LookupSymbolMeta 查询交易对元数据（合约的存储名 BTC_USDT_PERP 使用对应现货交易对的元数据）
+func LookupSymbolMeta(symbol string) (SymbolMeta, bool) {
+	symbol = strings.TrimSuffix(symbol, config.FuturesSymbolSuffix)
+	symbolRegistry.mu.RLock()
+	defer symbolRegistry.mu.RUnlock()
+	meta, ok := symbolRegistry.bySymbol[symbol]
+	return meta, ok
+}
+
+// ResolveCompactSymbol 把无分隔符的交易对（如 BTCUSDT）拆分为 BTC_USDT，元数据中没有时返回 false
+func ResolveCompactSymbol(compact string) (string, bool) {
+	symbolRegistry.mu.RLock()
+	defer symbolRegistry.mu.RUnlock()
+	symbol, ok := symbolRegistry.byCompact[compact]
+	return symbol, ok
+}
+
+// SymbolListedAt 交易对的上线时间（毫秒时间戳），元数据中没有或未知时返回 0
+func SymbolListedAt(symbol string) int64 {
+	meta, ok := LookupSymbolMeta(symbol)
+	if !ok {
+		return 0
+	}
+	return meta.ListedAt
+}
+
+// SymbolRegistryUpdatedAt 交易对元数据最近一次更新的时间（毫秒时间戳），没有元数据时返回 0
+func SymbolRegistryUpdatedAt() int64 {
+	symbolRegistry.mu.RLock()
+	defer symbolRegistry.mu.RUnlock()
+	return symbolRegistry.updatedAt
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// syncRangeLocks 每个币种一把锁，串行化对 sync_time_ranges 的“读取-替换”操作
+var syncRangeLocks symbolLocks
+
+// DeriveSyncTimeRanges 根据K线表中实际存在的数据推导已同步的时间段
+// 连续的分钟合并为一个时间段: [第一根K线的开盘时间, 最后一根K线的开盘时间 + 59999]
+func DeriveSyncTimeRanges(symbol string) ([]SyncTimeRange, error) {
+	var ranges []SyncTimeRange
+	var from int64
+	for {
+		klines, err := GetKLines1m(symbol, from, 0, integrityScanBatch)
+		if err != nil {
+			return nil, fmt.Errorf("读取K线失败: %w", err)
+		}
+
+		for _, k := range klines {
+			minute := k.OpenTime / minuteMs * minuteMs
+			if n := len(ranges); n > 0 && minute <= ranges[n-1].EndTime+1 {
+				ranges[n-1].EndTime = max(ranges[n-1].EndTime, minute+minuteMs-1)
+				continue
+			}
+			ranges = append(ranges, SyncTimeRange{StartTime: minute, EndTime: minute + minuteMs - 1})
+		}
+
+		if len(klines) < integrityScanBatch {
+			break
+		}
+		from = klines[len(klines)-1].OpenTime + 1
+	}
+	return ranges, nil
+}
+
+// SyncRangeRebuildResult 重建同步时间段的结果
+type SyncRangeRebuildResult struct {
+	Symbol         string `json:"symbol"`
+	RangesBefore   int    `json:"rangesBefore"`
+	RangesAfter    int    `json:"rangesAfter"`
+	CoveredMinutes int64  `json:"coveredMinutes"`
+}
+
+// RebuildSyncTimeRanges 用K线表中实际存在的数据重建指定币种的 sync_time_ranges（在一个事务中整体替换）
+// 重建期间其他同步任务记录时间段会等待重建完成，不会丢失；已按保留策略清理的时间段保持不变
+func RebuildSyncTimeRanges(symbol string) (*SyncRangeRebuildResult, error) {
+	s, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := syncRangeLocks.lock(symbol)
+	defer unlock()
+
+	before, err := s.GetSyncTimeRanges(symbol)
+	if err != nil {
+		return nil, err
+	}
+	derived, err := DeriveSyncTimeRanges(symbol)
+	if err != nil {
+		return nil, err
+	}
+	purgedBefore, err := s.GetPurgedBefore(symbol)
+	if err != nil {
+		return nil, err
+	}
+	ranges := derived
+	if purgedBefore > 0 {
+		// 清理边界之前也可能有K线（如清理后导入的旧数据），合并前按开始时间排序
+		ranges = subtractRanges(before, []SyncTimeRange{{StartTime: purgedBefore, EndTime: math.MaxInt64}})
+		ranges = append(ranges, derived...)
+		sort.Slice(ranges, func(i, j int) bool {
+			return ranges[i].StartTime < ranges[j].StartTime
+		})
+		ranges = mergeRanges(ranges)
+	}
+	if err := s.ReplaceSyncTimeRanges(symbol, ranges); err != nil {
+		return nil, err
+	}
+
+	result := &SyncRangeRebuildResult{
+		Symbol:       symbol,
+		RangesBefore: len(before),
+		RangesAfter:  len(ranges),
+	}
+	for _, r := range ranges {
+		result.CoveredMinutes += (r.EndTime - r.StartTime + 1) / minuteMs
+	}
+
+	logger.Infof("[%s] 已根据实际数据重建同步时间段: %d -> %d 个, 覆盖 %d 分钟",
+		symbol, result.RangesBefore, result.RangesAfter, result.CoveredMinutes)
+	return result, nil
+}
+
+// RebuildSyncTimeRangesFromConfig 重建配置文件中所有启用币种的同步时间段
+func RebuildSyncTimeRangesFromConfig() ([]*SyncRangeRebuildResult, error) {
+	allSymbols, err := config.GetAllEnabledSymbols()
+	if err != nil {
+		return nil, fmt.Errorf("获取币种配置失败: %w", err)
+	}
+
+	started := time.Now()
+	results := make([]*SyncRangeRebuildResult, 0, len(allSymbols))
+	for _, symbolConfig := range allSymbols {
+		result, err := RebuildSyncTimeRanges(symbolConfig.Symbol)
+		if err != nil {
+			return results, fmt.Errorf("重建同步时间段失败 (币种: %s): %w", symbolConfig.Symbol, err)
+		}
+		results = append(results, result)
+	}
+
+	logger.Infof("同步时间段重建完成: %d 个币种, 耗时 %s", len(results), time.Since(started).Round(time.Millisecond))
+	return results, nil
+}
+
+// ReopenSyncTimeRanges 从已同步时间段中移除指定的时间段（下次同步时会重新拉取）
+func ReopenSyncTimeRanges(symbol string, reopen []SyncTimeRange) error {
+	s, err := currentStore()
+	if err != nil {
+		return err
+	}
+
+	unlock := syncRangeLocks.lock(symbol)
+	defer unlock()
+
+	ranges, err := s.GetSyncTimeRanges(symbol)
+	if err != nil {
+		return err
+	}
+
+	remaining := subtractRanges(ranges, reopen)
+	if err := s.ReplaceSyncTimeRanges(symbol, remaining); err != nil {
+		return fmt.Errorf("更新同步时间段失败: %w", err)
+	}
+	return nil
+}
+
+// subtractRanges 从 ranges 中减去 remove 覆盖的部分（时间段均为闭区间）
+func subtractRanges(ranges, remove []SyncTimeRange) []SyncTimeRange {
+	result := append([]SyncTimeRange(nil), ranges...)
+	for _, rm := range remove {
+		var next []SyncTimeRange
+		for _, r := range result {
+			if rm.EndTime < r.StartTime || rm.StartTime > r.EndTime {
+				next = append(next, r)
+				continue
+			}
+			if r.StartTime < rm.StartTime {
+				next = append(next, SyncTimeRange{StartTime: r.StartTime, EndTime: rm.StartTime - 1})
+			}
+			if r.EndTime > rm.EndTime {
+				next = append(next, SyncTimeRange{StartTime: rm.EndTime + 1, EndTime: r.EndTime})
+			}
+		}
+		result = next
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestSubtractRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []SyncTimeRange
+		remove []SyncTimeRange
+		want   []SyncTimeRange
+	}{
+		{
+			name:   "不移除",
+			ranges: []SyncTimeRange{{0, 999}},
+			want:   []SyncTimeRange{{0, 999}},
+		},
+		{
+			name:   "完全覆盖",
+			ranges: []SyncTimeRange{{100, 199}},
+			remove: []SyncTimeRange{{100, 199}},
+		},
+		{
+			name:   "移除的范围更大",
+			ranges: []SyncTimeRange{{100, 199}, {300, 399}},
+			remove: []SyncTimeRange{{0, 999}},
+		},
+		{
+			name:   "中间拆分为两段",
+			ranges: []SyncTimeRange{{0, 999}},
+			remove: []SyncTimeRange{{400, 499}},
+			want:   []SyncTimeRange{{0, 399}, {500, 999}},
+		},
+		{
+			name:   "只移除一个点",
+			ranges: []SyncTimeRange{{0, 999}},
+			remove: []SyncTimeRange{{500, 500}},
+			want:   []SyncTimeRange{{0, 499}, {501, 999}},
+		},
+		{
+			name:   "与开始端点相接",
+			ranges: []SyncTimeRange{{100, 199}},
+			remove: []SyncTimeRange{{0, 100}},
+			want:   []SyncTimeRange{{101, 199}},
+		},
+		{
+			name:   "与结束端点相接",
+			ranges: []SyncTimeRange{{100, 199}},
+			remove: []SyncTimeRange{{199, 300}},
+			want:   []SyncTimeRange{{100, 198}},
+		},
+		{
+			name:   "紧邻但不重叠",
+			ranges: []SyncTimeRange{{100, 199}},
+			remove: []SyncTimeRange{{0, 99}, {200, 299}},
+			want:   []SyncTimeRange{{100, 199}},
+		},
+		{
+			name:   "跨越多个时间段",
+			ranges: []SyncTimeRange{{0, 99}, {200, 299}, {400, 499}},
+			remove: []SyncTimeRange{{50, 449}},
+			want:   []SyncTimeRange{{0, 49}, {450, 499}},
+		},
+		{
+			name:   "多个移除范围",
+			ranges: []SyncTimeRange{{0, 999}},
+			remove: []SyncTimeRange{{100, 199}, {0, 9}, {900, 999}},
+			want:   []SyncTimeRange{{10, 99}, {200, 899}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]SyncTimeRange(nil), tt.ranges...)
+			assertRanges(t, subtractRanges(tt.ranges, tt.remove), tt.want)
+			assertRanges(t, tt.ranges, input) // 不修改输入
+		})
+	}
+}
+
+func TestDeriveSyncTimeRanges(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+
+	klines := append(testKLines(symbol, 0, 2), testKLines(symbol, 5, 5)...)
+	misaligned := testKLine(symbol, 6, 106)
+	misaligned.OpenTime += 5000 // 非整分钟的K线按所在分钟计算
+	duplicate := testKLine(symbol, 2, 102)
+	duplicate.OpenTime += 30000 // 同一分钟的重复K线不延长时间段
+	klines = append(klines, misaligned, duplicate)
+	if _, err := SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+
+	ranges, err := DeriveSyncTimeRanges(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRanges(t, ranges, []SyncTimeRange{minuteSpan(0, 2), minuteSpan(5, 6)})
+}
+
+func TestRebuildSyncTimeRanges(t *testing.T) {
+	const symbol = "BTC_USDT"
+	tests := []struct {
+		name         string
+		before       []SyncTimeRange
+		klines       []KLine1m
+		purgedBefore int64
+		want         []SyncTimeRange
+	}{
+		{
+			name:   "移除没有数据的已同步时间段",
+			before: []SyncTimeRange{minuteSpan(0, 20)},
+			klines: append(testKLines(symbol, 0, 4), testKLines(symbol, 8, 9)...),
+			want:   []SyncTimeRange{minuteSpan(0, 4), minuteSpan(8, 9)},
+		},
+		{
+			name:   "补上缺少记录的时间段",
+			klines: testKLines(symbol, 3, 5),
+			want:   []SyncTimeRange{minuteSpan(3, 5)},
+		},
+		{
+			name:   "没有数据",
+			before: []SyncTimeRange{minuteSpan(0, 9)},
+		},
+		{
+			name:         "保留清理边界之前的时间段，与之后的数据相邻时合并",
+			before:       []SyncTimeRange{minuteSpan(0, 9), minuteSpan(12, 19)},
+			klines:       append(testKLines(symbol, 5, 6), testKLines(symbol, 15, 19)...),
+			purgedBefore: minuteAt(5),
+			want:         []SyncTimeRange{minuteSpan(0, 6), minuteSpan(15, 19)},
+		},
+		{
+			name:         "跨越清理边界的时间段只保留边界之前的部分",
+			before:       []SyncTimeRange{minuteSpan(0, 9)},
+			klines:       testKLines(symbol, 8, 9),
+			purgedBefore: minuteAt(5),
+			want:         []SyncTimeRange{minuteSpan(0, 4), minuteSpan(8, 9)},
+		},
+		{
+			name:         "清理边界之前也有数据",
+			before:       []SyncTimeRange{minuteSpan(3, 4)},
+			klines:       append(testKLines(symbol, 0, 1), testKLines(symbol, 8, 8)...),
+			purgedBefore: minuteAt(5),
+			want:         []SyncTimeRange{minuteSpan(0, 1), minuteSpan(3, 4), minuteSpan(8, 8)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := useMemoryStore(t)
+			if len(tt.klines) > 0 {
+				if _, err := SaveKLine1m(tt.klines); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := m.ReplaceSyncTimeRanges(symbol, tt.before); err != nil {
+				t.Fatal(err)
+			}
+			if tt.purgedBefore > 0 {
+				m.SetPurgedBefore(symbol, tt.purgedBefore)
+			}
+
+			result, err := RebuildSyncTimeRanges(symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ranges, err := GetSyncTimeRanges(symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRanges(t, ranges, tt.want)
+
+			var covered int64
+			for _, r := range tt.want {
+				covered += (r.EndTime - r.StartTime + 1) / minuteMs
+			}
+			if result.Symbol != symbol || result.RangesBefore != len(tt.before) || result.RangesAfter != len(tt.want) || result.CoveredMinutes != covered {
+				t.Errorf("结果 = %+v，期望 %d -> %d 个时间段, 覆盖 %d 分钟", result, len(tt.before), len(tt.want), covered)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+// Package export 把数据库中的K线导出为 CSV、JSON Lines 或 Parquet 文件
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Windows 等没有时区数据库的系统也能使用 Asia/Shanghai 等时区
+
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// 导出格式
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// 可导出的列
+const (
+	ColumnTime      = "time"       // 开盘时间（按指定时区格式化的字符串）
+	ColumnOpenTime  = "open_time"  // 开盘时间（毫秒时间戳）
+	ColumnCloseTime = "close_time" // 收盘时间（毫秒时间戳）
+	ColumnSymbol    = "symbol"
+	ColumnOpen      = "open"
+	ColumnHigh      = "high"
+	ColumnLow       = "low"
+	ColumnClose     = "close"
+	ColumnVolume    = "volume"       // 成交量（基础币种）
+	ColumnQuoteVol  = "quote_volume" // 成交额（计价币种）
+	ColumnTrades    = "trade_count"  // 成交笔数
+)
+
+// AllColumns 所有可导出的列
+var AllColumns = []string{
+	ColumnTime, ColumnOpenTime, ColumnCloseTime, ColumnSymbol,
+	ColumnOpen, ColumnHigh, ColumnLow, ColumnClose, ColumnVolume, ColumnQuoteVol, ColumnTrades,
+}
+
+// DefaultColumns 未指定列时导出的列
+var DefaultColumns = []string{ColumnTime, ColumnOpen, ColumnHigh, ColumnLow, ColumnClose, ColumnVolume}
+
+// DefaultTimeLayout time 列的默认格式（RFC3339，带时区偏移，pandas 等可以直接解析）
+const DefaultTimeLayout = time.RFC3339
+
+// exportBatchSize 每次从数据库读取的K线数量
+const exportBatchSize = 10000
+
+// Options 导出参数
+type Options struct {
+	Symbol     string   // 币种，如 BTC_USDT
+	Interval   string   // 周期: 1m 或聚合周期（5m、15m、1h、4h、1d），默认 1m
+	StartTime  int64    // 开始时间（毫秒时间戳，包含），0 表示不限
+	EndTime    int64    // 结束时间（毫秒时间戳，包含），0 表示不限
+	Format     string   // csv、jsonl、parquet，默认根据文件扩展名判断
+	Columns    []string // 导出的列，默认 DefaultColumns
+	Timezone   string   // time 列使用的时区（IANA 名称，如 Asia/Shanghai），默认 UTC
+	TimeLayout string   // time 列的格式（Go 时间格式），默认 DefaultTimeLayout
+}
+
+// Result 导出结果
+type Result struct {
+	Path      string   `json:"path"`
+	Format    string   `json:"format"`
+	Symbol    string   `json:"symbol"`
+	Interval  string   `json:"interval"`
+	Columns   []string `json:"columns"`
+	Rows      int      `json:"rows"`
+	StartTime int64    `json:"startTime"` // 第一根K线的开盘时间
+	EndTime   int64    `json:"endTime"`   // 最后一根K线的开盘时间
+	Bytes     int64    `json:"bytes"`
+}
+
+// FormatFromPath 根据文件扩展名推断导出格式
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".parquet":
+		return FormatParquet
+	default:
+		return FormatCSV
+	}
+}
+
+// ParseColumns 解析逗号分隔的列名，空字符串返回 DefaultColumns
+func ParseColumns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultColumns, nil
+	}
+	var columns []string
+	for _, c := range strings.Split(value, ",") {
+		columns = append(columns, strings.TrimSpace(c))
+	}
+	return columns, validateColumns(columns)
+}
+
+func validateColumns(columns []string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("至少需要导出一列")
+	}
+	seen := make(map[string]bool)
+	for _, c := range columns {
+		if !contains(AllColumns, c) {
+			return fmt.Errorf("不支持的列: %s（可选: %s）", c, strings.Join(AllColumns, ", "))
+		}
+		if seen[c] {
+			return fmt.Errorf("重复的列: %s", c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+// normalize 校验参数并填充默认值
+func (o *Options) normalize(path string) (*time.Location, error) {
+	if o.Symbol == "" {
+		return nil, fmt.Errorf("币种不能为空")
+	}
+	if o.Interval == "" {
+		o.Interval = "1m"
+	}
+	if _, ok := database.AggregateIntervals()[o.Interval]; !ok && o.Interval != "1m" {
+		return nil, fmt.Errorf("不支持的周期: %s", o.Interval)
+	}
+	if o.Interval != "1m" && !database.SupportsAggregates() {
+		return nil, fmt.Errorf("导出 %s 周期失败: %w", o.Interval, database.ErrAggregateNotSupported)
+	}
+	if o.EndTime > 0 && o.StartTime > o.EndTime {
+		return nil, fmt.Errorf("开始时间不能晚于结束时间")
+	}
+
+	if o.Format == "" {
+		o.Format = FormatFromPath(path)
+	}
+	if o.Format != FormatCSV && o.Format != FormatJSONL && o.Format != FormatParquet {
+		return nil, fmt.Errorf("不支持的导出格式: %s", o.Format)
+	}
+
+	if len(o.Columns) == 0 {
+		o.Columns = DefaultColumns
+	}
+	if err := validateColumns(o.Columns); err != nil {
+		return nil, err
+	}
+
+	if o.TimeLayout == "" {
+		o.TimeLayout = DefaultTimeLayout
+	}
+	if o.Timezone == "" {
+		o.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(o.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 %s: %w", o.Timezone, err)
+	}
+	return loc, nil
+}
+
+// rowWriter 按行写入一种导出格式
+type rowWriter interface {
+	writeRow(k database.KLine1m) error
+	close() error
+}
+
+// ToFile 把指定币种、周期、时间范围的K线流式导出到文件（分批读取，内存占用与导出的行数无关）
+// 导出失败时删除不完整的文件
+func ToFile(path string, opts Options) (result *Result, err error) {
+	loc, err := opts.normalize(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建导出目录失败: %w", err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	buffered := bufio.NewWriterSize(file, 1<<20)
+	rw, err := newRowWriter(buffered, opts, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	result = &Result{
+		Path:     path,
+		Format:   opts.Format,
+		Symbol:   opts.Symbol,
+		Interval: opts.Interval,
+		Columns:  opts.Columns,
+	}
+	from := opts.StartTime
+	for {
+		klines, err := readBatch(opts, from)
+		if err != nil {
+			return nil, fmt.Errorf("读取K线失败: %w", err)
+		}
+		for _, k := range klines {
+			if err := rw.writeRow(k); err != nil {
+				return nil, fmt.Errorf("写入导出文件失败: %w", err)
+			}
+			if result.Rows == 0 {
+				result.StartTime = k.OpenTime
+			}
+			result.EndTime = k.OpenTime
+			result.Rows++
+		}
+		if len(klines) < exportBatchSize {
+			break
+		}
+		from = klines[len(klines)-1].OpenTime + 1
+	}
+
+	if err := rw.close(); err != nil {
+		return nil, fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return nil, fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	if info, err := file.Stat(); err == nil {
+		result.Bytes = info.Size()
+	}
+
+	logger.Infof("[%s] 导出 %s K线完成: %d 行, 格式=%s, 文件=%s, 耗时=%s", opts.Symbol, opts.Interval,
+		result.Rows, opts.Format, path, time.Since(started).Round(time.Millisecond))
+	return result, nil
+}
+
+// readBatch 从 from 开始读取一批K线
+func readBatch(opts Options, from int64) ([]database.KLine1m, error) {
+	if opts.Interval == "1m" {
+		return database.GetKLines1m(opts.Symbol, from, opts.EndTime, exportBatchSize)
+	}
+	return database.GetAggregateKLines(opts.Symbol, opts.Interval, from, opts.EndTime, exportBatchSize)
+}
+
+func newRowWriter(w *bufio.Writer, opts Options, loc *time.Location) (rowWriter, error) {
+	switch opts.Format {
+	case FormatJSONL:
+		return &jsonlWriter{w: w, opts: opts, loc: loc}, nil
+	case FormatParquet:
+		return newParquetRowWriter(w, opts, loc)
+	default:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(opts.Columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw, opts: opts, loc: loc}, nil
+	}
+}
+
+// formatFloat 格式化价格和成交量（不丢失精度，不使用科学计数法）
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// columnValue 返回一列的文本值（CSV 使用）
+func columnValue(k database.KLine1m, column string, opts Options, loc *time.Location) string {
+	switch column {
+	case ColumnTime:
+		return time.UnixMilli(k.OpenTime).In(loc).Format(opts.TimeLayout)
+	case ColumnOpenTime:
+		return strconv.FormatInt(k.OpenTime, 10)
+	case ColumnCloseTime:
+		return strconv.FormatInt(k.CloseTime, 10)
+	case ColumnSymbol:
+		return opts.Symbol
+	case ColumnOpen:
+		return formatFloat(k.Open)
+	case ColumnHigh:
+		return formatFloat(k.High)
+	case ColumnLow:
+		return formatFloat(k.Low)
+	case ColumnClose:
+		return formatFloat(k.Close)
+	case ColumnVolume:
+		return formatFloat(k.Volume)
+	case ColumnQuoteVol:
+		return formatFloat(k.QuoteVolume)
+	case ColumnTrades:
+		return strconv.FormatInt(k.TradeCount, 10)
+	}
+	return ""
+}
+
+// csvWriter CSV 格式（第一行为列名）
+type csvWriter struct {
+	w    *csv.Writer
+	opts Options
+	loc  *time.Location
+	row  []string
+}
+
+func (c *csvWriter) writeRow(k database.KLine1m) error {
+	c.row = c.row[:0]
+	for _, column := range c.opts.Columns {
+		c.row = append(c.row, columnValue(k, column, c.opts, c.loc))
+	}
+	return c.w.Write(c.row)
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlWriter JSON Lines 格式（每行一个 JSON 对象，字段顺序与列顺序一致）
+type jsonlWriter struct {
+	w    *bufio.Writer
+	opts Options
+	loc  *time.Location
+	line []byte
+}
+
+func (j *jsonlWriter) writeRow(k database.KLine1m) error {
+	j.line = append(j.line[:0], '{')
+	for i, column := range j.opts.Columns {
+		if i > 0 {
+			j.line = append(j.line, ',')
+		}
+		j.line = strconv.AppendQuote(j.line, column)
+		j.line = append(j.line, ':')
+		value := columnValue(k, column, j.opts, j.loc)
+		if column == ColumnTime || column == ColumnSymbol {
+			quoted, _ := json.Marshal(value)
+			j.line = append(j.line, quoted...)
+		} else {
+			j.line = append(j.line, value...)
+		}
+	}
+	j.line = append(j.line, '}', '\n')
+	_, err := j.w.Write(j.line)
+	return err
+}
+
+func (j *jsonlWriter) close() error {
+	return nil
+}
+
+// parquetRowWriter Parquet 格式（open_time、close_time 为毫秒时间戳，trade_count 为 INT64，价格和成交量为 DOUBLE）
+type parquetRowWriter struct {
+	pw      *parquetWriter
+	opts    Options
+	loc     *time.Location
+	columns map[string]*parquetColumn
+}
+
+func newParquetRowWriter(w *bufio.Writer, opts Options, loc *time.Location) (*parquetRowWriter, error) {
+	prw := &parquetRowWriter{opts: opts, loc: loc, columns: make(map[string]*parquetColumn)}
+	var columns []*parquetColumn
+	for _, name := range opts.Columns {
+		c := &parquetColumn{name: name, physical: parquetDouble, converted: convertedNone}
+		switch name {
+		case ColumnTime, ColumnSymbol:
+			c.physical, c.converted = parquetByteArray, convertedUTF8
+		case ColumnOpenTime, ColumnCloseTime:
+			c.physical, c.converted = parquetInt64, convertedTimestampMillis
+		case ColumnTrades:
+			c.physical = parquetInt64
+		}
+		prw.columns[name] = c
+		columns = append(columns, c)
+	}
+
+	pw, err := newParquetWriter(w, columns, map[string]string{
+		"symbol":   opts.Symbol,
+		"interval": opts.Interval,
+		"timezone": opts.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	prw.pw = pw
+	return prw, nil
+}
+
+func (p *parquetRowWriter) writeRow(k database.KLine1m) error {
+	for _, name := range p.opts.Columns {
+		c := p.columns[name]
+		switch name {
+		case ColumnTime, ColumnSymbol:
+			c.appendString(columnValue(k, name, p.opts, p.loc))
+		case ColumnOpenTime:
+			c.appendInt64(k.OpenTime)
+		case ColumnCloseTime:
+			c.appendInt64(k.CloseTime)
+		case ColumnOpen:
+			c.appendDouble(k.Open)
+		case ColumnHigh:
+			c.appendDouble(k.High)
+		case ColumnLow:
+			c.appendDouble(k.Low)
+		case ColumnClose:
+			c.appendDouble(k.Close)
+		case ColumnVolume:
+			c.appendDouble(k.Volume)
+		case ColumnQuoteVol:
+			c.appendDouble(k.QuoteVolume)
+		case ColumnTrades:
+			c.appendInt64(k.TradeCount)
+		}
+	}
+	return p.pw.endRow()
+}
+
+func (p *parquetRowWriter) close() error {
+	return p.pw.close()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
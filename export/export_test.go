@@ -0,0 +1,181 @@
+package export
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"wails-contract-warn/database"
+)
+
+const testBase = int64(1700006400000) // 2023-11-15 00:00:00 UTC
+
+// useMemoryStore 使用内存存储，测试结束后恢复原来的存储
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	previous := database.GetStore()
+	database.SetStore(database.NewMemoryStore())
+	t.Cleanup(func() { database.SetStore(previous) })
+}
+
+// saveMinutes 保存第 from 到 to 分钟（不含）的1分钟K线
+func saveMinutes(t *testing.T, symbol string, from, to int) {
+	t.Helper()
+	var klines []database.KLine1m
+	for n := from; n < to; n++ {
+		openTime := testBase + int64(n)*60000
+		price := 36000 + float64(n)*0.5
+		klines = append(klines, database.KLine1m{
+			Symbol:      symbol,
+			OpenTime:    openTime,
+			Open:        price,
+			High:        price + 1.25,
+			Low:         price - 0.75,
+			Close:       price + 0.25,
+			Volume:      1.5 + float64(n),
+			QuoteVolume: 54000.25 + float64(n),
+			TradeCount:  int64(100 + n),
+			CloseTime:   openTime + 59999,
+		})
+	}
+	if _, err := database.SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToFileGolden(t *testing.T) {
+	useMemoryStore(t)
+	saveMinutes(t, "BTC_USDT", 0, 5)
+
+	tests := []struct {
+		name   string
+		file   string
+		golden string
+		opts   Options
+	}{
+		{
+			name:   "CSV 默认列",
+			file:   "default.csv",
+			golden: "default.csv",
+			opts:   Options{Symbol: "BTC_USDT", StartTime: testBase + 60000, EndTime: testBase + 3*60000},
+		},
+		{
+			name:   "CSV 指定列和时区",
+			file:   "columns.csv",
+			golden: "columns_shanghai.csv",
+			opts: Options{
+				Symbol: "BTC_USDT", StartTime: testBase + 60000, EndTime: testBase + 3*60000,
+				Columns:    []string{ColumnSymbol, ColumnTime, ColumnOpenTime, ColumnCloseTime, ColumnClose, ColumnQuoteVol, ColumnTrades},
+				Timezone:   "Asia/Shanghai",
+				TimeLayout: "2006-01-02 15:04:05",
+			},
+		},
+		{
+			name:   "JSONL 字段顺序与列顺序一致",
+			file:   "columns.jsonl",
+			golden: "columns_shanghai.jsonl",
+			opts: Options{
+				Symbol: "BTC_USDT", StartTime: testBase + 60000, EndTime: testBase + 3*60000,
+				Columns:  []string{ColumnTime, ColumnSymbol, ColumnOpenTime, ColumnClose, ColumnVolume, ColumnTrades},
+				Timezone: "Asia/Shanghai",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			result, err := ToFile(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Rows != 3 || result.StartTime != testBase+60000 || result.EndTime != testBase+3*60000 {
+				t.Errorf("Rows/StartTime/EndTime = %d/%d/%d", result.Rows, result.StartTime, result.EndTime)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("导出内容与 testdata/%s 不一致:\n%s", tt.golden, got)
+			}
+			if result.Bytes != int64(len(got)) {
+				t.Errorf("Bytes = %d，文件大小 %d", result.Bytes, len(got))
+			}
+		})
+	}
+}
+
+func TestToFileBatchBoundary(t *testing.T) {
+	// 行数恰好等于批大小时多读一次空批次；多一行时第二批只有一行
+	for _, rows := range []int{exportBatchSize - 1, exportBatchSize, exportBatchSize + 1} {
+		t.Run(strconv.Itoa(rows), func(t *testing.T) {
+			useMemoryStore(t)
+			saveMinutes(t, "BTC_USDT", 0, rows)
+
+			path := filepath.Join(t.TempDir(), "batch.csv")
+			result, err := ToFile(path, Options{Symbol: "BTC_USDT", Columns: []string{ColumnOpenTime}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Rows != rows || result.StartTime != testBase || result.EndTime != testBase+int64(rows-1)*60000 {
+				t.Errorf("Rows/StartTime/EndTime = %d/%d/%d", result.Rows, result.StartTime, result.EndTime)
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			scanner.Scan() // 列名
+			n := 0
+			for scanner.Scan() {
+				if want := strconv.FormatInt(testBase+int64(n)*60000, 10); scanner.Text() != want {
+					t.Fatalf("第 %d 行 = %s，期望 %s（批次边界处重复或遗漏）", n, scanner.Text(), want)
+				}
+				n++
+			}
+			if n != rows {
+				t.Errorf("文件中有 %d 行数据，期望 %d", n, rows)
+			}
+		})
+	}
+}
+
+func TestToFileErrors(t *testing.T) {
+	useMemoryStore(t)
+	saveMinutes(t, "BTC_USDT", 0, 3)
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"内存存储不支持聚合周期", Options{Symbol: "BTC_USDT", Interval: "5m"}, "导出 5m 周期失败"},
+		{"不支持的周期", Options{Symbol: "BTC_USDT", Interval: "3m"}, "不支持的周期"},
+		{"不支持的列", Options{Symbol: "BTC_USDT", Columns: []string{"vwap"}}, "不支持的列"},
+		{"重复的列", Options{Symbol: "BTC_USDT", Columns: []string{ColumnClose, ColumnClose}}, "重复的列"},
+		{"无效的时区", Options{Symbol: "BTC_USDT", Timezone: "Mars/Olympus"}, "无效的时区"},
+		{"开始时间晚于结束时间", Options{Symbol: "BTC_USDT", StartTime: testBase + 60000, EndTime: testBase}, "开始时间不能晚于结束时间"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.csv")
+			_, err := ToFile(path, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v，期望包含 %q", err, tt.want)
+			}
+			if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+				t.Errorf("导出失败后不应留下文件")
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package export
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// 精简的 Parquet 写入器：只支持 REQUIRED 的平铺列（INT64、DOUBLE、UTF8 字符串），
+// 每个行组的每一列写一个 PLAIN 编码、不压缩的数据页，元数据使用 Thrift Compact 协议编码。
+// 足以被 pyarrow、pandas、DuckDB、Spark 等读取，无需引入额外的依赖。
+
+// parquetMagic Parquet 文件头尾的魔数
+const parquetMagic = "PAR1"
+
+// parquetRowGroupRows 每个行组的行数
+const parquetRowGroupRows = 100000
+
+// Parquet 物理类型
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet 转换类型（ConvertedType）
+const (
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+)
+
+// parquetColumn 一列的定义和当前行组的数据
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	data      []byte // 当前行组 PLAIN 编码后的值
+}
+
+// parquetColumnChunk 已写入文件的列块位置
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+}
+
+// parquetRowGroup 已写入文件的行组
+type parquetRowGroup struct {
+	numRows int64
+	columns []parquetColumnChunk
+}
+
+// parquetWriter 按行写入 Parquet 文件
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64 // 当前行组的行数
+	totalRows int64
+	rowGroups []parquetRowGroup
+	metadata  map[string]string
+}
+
+// newParquetWriter 创建 Parquet 写入器并写入文件头
+func newParquetWriter(w io.Writer, columns []*parquetColumn, metadata map[string]string) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w, columns: columns, metadata: metadata}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// appendInt64 追加 INT64 值
+func (c *parquetColumn) appendInt64(v int64) {
+	c.data = binary.LittleEndian.AppendUint64(c.data, uint64(v))
+}
+
+// appendDouble 追加 DOUBLE 值
+func (c *parquetColumn) appendDouble(v float64) {
+	c.data = binary.LittleEndian.AppendUint64(c.data, math.Float64bits(v))
+}
+
+// appendString 追加 BYTE_ARRAY 值
+func (c *parquetColumn) appendString(v string) {
+	c.data = binary.LittleEndian.AppendUint32(c.data, uint32(len(v)))
+	c.data = append(c.data, v...)
+}
+
+// endRow 一行的所有列追加完成，达到行组大小时写入文件
+func (pw *parquetWriter) endRow() error {
+	pw.rows++
+	if pw.rows >= parquetRowGroupRows {
+		return pw.flushRowGroup()
+	}
+	return nil
+}
+
+// flushRowGroup 把当前行组写入文件
+func (pw *parquetWriter) flushRowGroup() error {
+	if pw.rows == 0 {
+		return nil
+	}
+
+	group := parquetRowGroup{numRows: pw.rows}
+	for _, c := range pw.columns {
+		header := encodeDataPageHeader(int32(pw.rows), int32(len(c.data)))
+		chunk := parquetColumnChunk{offset: pw.offset, size: int64(len(header) + len(c.data))}
+		if err := pw.write(header); err != nil {
+			return err
+		}
+		if err := pw.write(c.data); err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		c.data = c.data[:0]
+	}
+
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.totalRows += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// close 写入剩余的行组和文件元数据
+func (pw *parquetWriter) close() error {
+	if err := pw.flushRowGroup(); err != nil {
+		return err
+	}
+
+	footer := pw.encodeFileMetaData()
+	if err := pw.write(footer); err != nil {
+		return err
+	}
+	if err := pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return pw.write([]byte(parquetMagic))
+}
+
+// encodeDataPageHeader 编码 PageHeader（DATA_PAGE，PLAIN 编码，REQUIRED 列没有定义/重复级别）
+func encodeDataPageHeader(numValues, size int32) []byte {
+	var t thriftCompact
+	t.beginStruct()
+	t.i32(1, 0)    // type: DATA_PAGE
+	t.i32(2, size) // uncompressed_page_size
+	t.i32(3, size) // compressed_page_size
+	t.structField(5)
+	t.i32(1, numValues) // num_values
+	t.i32(2, 0)         // encoding: PLAIN
+	t.i32(3, 3)         // definition_level_encoding: RLE
+	t.i32(4, 3)         // repetition_level_encoding: RLE
+	t.endStruct()
+	t.endStruct()
+	return t.buf
+}
+
+// encodeFileMetaData 编码 FileMetaData
+func (pw *parquetWriter) encodeFileMetaData() []byte {
+	var t thriftCompact
+	t.beginStruct()
+	t.i32(1, 1) // version
+
+	// schema: 根节点 + 每列一个叶子节点
+	t.listHeader(2, thriftStruct, len(pw.columns)+1)
+	t.beginStruct()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.endStruct()
+	for _, c := range pw.columns {
+		t.beginStruct()
+		t.i32(1, c.physical)
+		t.i32(3, 0) // repetition_type: REQUIRED
+		t.binary(4, c.name)
+		if c.converted != convertedNone {
+			t.i32(6, c.converted)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, pw.totalRows)
+
+	t.listHeader(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		var totalSize int64
+		t.beginStruct()
+		t.listHeader(1, thriftStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			c := pw.columns[i]
+			totalSize += chunk.size
+			t.beginStruct()
+			t.i64(2, chunk.offset) // file_offset
+			t.structField(3)       // meta_data
+			t.i32(1, c.physical)
+			t.listHeader(2, thriftI32, 1)
+			t.listI32(0) // encodings: PLAIN
+			t.listHeader(3, thriftBinary, 1)
+			t.listBinary(c.name)
+			t.i32(4, 0) // codec: UNCOMPRESSED
+			t.i64(5, group.numRows)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset) // data_page_offset
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, totalSize)
+		t.i64(3, group.numRows)
+		t.endStruct()
+	}
+
+	if len(pw.metadata) > 0 {
+		keys := sortedKeys(pw.metadata)
+		t.listHeader(5, thriftStruct, len(keys))
+		for _, k := range keys {
+			t.beginStruct()
+			t.binary(1, k)
+			t.binary(2, pw.metadata[k])
+			t.endStruct()
+		}
+	}
+	t.binary(6, "wails-contract-warn")
+	t.endStruct()
+	return t.buf
+}
+
+// Thrift Compact 协议的类型编号
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftCompact Thrift Compact 协议编码器（只实现 Parquet 元数据用到的部分）
+type thriftCompact struct {
+	buf    []byte
+	lastID []int16 // 每层结构体中上一个字段的编号
+}
+
+func (t *thriftCompact) beginStruct() {
+	t.lastID = append(t.lastID, 0)
+}
+
+func (t *thriftCompact) endStruct() {
+	t.buf = append(t.buf, 0) // STOP
+	t.lastID = t.lastID[:len(t.lastID)-1]
+}
+
+// fieldHeader 写入字段头（与上一个字段编号的差值在 1~15 时使用短格式）
+func (t *thriftCompact) fieldHeader(id int16, typ byte) {
+	last := &t.lastID[len(t.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftCompact) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func (t *thriftCompact) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftCompact) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftCompact) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftCompact) binary(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.listBinary(v)
+}
+
+// structField 写入结构体类型的字段头并进入该结构体（以 endStruct 结束）
+func (t *thriftCompact) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginStruct()
+}
+
+// listHeader 写入列表类型的字段头，随后依次写入 n 个元素
+func (t *thriftCompact) listHeader(id int16, elemType byte, n int) {
+	t.fieldHeader(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elemType)
+	} else {
+		t.buf = append(t.buf, 0xF0|elemType)
+		t.varint(uint64(n))
+	}
+}
+
+func (t *thriftCompact) listI32(v int32) {
+	t.zigzag(int64(v))
+}
+
+func (t *thriftCompact) listBinary(v string) {
+	t.varint(uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
@@ -0,0 +1,279 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// thriftReader 测试用的 Thrift Compact 解码器（只支持 Parquet 元数据用到的类型），
+// 结构体解码为 字段编号 → 值，列表解码为 []interface{}
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("无效的 varint（位置 %d）", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		header := r.byte()
+		n, elemType := int(header>>4), header&0x0F
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = r.value(elemType)
+		}
+		return list
+	case thriftStruct:
+		return r.structValue()
+	}
+	panic(fmt.Sprintf("不支持的 Thrift 类型 %d（位置 %d）", typ, r.pos))
+}
+
+func (r *thriftReader) structValue() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		typ := header & 0x0F
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(typ)
+		last = id
+	}
+}
+
+// decodeStruct 从 offset 处解码一个结构体，返回结构体和结束位置
+func decodeStruct(t *testing.T, buf []byte, offset int) (fields map[int16]interface{}, end int) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("解码 Thrift 结构体失败: %v", r)
+		}
+	}()
+	r := &thriftReader{buf: buf, pos: offset}
+	fields = r.structValue()
+	return fields, r.pos
+}
+
+func structAt(v interface{}) map[int16]interface{} { return v.(map[int16]interface{}) }
+func listAt(v interface{}) []interface{}           { return v.([]interface{}) }
+
+func TestParquetFileLayout(t *testing.T) {
+	var buf bytes.Buffer
+	openTime := &parquetColumn{name: "open_time", physical: parquetInt64, converted: convertedTimestampMillis}
+	symbol := &parquetColumn{name: "symbol", physical: parquetByteArray, converted: convertedUTF8}
+	closePrice := &parquetColumn{name: "close", physical: parquetDouble, converted: convertedNone}
+	pw, err := newParquetWriter(&buf, []*parquetColumn{openTime, symbol, closePrice}, map[string]string{"symbol": "BTC_USDT", "interval": "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个行组：5 行和 3 行
+	const rows = 8
+	for i := 0; i < rows; i++ {
+		openTime.appendInt64(int64(1700000000000 + i*60000))
+		symbol.appendString("BTC_USDT")
+		closePrice.appendDouble(100.5 + float64(i))
+		if err := pw.endRow(); err != nil {
+			t.Fatal(err)
+		}
+		if i == 4 {
+			if err := pw.flushRowGroup(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := pw.close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// 文件结构: PAR1 | 列块... | FileMetaData | 4 字节长度 | PAR1
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("文件头尾的魔数不正确")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	meta, end := decodeStruct(t, data, footerStart)
+	if end != len(data)-8 {
+		t.Fatalf("FileMetaData 结束于 %d，期望 %d", end, len(data)-8)
+	}
+
+	if meta[1] != int64(1) || meta[3] != int64(rows) || meta[6] != "wails-contract-warn" {
+		t.Errorf("version/num_rows/created_by = %v/%v/%v", meta[1], meta[3], meta[6])
+	}
+
+	// schema: 根节点 + 3 个 REQUIRED 叶子节点
+	schema := listAt(meta[2])
+	if len(schema) != 4 {
+		t.Fatalf("schema 节点数 = %d，期望 4", len(schema))
+	}
+	if root := structAt(schema[0]); root[4] != "schema" || root[5] != int64(3) {
+		t.Errorf("根节点 = %v", root)
+	}
+	wantSchema := []struct {
+		name      string
+		physical  int64
+		converted interface{}
+	}{
+		{"open_time", parquetInt64, int64(convertedTimestampMillis)},
+		{"symbol", parquetByteArray, int64(convertedUTF8)},
+		{"close", parquetDouble, nil},
+	}
+	for i, want := range wantSchema {
+		leaf := structAt(schema[i+1])
+		if leaf[1] != want.physical || leaf[3] != int64(0) || leaf[4] != want.name || leaf[6] != want.converted {
+			t.Errorf("第 %d 列的 schema = %v，期望 %+v", i, leaf, want)
+		}
+	}
+
+	// key_value_metadata 按键排序
+	kv := listAt(meta[5])
+	if len(kv) != 2 || structAt(kv[0])[1] != "interval" || structAt(kv[0])[2] != "1m" ||
+		structAt(kv[1])[1] != "symbol" || structAt(kv[1])[2] != "BTC_USDT" {
+		t.Errorf("key_value_metadata = %v", kv)
+	}
+
+	// 行组: 每列的 file_offset 指向 PageHeader，数据页之后紧跟下一个列块
+	groups := listAt(meta[4])
+	if len(groups) != 2 {
+		t.Fatalf("行组数 = %d，期望 2", len(groups))
+	}
+	offset := int64(4)
+	row := 0
+	for g, groupRows := range []int64{5, 3} {
+		group := structAt(groups[g])
+		if group[3] != groupRows {
+			t.Errorf("第 %d 个行组的行数 = %v，期望 %d", g, group[3], groupRows)
+		}
+		chunks := listAt(group[1])
+		if len(chunks) != 3 {
+			t.Fatalf("第 %d 个行组的列块数 = %d，期望 3", g, len(chunks))
+		}
+		var totalSize int64
+		for c, chunk := range chunks {
+			chunkMeta := structAt(structAt(chunk)[3])
+			if structAt(chunk)[2] != offset || chunkMeta[9] != offset {
+				t.Fatalf("第 %d 个行组第 %d 列的偏移 = %v/%v，期望 %d", g, c, structAt(chunk)[2], chunkMeta[9], offset)
+			}
+			if chunkMeta[1] != wantSchema[c].physical || chunkMeta[4] != int64(0) || chunkMeta[5] != groupRows ||
+				listAt(chunkMeta[3])[0] != wantSchema[c].name || listAt(chunkMeta[2])[0] != int64(0) {
+				t.Errorf("第 %d 个行组第 %d 列的元数据 = %v", g, c, chunkMeta)
+			}
+
+			header, dataStart := decodeStruct(t, data, int(offset))
+			pageSize := header[3].(int64)
+			page := structAt(header[5])
+			if header[1] != int64(0) || header[2] != pageSize || page[1] != groupRows || page[2] != int64(0) {
+				t.Errorf("第 %d 个行组第 %d 列的 PageHeader = %v", g, c, header)
+			}
+			chunkSize := int64(dataStart) - offset + pageSize
+			if chunkMeta[6] != chunkSize || chunkMeta[7] != chunkSize {
+				t.Errorf("第 %d 个行组第 %d 列的大小 = %v，期望 %d", g, c, chunkMeta[6], chunkSize)
+			}
+			assertPlainValues(t, c, data[dataStart:dataStart+int(pageSize)], row, int(groupRows))
+
+			totalSize += chunkSize
+			offset += chunkSize
+		}
+		if group[2] != totalSize {
+			t.Errorf("第 %d 个行组的 total_byte_size = %v，期望 %d", g, group[2], totalSize)
+		}
+		row += int(groupRows)
+	}
+	if offset != int64(footerStart) {
+		t.Errorf("最后一个列块结束于 %d，FileMetaData 开始于 %d", offset, footerStart)
+	}
+}
+
+// assertPlainValues 检查 PLAIN 编码的数据页（列序号与 TestParquetFileLayout 中的列对应）
+func assertPlainValues(t *testing.T, column int, page []byte, firstRow, rows int) {
+	t.Helper()
+	pos := 0
+	for i := 0; i < rows; i++ {
+		row := firstRow + i
+		switch column {
+		case 0:
+			if v := int64(binary.LittleEndian.Uint64(page[pos:])); v != int64(1700000000000+row*60000) {
+				t.Errorf("第 %d 行 open_time = %d", row, v)
+			}
+			pos += 8
+		case 1:
+			n := int(binary.LittleEndian.Uint32(page[pos:]))
+			if v := string(page[pos+4 : pos+4+n]); v != "BTC_USDT" {
+				t.Errorf("第 %d 行 symbol = %q", row, v)
+			}
+			pos += 4 + n
+		case 2:
+			if v := math.Float64frombits(binary.LittleEndian.Uint64(page[pos:])); v != 100.5+float64(row) {
+				t.Errorf("第 %d 行 close = %v", row, v)
+			}
+			pos += 8
+		}
+	}
+	if pos != len(page) {
+		t.Errorf("第 %d 列的数据页长度 = %d，解码了 %d 字节", column, len(page), pos)
+	}
+}
+
+func TestThriftCompactFieldHeader(t *testing.T) {
+	// 字段编号差值超过 15 时使用长格式（类型字节 + zigzag 编号）
+	var c thriftCompact
+	c.beginStruct()
+	c.i32(1, -3)
+	c.i64(20, 300)
+	c.binary(21, "ab")
+	c.listHeader(22, thriftI32, 20)
+	for i := 0; i < 20; i++ {
+		c.listI32(int32(i))
+	}
+	c.endStruct()
+
+	fields, end := decodeStruct(t, c.buf, 0)
+	if end != len(c.buf) {
+		t.Fatalf("解码结束于 %d，期望 %d", end, len(c.buf))
+	}
+	if fields[1] != int64(-3) || fields[20] != int64(300) || fields[21] != "ab" {
+		t.Errorf("字段 = %v", fields)
+	}
+	if list := listAt(fields[22]); len(list) != 20 || list[19] != int64(19) {
+		t.Errorf("列表 = %v", fields[22])
+	}
+}
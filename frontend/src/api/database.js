@@ -74,3 +74,101 @@ export async function stopAutoSync(symbol) {
   }
 }
 
+/**
+ * 获取历史回填进度
+ * @param {string} symbol - 交易对（为空时返回所有币种）
+ * @returns {Promise<Array>} 每个币种的状态、下一个时间段、完成百分比（percent）和预计剩余秒数（etaSeconds，-1 表示无法估算）
+ */
+export async function getBackfillProgress(symbol = '') {
+  try {
+    return JSON.parse(await window.go.main.App.GetBackfillProgress(symbol))
+  } catch (error) {
+    console.error('获取回填进度失败:', error)
+    throw error
+  }
+}
+
+/**
+ * 暂停历史回填
+ * @param {string} symbol - 交易对
+ * @returns {Promise<void>}
+ */
+export async function pauseBackfill(symbol) {
+  try {
+    await window.go.main.App.PauseBackfill(symbol)
+  } catch (error) {
+    console.error('暂停回填失败:', error)
+    throw error
+  }
+}
+
+/**
+ * 恢复历史回填（从保存的进度继续）
+ * @param {string} symbol - 交易对
+ * @returns {Promise<void>}
+ */
+export async function resumeBackfill(symbol) {
+  try {
+    await window.go.main.App.ResumeBackfill(symbol)
+  } catch (error) {
+    console.error('恢复回填失败:', error)
+    throw error
+  }
+}
+
+/**
+ * 获取K线校验报告（与参考交易所偏差过大或价格尖刺、被隔离没有保存的K线）
+ * @param {string} symbol - 交易对（为空时汇总所有币种）
+ * @param {number} startTime - 开始时间（毫秒时间戳，0 表示不限制）
+ * @param {number} endTime - 结束时间（毫秒时间戳，0 表示不限制）
+ * @returns {Promise<Object>} { total, reasonCounts, maxDeviationBps, rows }
+ */
+export async function getValidationReport(symbol = '', startTime = 0, endTime = 0) {
+  try {
+    return JSON.parse(await window.go.main.App.GetValidationReport(symbol, startTime, endTime))
+  } catch (error) {
+    console.error('获取校验报告失败:', error)
+    throw error
+  }
+}
+
+/**
+ * 获取交易对元数据（来自交易所的交易对接口，启动时加载并每天更新）
+ * @param {string} symbol - 交易对
+ * @returns {Promise<Object|null>} { base, quote, pricePrecision, amountPrecision, minBaseAmount, minQuoteAmount, listedAt, tradeStatus }，没有元数据时为 null
+ */
+export async function getSymbolMetadata(symbol) {
+  try {
+    return JSON.parse(await window.go.main.App.GetSymbolMetadata(symbol))
+  } catch (error) {
+    console.error('获取交易对元数据失败:', error)
+    throw error
+  }
+}
+
+/**
+ * 获取停止同步的交易对（已下架、暂停交易或已更名）
+ * @returns {Promise<Array>} [{ symbol, status, reason, detectedAt }]，status 为 delisted、suspended 或 renamed
+ */
+export async function getInactiveSymbols() {
+  try {
+    return JSON.parse(await window.go.main.App.GetInactiveSymbols())
+  } catch (error) {
+    console.error('获取停止同步的交易对失败:', error)
+    throw error
+  }
+}
+
+/**
+ * 恢复已停止同步的交易对（如交易所恢复交易后）
+ * @param {string} symbol - 交易对
+ * @returns {Promise<void>}
+ */
+export async function reactivateSymbol(symbol) {
+  try {
+    await window.go.main.App.ReactivateSymbol(symbol)
+  } catch (error) {
+    console.error('恢复交易对同步失败:', error)
+    throw error
+  }
+}
@@ -14,6 +14,7 @@ export function useMarketData(initialSymbol = 'BTCUSDT', initialPeriod = '1m') {
   const alertSignals = ref([])
   const latestAlert = ref(null)
   const isStreaming = ref(false)
+  const symbolStatus = ref(null) // 最近一次停止同步的交易对（已下架、暂停交易或已更名）
   let updateTimer = null
   let currentStreamPeriod = null // 记录当前数据流的周期
   let isLoading = false // 防止并发加载
@@ -216,10 +217,22 @@ export function useMarketData(initialSymbol = 'BTCUSDT', initialPeriod = '1m') {
 
   // 监听实时价格更新
   let realtimePriceUnsubscribe = null
+  let symbolStatusUnsubscribe = null
 
   onMounted(() => {
     loadData()
 
+    // 监听交易对状态变化（后端停止同步已下架、暂停交易或已更名的交易对，恢复同步时 status 为 active）
+    symbolStatusUnsubscribe = EventsOn('symbol-status', (status) => {
+      if (!status || !status.symbol) return
+      console.warn('交易对状态变化:', status)
+      if (status.status !== 'active') {
+        symbolStatus.value = status
+      } else if (symbolStatus.value && symbolStatus.value.symbol === status.symbol) {
+        symbolStatus.value = null
+      }
+    })
+
     // 监听实时价格更新事件
     realtimePriceUnsubscribe = EventsOn('realtime-price', (priceData) => {
       if (!priceData || !priceData.symbol) return
@@ -254,6 +267,8 @@ export function useMarketData(initialSymbol = 'BTCUSDT', initialPeriod = '1m') {
             lastKline.low = Math.min(lastKline.low, priceData.low)
             lastKline.close = priceData.close
             lastKline.volume = priceData.volume
+            lastKline.quoteVolume = priceData.quoteVolume || 0
+            lastKline.tradeCount = priceData.tradeCount || 0
           } else {
             // 添加新的K线
             klineData.value.push({
@@ -263,6 +278,8 @@ export function useMarketData(initialSymbol = 'BTCUSDT', initialPeriod = '1m') {
               low: priceData.low,
               close: priceData.close,
               volume: priceData.volume,
+              quoteVolume: priceData.quoteVolume || 0,
+              tradeCount: priceData.tradeCount || 0,
             })
             // 保持最多1000条数据
             if (klineData.value.length > 1000) {
@@ -292,6 +309,10 @@ export function useMarketData(initialSymbol = 'BTCUSDT', initialPeriod = '1m') {
       realtimePriceUnsubscribe()
       realtimePriceUnsubscribe = null
     }
+    if (symbolStatusUnsubscribe) {
+      symbolStatusUnsubscribe()
+      symbolStatusUnsubscribe = null
+    }
   })
 
   // 加载测试数据到图表
@@ -329,6 +350,7 @@ export function useMarketData(initialSymbol = 'BTCUSDT', initialPeriod = '1m') {
     alertSignals,
     latestAlert,
     isStreaming,
+    symbolStatus,
     loadData,
     toggleStream,
     loadTestData,
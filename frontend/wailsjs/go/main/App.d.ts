@@ -3,26 +3,60 @@
 
 export function AnalyzeTestData(arg1:string):Promise<string>;
 
+export function ExportKLines(arg1:string):Promise<string>;
+
 export function GetAlertSignals(arg1:string,arg2:string):Promise<string>;
 
 export function GetAlerts(arg1:number):Promise<string>;
 
+export function GetBackfillProgress(arg1:string):Promise<string>;
+
+export function GetFailedSyncRanges(arg1:string):Promise<string>;
+
+export function GetInactiveSymbols():Promise<string>;
+
 export function GetIndicators(arg1:string,arg2:string):Promise<string>;
 
 export function GetMarketData(arg1:string,arg2:string):Promise<string>;
 
 export function GetMarketPrice(arg1:string,arg2:string):Promise<string>;
 
+export function GetMigrationStatus():Promise<string>;
+
 export function GetNetworkLogs(arg1:number):Promise<string>;
 
+export function GetSymbolMetadata(arg1:string):Promise<string>;
+
+export function GetSyncSchedulerStatus():Promise<string>;
+
+export function GetValidationReport(arg1:string,arg2:number,arg3:number):Promise<string>;
+
+export function ImportKLines(arg1:string,arg2:string):Promise<string>;
+
 export function InitDatabase(arg1:string):Promise<string>;
 
 export function IsRealtimeSyncRunning():Promise<boolean>;
 
 export function LoadTestData(arg1:string):Promise<string>;
 
+export function PauseBackfill(arg1:string):Promise<void>;
+
 export function ProxyAPI(arg1:string,arg2:string):Promise<string>;
 
+export function PurgeExpiredKLines():Promise<string>;
+
+export function ReactivateSymbol(arg1:string):Promise<void>;
+
+export function RebuildAggregates(arg1:string):Promise<string>;
+
+export function RebuildSyncTimeRanges(arg1:string):Promise<string>;
+
+export function ResumeBackfill(arg1:string):Promise<void>;
+
+export function ScanDataIntegrity(arg1:string,arg2:boolean):Promise<string>;
+
+export function SeedTestData(arg1:string,arg2:string):Promise<string>;
+
 export function StartAutoSync(arg1:string,arg2:number):Promise<string>;
 
 export function StartGapFillService():Promise<void>;
@@ -37,6 +71,10 @@ export function StartRealtimePriceService():Promise<void>;
 
 export function StartRealtimeSyncService():Promise<string>;
 
+export function StartRetentionService():Promise<void>;
+
+export function StartStreamService():Promise<void>;
+
 export function StopAutoSync(arg1:string):Promise<string>;
 
 export function StopMarketDataStream(arg1:string):Promise<void>;
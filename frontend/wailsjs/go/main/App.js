@@ -6,6 +6,10 @@ export function AnalyzeTestData(arg1) {
   return window['go']['main']['App']['AnalyzeTestData'](arg1);
 }
 
+export function ExportKLines(arg1) {
+  return window['go']['main']['App']['ExportKLines'](arg1);
+}
+
 export function GetAlertSignals(arg1, arg2) {
   return window['go']['main']['App']['GetAlertSignals'](arg1, arg2);
 }
@@ -14,6 +18,18 @@ export function GetAlerts(arg1) {
   return window['go']['main']['App']['GetAlerts'](arg1);
 }
 
+export function GetBackfillProgress(arg1) {
+  return window['go']['main']['App']['GetBackfillProgress'](arg1);
+}
+
+export function GetFailedSyncRanges(arg1) {
+  return window['go']['main']['App']['GetFailedSyncRanges'](arg1);
+}
+
+export function GetInactiveSymbols() {
+  return window['go']['main']['App']['GetInactiveSymbols']();
+}
+
 export function GetIndicators(arg1, arg2) {
   return window['go']['main']['App']['GetIndicators'](arg1, arg2);
 }
@@ -26,10 +42,30 @@ export function GetMarketPrice(arg1, arg2) {
   return window['go']['main']['App']['GetMarketPrice'](arg1, arg2);
 }
 
+export function GetMigrationStatus() {
+  return window['go']['main']['App']['GetMigrationStatus']();
+}
+
 export function GetNetworkLogs(arg1) {
   return window['go']['main']['App']['GetNetworkLogs'](arg1);
 }
 
+export function GetSymbolMetadata(arg1) {
+  return window['go']['main']['App']['GetSymbolMetadata'](arg1);
+}
+
+export function GetSyncSchedulerStatus() {
+  return window['go']['main']['App']['GetSyncSchedulerStatus']();
+}
+
+export function GetValidationReport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetValidationReport'](arg1, arg2, arg3);
+}
+
+export function ImportKLines(arg1, arg2) {
+  return window['go']['main']['App']['ImportKLines'](arg1, arg2);
+}
+
 export function InitDatabase(arg1) {
   return window['go']['main']['App']['InitDatabase'](arg1);
 }
@@ -42,10 +78,42 @@ export function LoadTestData(arg1) {
   return window['go']['main']['App']['LoadTestData'](arg1);
 }
 
+export function PauseBackfill(arg1) {
+  return window['go']['main']['App']['PauseBackfill'](arg1);
+}
+
 export function ProxyAPI(arg1, arg2) {
   return window['go']['main']['App']['ProxyAPI'](arg1, arg2);
 }
 
+export function PurgeExpiredKLines() {
+  return window['go']['main']['App']['PurgeExpiredKLines']();
+}
+
+export function ReactivateSymbol(arg1) {
+  return window['go']['main']['App']['ReactivateSymbol'](arg1);
+}
+
+export function RebuildAggregates(arg1) {
+  return window['go']['main']['App']['RebuildAggregates'](arg1);
+}
+
+export function RebuildSyncTimeRanges(arg1) {
+  return window['go']['main']['App']['RebuildSyncTimeRanges'](arg1);
+}
+
+export function ResumeBackfill(arg1) {
+  return window['go']['main']['App']['ResumeBackfill'](arg1);
+}
+
+export function ScanDataIntegrity(arg1, arg2) {
+  return window['go']['main']['App']['ScanDataIntegrity'](arg1, arg2);
+}
+
+export function SeedTestData(arg1, arg2) {
+  return window['go']['main']['App']['SeedTestData'](arg1, arg2);
+}
+
 export function StartAutoSync(arg1, arg2) {
   return window['go']['main']['App']['StartAutoSync'](arg1, arg2);
 }
@@ -74,6 +142,14 @@ export function StartRealtimeSyncService() {
   return window['go']['main']['App']['StartRealtimeSyncService']();
 }
 
+export function StartRetentionService() {
+  return window['go']['main']['App']['StartRetentionService']();
+}
+
+export function StartStreamService() {
+  return window['go']['main']['App']['StartStreamService']();
+}
+
 export function StopAutoSync(arg1) {
   return window['go']['main']['App']['StopAutoSync'](arg1);
 }
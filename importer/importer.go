@@ -0,0 +1,683 @@
+// Package importer 从 CSV / JSON 归档文件导入1分钟K线
+//
+// 支持的格式:
+//   - JSON 对象数组或 JSON Lines（models.KLineData 格式，如 data/*.json，以及 export 包导出的 .jsonl）
+//   - JSON 二维数组（Gate.io 接口格式或 Binance 接口格式）
+//   - 带表头的 CSV（按列名识别，兼容 export 包导出的 CSV）
+//   - 无表头的 CSV（Gate.io 或 Binance 历史数据下载格式）
+//
+// 数据通过 database.SaveKLine1m 写入（已存在的K线跳过），覆盖的时间段记录到 sync_time_ranges，同步服务不会重复拉取
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+	"wails-contract-warn/utils"
+)
+
+// 数组格式（JSON 二维数组、无表头 CSV）的列布局
+const (
+	LayoutAuto    = "auto"    // 根据数据自动识别
+	LayoutGate    = "gate"    // [时间戳(秒), 成交额, 收盘价, 最高价, 最低价, 开盘价, 成交量, ...]
+	LayoutBinance = "binance" // [开盘时间(毫秒), 开盘价, 最高价, 最低价, 收盘价, 成交量, 收盘时间, 成交额, 成交笔数, ...]
+)
+
+// importBatchSize 每次写入数据库的K线数量
+const importBatchSize = 10000
+
+// maxImportErrors 结果中保留的错误明细数量上限
+const maxImportErrors = 20
+
+// layoutDetectRows 自动识别列布局时检查的行数
+const layoutDetectRows = 100
+
+// Options 导入参数
+type Options struct {
+	Symbol string // 交易对，为空时从文件名推断（如 BTCUSDT-1m-2024-01.csv）
+	Layout string // 数组格式的列布局: auto、gate、binance，默认 auto
+	DryRun bool   // 只解析和校验，不写入数据库
+}
+
+// Result 单个文件的导入结果
+type Result struct {
+	File          string   `json:"file"`
+	Symbol        string   `json:"symbol"`
+	Format        string   `json:"format"` // json、jsonl、csv
+	Layout        string   `json:"layout,omitempty"`
+	TotalRows     int      `json:"totalRows"`
+	InvalidRows   int      `json:"invalidRows"`   // 解析或校验失败的行
+	DuplicateRows int      `json:"duplicateRows"` // 文件内重复的分钟
+	InsertedCount int      `json:"insertedCount"`
+	SkippedCount  int      `json:"skippedCount"` // 数据库中已存在
+	ErrorCount    int      `json:"errorCount"`   // 写入失败
+	Ranges        int      `json:"ranges"`       // 记录到 sync_time_ranges 的时间段数量
+	StartTime     int64    `json:"startTime"`
+	EndTime       int64    `json:"endTime"`
+	DryRun        bool     `json:"dryRun"`
+	Errors        []string `json:"errors,omitempty"` // 最多 maxImportErrors 条
+}
+
+func (r *Result) addError(row int, format string, args ...interface{}) {
+	r.InvalidRows++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("第 %d 行: ", row)+fmt.Sprintf(format, args...))
+	}
+}
+
+// candle 解析后的一根K线
+type candle struct {
+	openTime                       int64
+	open, high, low, close, volume float64
+	quoteVolume                    float64
+	tradeCount                     int64
+}
+
+// SymbolFromFileName 从文件名推断交易对，如 BTCUSDT-1m-2024-01.csv → BTC_USDT，BTC_USDT.json → BTC_USDT
+func SymbolFromFileName(path string) (string, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if idx := strings.IndexAny(name, "-."); idx > 0 {
+		name = name[:idx]
+	}
+	return utils.NormalizeSymbol(name)
+}
+
+// ImportFiles 依次导入多个文件，遇到错误时返回已完成的结果
+func ImportFiles(paths []string, opts Options) ([]*Result, error) {
+	results := make([]*Result, 0, len(paths))
+	for _, path := range paths {
+		result, err := ImportFile(path, opts)
+		if err != nil {
+			return results, fmt.Errorf("导入 %s 失败: %w", path, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ImportFile 导入单个文件（流式解析，内存占用与文件大小无关）
+func ImportFile(path string, opts Options) (*Result, error) {
+	symbol := opts.Symbol
+	var err error
+	if symbol == "" {
+		if symbol, err = SymbolFromFileName(path); err != nil {
+			return nil, fmt.Errorf("无法从文件名推断交易对，请指定 symbol: %w", err)
+		}
+	} else if symbol, err = utils.NormalizeSymbol(symbol); err != nil {
+		return nil, err
+	}
+
+	layout := opts.Layout
+	if layout == "" {
+		layout = LayoutAuto
+	}
+	if layout != LayoutAuto && layout != LayoutGate && layout != LayoutBinance {
+		return nil, fmt.Errorf("不支持的列布局: %s（可选: auto、gate、binance）", layout)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	started := time.Now()
+	result := &Result{File: path, Symbol: symbol, DryRun: opts.DryRun}
+	sink := &batchWriter{symbol: symbol, dryRun: opts.DryRun, result: result}
+
+	reader := bufio.NewReaderSize(file, 1<<20)
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+	switch first {
+	case '[':
+		result.Format = "json"
+		err = parseJSONArray(reader, layout, sink)
+	case '{':
+		result.Format = "jsonl"
+		err = parseJSONStream(reader, layout, sink)
+	default:
+		result.Format = "csv"
+		err = parseCSV(reader, layout, sink)
+	}
+	if err == nil {
+		err = sink.close()
+	}
+	if err != nil {
+		return result, err
+	}
+
+	logger.Infof("[%s] 导入完成: 文件=%s, 行数=%d, 插入=%d, 跳过=%d, 无效=%d, 重复=%d, 时间段=%d, 耗时=%s",
+		symbol, path, result.TotalRows, result.InsertedCount, result.SkippedCount, result.InvalidRows,
+		result.DuplicateRows, result.Ranges, time.Since(started).Round(time.Millisecond))
+	return result, nil
+}
+
+// peekNonSpace 跳过开头的空白和 UTF-8 BOM，返回第一个有效字符（不消耗）
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err == io.EOF {
+			return 0, fmt.Errorf("文件为空")
+		}
+		if err != nil {
+			return 0, err
+		}
+		if bom, _ := r.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+			r.Discard(3)
+			continue
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// parseJSONArray 解析 JSON 数组（元素为对象或数组）
+func parseJSONArray(r io.Reader, layout string, sink *batchWriter) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+	detector := &layoutDetector{layout: layout, sink: sink}
+	row := 0
+	for dec.More() {
+		row++
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("解析 JSON 失败（第 %d 个元素）: %w", row, err)
+		}
+		if err := handleJSONValue(raw, row, detector, sink); err != nil {
+			return err
+		}
+	}
+	return detector.flush()
+}
+
+// parseJSONStream 解析连续的 JSON 值（JSON Lines）
+func parseJSONStream(r io.Reader, layout string, sink *batchWriter) error {
+	dec := json.NewDecoder(r)
+	detector := &layoutDetector{layout: layout, sink: sink}
+	row := 0
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			return fmt.Errorf("解析 JSON 失败（第 %d 行）: %w", row, err)
+		}
+		if err := handleJSONValue(raw, row, detector, sink); err != nil {
+			return err
+		}
+	}
+	return detector.flush()
+}
+
+// handleJSONValue 处理一个 JSON 元素：对象按字段名解析，数组按列布局解析
+func handleJSONValue(raw json.RawMessage, row int, detector *layoutDetector, sink *batchWriter) error {
+	sink.result.TotalRows++
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var values []interface{}
+		if err := dec.Decode(&values); err != nil {
+			sink.result.addError(row, "%v", err)
+			return nil
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			fields[i] = fmt.Sprint(v)
+		}
+		return detector.add(row, fields)
+	}
+
+	var object map[string]interface{}
+	if err := dec.Decode(&object); err != nil {
+		sink.result.addError(row, "%v", err)
+		return nil
+	}
+	fields := make(map[string]string, len(object))
+	for k, v := range object {
+		fields[strings.ToLower(k)] = fmt.Sprint(v)
+	}
+	c, err := parseNamedFields(fields)
+	if err != nil {
+		sink.result.addError(row, "%v", err)
+		return nil
+	}
+	return sink.add(row, c)
+}
+
+// parseCSV 解析 CSV（第一行不是数字时作为表头）
+func parseCSV(r io.Reader, layout string, sink *batchWriter) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+
+	var header []string
+	detector := &layoutDetector{layout: layout, sink: sink}
+	row := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				sink.result.TotalRows++
+				sink.result.addError(row, "%v", err)
+				continue
+			}
+			return fmt.Errorf("读取 CSV 失败: %w", err)
+		}
+
+		if row == 1 {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(record[0]), 64); err != nil {
+				for _, name := range record {
+					header = append(header, strings.ToLower(strings.TrimSpace(name)))
+				}
+				continue
+			}
+		}
+
+		sink.result.TotalRows++
+		if header == nil {
+			if err := detector.add(row, append([]string(nil), record...)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fields := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				fields[name] = record[i]
+			}
+		}
+		c, err := parseNamedFields(fields)
+		if err != nil {
+			sink.result.addError(row, "%v", err)
+			continue
+		}
+		if err := sink.add(row, c); err != nil {
+			return err
+		}
+	}
+	return detector.flush()
+}
+
+// 按字段名解析时识别的列名（不区分大小写）
+var (
+	timeFieldNames   = []string{"open_time", "time", "timestamp", "t", "date"}
+	openFieldNames   = []string{"open", "o"}
+	highFieldNames   = []string{"high", "h"}
+	lowFieldNames    = []string{"low", "l"}
+	closeFieldNames  = []string{"close", "c"}
+	volumeFieldNames = []string{"volume", "vol", "v"}
+	// 可选的字段（缺失时为 0）
+	quoteVolumeFieldNames = []string{"quote_volume", "quotevolume", "quote_asset_volume", "turnover"}
+	tradeCountFieldNames  = []string{"trade_count", "tradecount", "trades", "number_of_trades"}
+)
+
+func lookupField(fields map[string]string, names []string) (string, bool) {
+	for _, name := range names {
+		if v, ok := fields[name]; ok && v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// parseNamedFields 按字段名解析一根K线（JSON 对象或带表头的 CSV）
+func parseNamedFields(fields map[string]string) (candle, error) {
+	var c candle
+	value, ok := lookupField(fields, timeFieldNames)
+	if !ok {
+		return c, fmt.Errorf("缺少时间字段（%s）", strings.Join(timeFieldNames, "/"))
+	}
+	openTime, err := parseTime(value)
+	if err != nil {
+		return c, err
+	}
+	c.openTime = openTime
+
+	targets := []struct {
+		names []string
+		dst   *float64
+	}{
+		{openFieldNames, &c.open},
+		{highFieldNames, &c.high},
+		{lowFieldNames, &c.low},
+		{closeFieldNames, &c.close},
+		{volumeFieldNames, &c.volume},
+	}
+	for _, target := range targets {
+		value, ok := lookupField(fields, target.names)
+		if !ok {
+			return c, fmt.Errorf("缺少字段 %s", target.names[0])
+		}
+		if *target.dst, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return c, fmt.Errorf("字段 %s 不是数字: %s", target.names[0], value)
+		}
+	}
+
+	if value, ok := lookupField(fields, quoteVolumeFieldNames); ok {
+		if c.quoteVolume, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return c, fmt.Errorf("字段 %s 不是数字: %s", quoteVolumeFieldNames[0], value)
+		}
+	}
+	if value, ok := lookupField(fields, tradeCountFieldNames); ok {
+		trades, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return c, fmt.Errorf("字段 %s 不是数字: %s", tradeCountFieldNames[0], value)
+		}
+		c.tradeCount = int64(trades)
+	}
+	return c, nil
+}
+
+// parseArrayFields 按列布局解析一根K线
+func parseArrayFields(fields []string, layout string) (candle, error) {
+	var c candle
+	if len(fields) < 6 {
+		return c, fmt.Errorf("列数不足: %d", len(fields))
+	}
+
+	values := make([]float64, 6)
+	for i := 1; i < 6; i++ {
+		v, err := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64)
+		if err != nil {
+			return c, fmt.Errorf("第 %d 列不是数字: %s", i+1, fields[i])
+		}
+		values[i] = v
+	}
+	openTime, err := parseTime(fields[0])
+	if err != nil {
+		return c, err
+	}
+	c.openTime = openTime
+
+	// 成交量、成交额和成交笔数为可选列（缺失或无法解析时为 0）
+	optional := func(i int) float64 {
+		if i >= len(fields) {
+			return 0
+		}
+		v, _ := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64)
+		return v
+	}
+	if layout == LayoutGate {
+		c.quoteVolume, c.close, c.high, c.low, c.open = values[1], values[2], values[3], values[4], values[5]
+		c.volume = optional(6)
+	} else {
+		c.open, c.high, c.low, c.close, c.volume = values[1], values[2], values[3], values[4], values[5]
+		c.quoteVolume = optional(7)
+		c.tradeCount = int64(optional(8))
+	}
+	return c, nil
+}
+
+// parseTime 解析时间：秒、毫秒、微秒时间戳（按数量级识别），或 RFC3339 / 2006-01-02 15:04:05（UTC）格式的字符串
+func parseTime(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if ts, err := strconv.ParseFloat(value, 64); err == nil {
+		switch {
+		case ts >= 1e15: // 微秒（Binance 2025 年以后的现货数据）
+			return int64(ts / 1000), nil
+		case ts >= 1e11: // 毫秒
+			return int64(ts), nil
+		default: // 秒（Gate.io）
+			return int64(ts * 1000), nil
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.UnixMilli(), nil
+		}
+	}
+	return 0, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// layoutDetector 数组格式的列布局识别：布局为 auto 时先缓存前 layoutDetectRows 行，
+// 根据 OHLC 关系（最高价不低于开盘/收盘价，最低价不高于开盘/收盘价）判断是 Gate.io 还是 Binance 格式
+type layoutDetector struct {
+	layout  string
+	sink    *batchWriter
+	pending []pendingRow
+}
+
+type pendingRow struct {
+	row    int
+	fields []string
+}
+
+func (d *layoutDetector) add(row int, fields []string) error {
+	if d.layout != LayoutAuto {
+		return d.emit(row, fields)
+	}
+	d.pending = append(d.pending, pendingRow{row: row, fields: fields})
+	if len(d.pending) >= layoutDetectRows {
+		return d.flush()
+	}
+	return nil
+}
+
+// flush 识别列布局并处理缓存的行
+func (d *layoutDetector) flush() error {
+	if len(d.pending) == 0 {
+		return nil
+	}
+	if d.layout == LayoutAuto {
+		layout, err := detectLayout(d.pending)
+		if err != nil {
+			return err
+		}
+		d.layout = layout
+	}
+	for _, p := range d.pending {
+		if err := d.emit(p.row, p.fields); err != nil {
+			return err
+		}
+	}
+	d.pending = nil
+	return nil
+}
+
+func (d *layoutDetector) emit(row int, fields []string) error {
+	d.sink.result.Layout = d.layout
+	c, err := parseArrayFields(fields, d.layout)
+	if err != nil {
+		d.sink.result.addError(row, "%v", err)
+		return nil
+	}
+	return d.sink.add(row, c)
+}
+
+// detectLayout 统计两种布局下 OHLC 关系成立的行数，成立行数更多的布局胜出（相同时无法确定）
+func detectLayout(rows []pendingRow) (string, error) {
+	gateOK, binanceOK := 0, 0
+	for _, p := range rows {
+		gate, err := parseArrayFields(p.fields, LayoutGate)
+		if err != nil {
+			continue
+		}
+		binance, _ := parseArrayFields(p.fields, LayoutBinance)
+		if ohlcConsistent(gate) {
+			gateOK++
+		}
+		if ohlcConsistent(binance) {
+			binanceOK++
+		}
+	}
+	switch {
+	case gateOK > binanceOK:
+		return LayoutGate, nil
+	case binanceOK > gateOK:
+		return LayoutBinance, nil
+	case gateOK == 0:
+		return LayoutBinance, nil // 全部无法解析，由逐行校验报告错误
+	default:
+		return "", fmt.Errorf("无法自动识别列布局，请指定 layout（gate 或 binance）")
+	}
+}
+
+func ohlcConsistent(c candle) bool {
+	return c.high >= max(c.open, c.close) && c.low <= min(c.open, c.close) && c.low > 0
+}
+
+// validate 校验一根K线
+func validate(c candle) error {
+	if c.openTime <= 0 {
+		return fmt.Errorf("时间无效: %d", c.openTime)
+	}
+	if c.open <= 0 || c.high <= 0 || c.low <= 0 || c.close <= 0 || c.volume < 0 {
+		return fmt.Errorf("价格或成交量无效: open=%v, high=%v, low=%v, close=%v, volume=%v",
+			c.open, c.high, c.low, c.close, c.volume)
+	}
+	if !ohlcConsistent(c) {
+		return fmt.Errorf("OHLC 不一致: open=%v, high=%v, low=%v, close=%v", c.open, c.high, c.low, c.close)
+	}
+	return nil
+}
+
+// batchWriter 校验K线、按批次写入数据库，并记录覆盖的连续时间段
+type batchWriter struct {
+	symbol     string
+	dryRun     bool
+	result     *Result
+	batch      []database.KLine1m
+	run        *database.SyncTimeRange // 尚未记录的连续时间段
+	checkedGap bool
+}
+
+func (w *batchWriter) add(row int, c candle) error {
+	if err := validate(c); err != nil {
+		w.result.addError(row, "%v", err)
+		return nil
+	}
+	// 未对齐的时间向下取整到分钟（data/*.json 的测试数据使用生成时的毫秒时间戳）
+	c.openTime -= c.openTime % 60000
+	w.batch = append(w.batch, database.KLine1m{
+		Symbol:      w.symbol,
+		OpenTime:    c.openTime,
+		Open:        c.open,
+		High:        c.high,
+		Low:         c.low,
+		Close:       c.close,
+		Volume:      c.volume,
+		QuoteVolume: c.quoteVolume,
+		TradeCount:  c.tradeCount,
+		CloseTime:   c.openTime + 60000 - 1,
+	})
+	if len(w.batch) >= importBatchSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush 排序、去重并写入当前批次
+func (w *batchWriter) flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	sort.SliceStable(w.batch, func(i, j int) bool {
+		return w.batch[i].OpenTime < w.batch[j].OpenTime
+	})
+	unique := w.batch[:1]
+	for _, k := range w.batch[1:] {
+		if k.OpenTime == unique[len(unique)-1].OpenTime {
+			w.result.DuplicateRows++
+			unique[len(unique)-1] = k
+			continue
+		}
+		unique = append(unique, k)
+	}
+	w.batch = nil
+
+	// 第一批数据检查K线间隔，避免把5分钟、1小时等周期的数据写入1分钟表
+	if !w.checkedGap && len(unique) > 1 {
+		w.checkedGap = true
+		minGap := unique[1].OpenTime - unique[0].OpenTime
+		for i := 2; i < len(unique); i++ {
+			minGap = min(minGap, unique[i].OpenTime-unique[i-1].OpenTime)
+		}
+		if minGap > 60000 {
+			return fmt.Errorf("数据的最小间隔为 %d 分钟，不是1分钟K线", minGap/60000)
+		}
+	}
+
+	if w.result.StartTime == 0 || unique[0].OpenTime < w.result.StartTime {
+		w.result.StartTime = unique[0].OpenTime
+	}
+	w.result.EndTime = max(w.result.EndTime, unique[len(unique)-1].OpenTime)
+
+	if !w.dryRun {
+		saved, err := database.SaveKLine1m(unique)
+		if err != nil {
+			return fmt.Errorf("写入K线失败: %w", err)
+		}
+		w.result.InsertedCount += saved.InsertedCount
+		w.result.SkippedCount += saved.SkippedCount
+		w.result.ErrorCount += saved.ErrorCount
+	}
+
+	for _, k := range unique {
+		if w.run != nil && k.OpenTime >= w.run.StartTime && k.OpenTime <= w.run.EndTime+1 {
+			w.run.EndTime = max(w.run.EndTime, k.OpenTime+60000-1)
+			continue
+		}
+		if err := w.recordRun(); err != nil {
+			return err
+		}
+		w.run = &database.SyncTimeRange{StartTime: k.OpenTime, EndTime: k.OpenTime + 60000 - 1}
+	}
+	return nil
+}
+
+// recordRun 把当前连续时间段记录为已同步
+func (w *batchWriter) recordRun() error {
+	if w.run == nil {
+		return nil
+	}
+	w.result.Ranges++
+	if !w.dryRun {
+		if err := database.AddSyncTimeRange(w.symbol, w.run.StartTime, w.run.EndTime); err != nil {
+			return fmt.Errorf("记录同步时间段失败: %w", err)
+		}
+	}
+	w.run = nil
+	return nil
+}
+
+// close 写入剩余数据
+func (w *batchWriter) close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.recordRun()
+}
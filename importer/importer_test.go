@@ -0,0 +1,339 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"wails-contract-warn/database"
+)
+
+const testBase = int64(1700006400000) // 2023-11-15 00:00:00 UTC
+
+func minuteAt(n int) int64 { return testBase + int64(n)*60000 }
+
+// minuteRange 第 from 到 to 分钟（不含）的同步时间段
+func minuteRange(from, to int) database.SyncTimeRange {
+	return database.SyncTimeRange{StartTime: minuteAt(from), EndTime: minuteAt(to) - 1}
+}
+
+// useMemoryStore 使用内存存储，测试结束后恢复原来的存储
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	previous := database.GetStore()
+	database.SetStore(database.NewMemoryStore())
+	t.Cleanup(func() { database.SetStore(previous) })
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1700006400", testBase},             // 秒
+		{"1700006400.5", testBase + 500},     // 带小数的秒
+		{"1700006400000", testBase},          // 毫秒
+		{"1700006400000000", testBase},       // 微秒
+		{"1700006400123456", testBase + 123}, // 微秒向下取整到毫秒
+		{" 1700006400000 ", testBase},
+		{"2023-11-15T00:00:00Z", testBase},
+		{"2023-11-15T08:00:00+08:00", testBase},
+		{"2023-11-15 00:00:00", testBase},
+		{"2023-11-15T00:00:00", testBase},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTime(%q) = %d, %v，期望 %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "2023/11/15"} {
+		if _, err := parseTime(value); err == nil {
+			t.Errorf("parseTime(%q) 应返回错误", value)
+		}
+	}
+}
+
+func TestDetectLayout(t *testing.T) {
+	rows := func(records ...string) []pendingRow {
+		var pending []pendingRow
+		for i, record := range records {
+			pending = append(pending, pendingRow{row: i + 1, fields: strings.Split(record, ",")})
+		}
+		return pending
+	}
+	tests := []struct {
+		name    string
+		rows    []pendingRow
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Gate.io",
+			rows: rows("1700006400,54000.5,36001,36002,35999,36000,1.5", "1700006460,54001.5,36002,36003,36000,36001,2.5"),
+			want: LayoutGate,
+		},
+		{
+			name: "Binance",
+			rows: rows("1700006400000,36000,36002,35999,36001,1.5,1700006459999,54000.5,120"),
+			want: LayoutBinance,
+		},
+		{
+			name: "多数行决定布局",
+			rows: rows(
+				"1700006400000,36000,36002,35999,36001,1.5",
+				"1700006460000,36001,36003,36000,36002,2.5",
+				"1700006520,54002.5,36003,36004,36001,36002,3.5",
+			),
+			want: LayoutBinance,
+		},
+		{
+			name: "全部无法解析时按 Binance 逐行报告错误",
+			rows: rows("1700006400,x,y,z", "a,b,c,d,e,f"),
+			want: LayoutBinance,
+		},
+		{
+			name:    "两种布局都成立时无法确定",
+			rows:    rows("1700006400,100,100,100,100,100"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectLayout(tt.rows)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("detectLayout = %s，期望返回错误", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("detectLayout = %s, %v，期望 %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		opts       Options
+		want       Result // 只比较计数、格式、布局和时间范围
+		wantRanges []database.SyncTimeRange
+		wantFirst  database.KLine1m // 第一根K线（ID 不比较）
+	}{
+		{
+			name: "无表头 Gate.io CSV（秒级时间戳、重复和无效行、中间有空缺）",
+			file: "gate.csv",
+			opts: Options{Symbol: "btc_usdt"},
+			want: Result{
+				Format: "csv", Layout: LayoutGate, TotalRows: 7, InvalidRows: 1, DuplicateRows: 1,
+				InsertedCount: 5, Ranges: 2, StartTime: minuteAt(0), EndTime: minuteAt(6),
+			},
+			wantRanges: []database.SyncTimeRange{minuteRange(0, 3), minuteRange(5, 7)},
+			wantFirst: database.KLine1m{
+				Symbol: "BTC_USDT", OpenTime: minuteAt(0), Open: 36000, High: 36002, Low: 35999, Close: 36001,
+				Volume: 1.5, QuoteVolume: 54000.5, CloseTime: minuteAt(1) - 1,
+			},
+		},
+		{
+			name: "无表头 Binance CSV（微秒时间戳，交易对从文件名推断）",
+			file: "BTCUSDT-1m-2023-11.csv",
+			want: Result{
+				Format: "csv", Layout: LayoutBinance, TotalRows: 3, InsertedCount: 3, Ranges: 1,
+				StartTime: minuteAt(0), EndTime: minuteAt(2),
+			},
+			wantRanges: []database.SyncTimeRange{minuteRange(0, 3)},
+			wantFirst: database.KLine1m{
+				Symbol: "BTC_USDT", OpenTime: minuteAt(0), Open: 36000, High: 36002, Low: 35999, Close: 36001,
+				Volume: 1.5, QuoteVolume: 54000.5, TradeCount: 120, CloseTime: minuteAt(1) - 1,
+			},
+		},
+		{
+			name: "带 BOM 和表头的 CSV（CRLF 换行，带时区的时间）",
+			file: "header_bom.csv",
+			opts: Options{Symbol: "BTC_USDT"},
+			want: Result{
+				Format: "csv", TotalRows: 3, InsertedCount: 3, Ranges: 1,
+				StartTime: minuteAt(0), EndTime: minuteAt(2),
+			},
+			wantRanges: []database.SyncTimeRange{minuteRange(0, 3)},
+			wantFirst: database.KLine1m{
+				Symbol: "BTC_USDT", OpenTime: minuteAt(0), Open: 36000, High: 36002, Low: 35999, Close: 36001,
+				Volume: 1.5, QuoteVolume: 54000.5, TradeCount: 120, CloseTime: minuteAt(1) - 1,
+			},
+		},
+		{
+			name: "Gate.io 接口格式的 JSON 二维数组",
+			file: "gate.json",
+			opts: Options{Symbol: "BTC_USDT"},
+			want: Result{
+				Format: "json", Layout: LayoutGate, TotalRows: 3, InsertedCount: 3, Ranges: 1,
+				StartTime: minuteAt(0), EndTime: minuteAt(2),
+			},
+			wantRanges: []database.SyncTimeRange{minuteRange(0, 3)},
+			wantFirst: database.KLine1m{
+				Symbol: "BTC_USDT", OpenTime: minuteAt(0), Open: 36000, High: 36002, Low: 35999, Close: 36001,
+				Volume: 1.5, QuoteVolume: 54000.5, CloseTime: minuteAt(1) - 1,
+			},
+		},
+		{
+			name: "JSON Lines（export 包导出的格式）",
+			file: "klines.jsonl",
+			opts: Options{Symbol: "BTC_USDT"},
+			want: Result{
+				Format: "jsonl", TotalRows: 2, InsertedCount: 2, Ranges: 1,
+				StartTime: minuteAt(0), EndTime: minuteAt(1),
+			},
+			wantRanges: []database.SyncTimeRange{minuteRange(0, 2)},
+			wantFirst: database.KLine1m{
+				Symbol: "BTC_USDT", OpenTime: minuteAt(0), Open: 36000, High: 36002, Low: 35999, Close: 36001,
+				Volume: 1.5, QuoteVolume: 54000.5, TradeCount: 120, CloseTime: minuteAt(1) - 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryStore(t)
+			path := filepath.Join("testdata", tt.file)
+			result, err := ImportFile(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Result{
+				Format: result.Format, Layout: result.Layout, TotalRows: result.TotalRows,
+				InvalidRows: result.InvalidRows, DuplicateRows: result.DuplicateRows,
+				InsertedCount: result.InsertedCount, SkippedCount: result.SkippedCount, ErrorCount: result.ErrorCount,
+				Ranges: result.Ranges, StartTime: result.StartTime, EndTime: result.EndTime,
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Result = %+v\n期望 %+v\n错误明细: %v", got, tt.want, result.Errors)
+			}
+
+			ranges, err := database.GetSyncTimeRanges("BTC_USDT")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ranges, tt.wantRanges) {
+				t.Errorf("同步时间段 = %v，期望 %v", ranges, tt.wantRanges)
+			}
+
+			klines, err := database.GetKLines1m("BTC_USDT", 0, 0, 1)
+			if err != nil || len(klines) != 1 {
+				t.Fatalf("读取K线: %v, %v", klines, err)
+			}
+			first := klines[0]
+			first.ID = 0
+			if first != tt.wantFirst {
+				t.Errorf("第一根K线 = %+v\n期望 %+v", first, tt.wantFirst)
+			}
+
+			// 再次导入时全部跳过
+			again, err := ImportFile(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.InsertedCount != 0 || again.SkippedCount != tt.want.InsertedCount {
+				t.Errorf("再次导入: 插入=%d, 跳过=%d，期望 0/%d", again.InsertedCount, again.SkippedCount, tt.want.InsertedCount)
+			}
+		})
+	}
+}
+
+func TestImportFileDuplicateKeepsLastRow(t *testing.T) {
+	useMemoryStore(t)
+	if _, err := ImportFile(filepath.Join("testdata", "gate.csv"), Options{Symbol: "BTC_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+	klines, err := database.GetKLines1m("BTC_USDT", minuteAt(1), minuteAt(1), 0)
+	if err != nil || len(klines) != 1 {
+		t.Fatalf("读取K线: %v, %v", klines, err)
+	}
+	if klines[0].Close != 36009 || klines[0].Volume != 9.5 {
+		t.Errorf("重复的分钟应保留文件中最后一行: %+v", klines[0])
+	}
+}
+
+func TestImportFileRejectsNon1mInterval(t *testing.T) {
+	useMemoryStore(t)
+	result, err := ImportFile(filepath.Join("testdata", "five_minute.csv"), Options{Symbol: "BTC_USDT"})
+	if err == nil || !strings.Contains(err.Error(), "最小间隔为 5 分钟") {
+		t.Fatalf("err = %v，期望拒绝5分钟K线", err)
+	}
+	if result.InsertedCount != 0 || result.Ranges != 0 {
+		t.Errorf("插入=%d, 时间段=%d，期望都为 0", result.InsertedCount, result.Ranges)
+	}
+	if klines, _ := database.GetKLines1m("BTC_USDT", 0, 0, 0); len(klines) != 0 {
+		t.Errorf("不应写入任何K线，实际 %d 根", len(klines))
+	}
+	if ranges, _ := database.GetSyncTimeRanges("BTC_USDT"); len(ranges) != 0 {
+		t.Errorf("不应记录同步时间段: %v", ranges)
+	}
+}
+
+func TestImportFileDryRun(t *testing.T) {
+	useMemoryStore(t)
+	result, err := ImportFile(filepath.Join("testdata", "gate.csv"), Options{Symbol: "BTC_USDT", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InsertedCount != 0 || result.Ranges != 2 || result.TotalRows != 7 {
+		t.Errorf("插入=%d, 时间段=%d, 行数=%d，期望 0/2/7", result.InsertedCount, result.Ranges, result.TotalRows)
+	}
+	if klines, _ := database.GetKLines1m("BTC_USDT", 0, 0, 0); len(klines) != 0 {
+		t.Errorf("DryRun 不应写入K线，实际 %d 根", len(klines))
+	}
+	if ranges, _ := database.GetSyncTimeRanges("BTC_USDT"); len(ranges) != 0 {
+		t.Errorf("DryRun 不应记录同步时间段: %v", ranges)
+	}
+}
+
+func TestBatchWriterRecordRun(t *testing.T) {
+	useMemoryStore(t)
+	w := &batchWriter{symbol: "BTC_USDT", result: &Result{}}
+	add := func(minutes ...int) {
+		t.Helper()
+		for _, n := range minutes {
+			price := 36000 + float64(n)
+			c := candle{openTime: minuteAt(n) + 1234, open: price, high: price + 1, low: price - 1, close: price, volume: 1}
+			if err := w.add(n+1, c); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 第一批: 0-2 分钟（未对齐的时间向下取整）
+	add(2, 0, 1)
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if ranges, _ := database.GetSyncTimeRanges("BTC_USDT"); len(ranges) != 0 {
+		t.Fatalf("连续时间段在下一次间断前不应记录: %v", ranges)
+	}
+
+	// 第二批接续上一批的时间段（含已覆盖的分钟），之后出现空缺
+	add(3, 1, 4, 8)
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	ranges, _ := database.GetSyncTimeRanges("BTC_USDT")
+	if want := []database.SyncTimeRange{minuteRange(0, 5)}; !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("跨批次的连续时间段 = %v，期望 %v", ranges, want)
+	}
+
+	// 关闭时记录最后一个时间段
+	add(9)
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	ranges, _ = database.GetSyncTimeRanges("BTC_USDT")
+	if want := []database.SyncTimeRange{minuteRange(0, 5), minuteRange(8, 10)}; !reflect.DeepEqual(ranges, want) {
+		t.Errorf("同步时间段 = %v，期望 %v", ranges, want)
+	}
+	if w.result.Ranges != 2 || w.result.InsertedCount != 7 || w.result.SkippedCount != 1 {
+		t.Errorf("时间段=%d, 插入=%d, 跳过=%d，期望 2/7/1", w.result.Ranges, w.result.InsertedCount, w.result.SkippedCount)
+	}
+}
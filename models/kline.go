@@ -2,12 +2,14 @@ package models
 
 // KLineData K线数据
 type KLineData struct {
-	Time   int64   `json:"time"`
-	Open   float64 `json:"open"`
-	High   float64 `json:"high"`
-	Low    float64 `json:"low"`
-	Close  float64 `json:"close"`
-	Volume float64 `json:"volume"`
+	Time        int64   `json:"time"`
+	Open        float64 `json:"open"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Close       float64 `json:"close"`
+	Volume      float64 `json:"volume"`      // 成交量（基础币种）
+	QuoteVolume float64 `json:"quoteVolume"` // 成交额（计价币种），交易所不提供时为 0
+	TradeCount  int64   `json:"tradeCount"`  // 成交笔数，交易所不提供时为 0
 }
 
 // Indicators 技术指标
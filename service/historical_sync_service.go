@@ -10,12 +10,14 @@ import (
 )
 
 // HistoricalSyncService 历史数据同步服务
-// 从2020年开始倒推获取历史数据，批量获取，每次300条
+// 从近到远倒推获取历史数据，批量获取，每次300条
+// 每一轮把所有币种的回填任务以最低优先级交给调度器，全部完成后开始下一轮；
+// 回填进度按币种持久化（sync_backfill_cursors），应用重启后从中断处继续，可以按币种暂停和恢复
 type HistoricalSyncService struct {
 	mu           sync.RWMutex
 	running      bool
 	stopChan     chan struct{}
-	syncInterval time.Duration // 同步间隔（每个币种之间的间隔）
+	syncInterval time.Duration // 同步间隔（每一轮之间的间隔）
 	batchSize    int           // 每批获取的数据量
 	startYear    int           // 起始年份
 }
@@ -82,38 +84,34 @@ func (s *HistoricalSyncService) syncLoop() {
 
 	logger.Infof("历史数据同步服务: 开始同步 %d 个币种的历史数据", len(allSymbols))
 
-	// 轮询同步：每次同步一个币种的历史数据
-	currentIdx := 0
-
 	for {
 		select {
 		case <-s.stopChan:
 			return
 		default:
-			// 选择当前币种
-			symbolConfig := allSymbols[currentIdx]
-			currentIdx = (currentIdx + 1) % len(allSymbols)
-
-			logger.Infof("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-			logger.Infof("历史数据同步: 开始同步币种 %s (从近到远按天倒推，直到 %d 年)", symbolConfig.Symbol, s.startYear)
-
-			// 同步历史数据（按天倒推，使用时间段状态表，智能跳过已同步的数据）
-			// 限制只拉取最近7天的数据
-			if err := datasync.SyncSymbolHistoricalBackward(symbolConfig.Symbol, s.startYear, s.batchSize, 7); err != nil {
-				logger.Errorf("❌ 历史数据同步失败: symbol=%s, error=%v", symbolConfig.Symbol, err)
-			} else {
-				logger.Infof("✅ 历史数据同步成功: %s", symbolConfig.Symbol)
-			}
+		}
 
-			logger.Infof("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		// 按保存的进度继续回填（每个任务处理若干批后让出 worker，下一轮继续），已同步的数据直接跳过
+		// 限制只拉取最近7天的数据
+		jobs := make([]datasync.Job, 0, len(allSymbols))
+		for _, symbolConfig := range allSymbols {
+			symbol := symbolConfig.Symbol
+			jobs = append(jobs, datasync.Job{
+				Symbol:   symbol,
+				Name:     "historical_backfill",
+				Priority: datasync.PriorityHistorical,
+				Run: func() error {
+					return datasync.BackfillSymbol(symbol, s.startYear, s.batchSize, 7)
+				},
+			})
+		}
+		datasync.SubmitAndWait(jobs...)
 
-			// 等待指定间隔后继续下一个币种
-			select {
-			case <-s.stopChan:
-				return
-			case <-time.After(s.syncInterval):
-				// 继续下一个币种
-			}
+		// 等待指定间隔后开始下一轮（至少1秒，避免没有缺失数据时空转）
+		select {
+		case <-s.stopChan:
+			return
+		case <-time.After(max(s.syncInterval, time.Second)):
 		}
 	}
 }
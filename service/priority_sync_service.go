@@ -1,7 +1,6 @@
 package service
 
 import (
-	"strings"
 	"sync"
 	"time"
 
@@ -92,9 +91,7 @@ func (s *PrioritySyncService) idleSyncLoop() {
 	ticker := time.NewTicker(s.idleSyncInterval)
 	defer ticker.Stop()
 
-	// 延迟启动，让优先同步先执行
-	time.Sleep(10 * time.Second)
-
+	// 历史数据的任务优先级低于近期数据，由调度器保证优先同步先执行
 	for {
 		select {
 		case <-s.stopChan:
@@ -125,25 +122,25 @@ func (s *PrioritySyncService) syncPrioritySymbols() {
 
 	logger.Infof("开始优先同步 %d 个币种的近期数据", len(allSymbols))
 
-	// 顺序同步所有币种（避免并发请求过多导致API限流）
-	// 优先模式：只同步近期数据
+	// 优先模式：只同步近期数据（由调度器并发执行，请求频率由各交易所的限流控制）
+	jobs := make([]datasync.Job, 0, len(allSymbols))
 	for _, symbolConfig := range allSymbols {
-		logger.Infof("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		logger.Infof("开始同步币种: %s", symbolConfig.Symbol)
-		if err := datasync.SyncSymbolWithPriority(symbolConfig.Symbol, true); err != nil {
-			// 忽略小币种的错误（用户要求）
-			if strings.Contains(err.Error(), "INVALID_CURRENCY_PAIR") {
-				logger.Warnf("币种可能不存在，跳过: symbol=%s", symbolConfig.Symbol)
-				continue
-			}
-			logger.Errorf("❌ 优先同步币种失败: symbol=%s, error=%v", symbolConfig.Symbol, err)
-		} else {
-			logger.Infof("✅ 优先同步币种成功: %s", symbolConfig.Symbol)
-		}
-		logger.Infof("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		// 每个币种之间稍作延迟，避免API限流
-		time.Sleep(300 * time.Millisecond)
+		symbol := symbolConfig.Symbol
+		jobs = append(jobs, datasync.Job{
+			Symbol:   symbol,
+			Name:     "recent",
+			Priority: datasync.PriorityRealtime,
+			Run: func() error {
+				// 交易对不存在或已下架时由调度器标记停止同步，之后不再提交该币种的任务
+				if err := datasync.SyncSymbolWithPriority(symbol, true); err != nil {
+					return err
+				}
+				logger.Infof("✅ 优先同步币种成功: %s", symbol)
+				return nil
+			},
+		})
 	}
+	datasync.SubmitAndWait(jobs...)
 
 	logger.Debugf("完成优先同步 %d 个币种", len(allSymbols))
 }
@@ -187,16 +184,18 @@ func (s *PrioritySyncService) syncIdleSymbols() {
 		currentIdx = 0
 	}
 
-	symbolConfig := allSymbols[currentIdx]
-	logger.Infof("空闲同步: 同步币种 %s 的历史数据（从 %d 年开始）",
-		symbolConfig.Symbol, syncConfig.HistoricalStartYear)
+	symbol := allSymbols[currentIdx].Symbol
+	logger.Infof("空闲同步: 同步币种 %s 的历史数据（从 %d 年开始）", symbol, syncConfig.HistoricalStartYear)
 
 	// 同步历史数据
-	if err := datasync.SyncSymbolHistorical(symbolConfig.Symbol, syncConfig.HistoricalStartYear); err != nil {
-		logger.Errorf("空闲同步历史数据失败: symbol=%s, error=%v", symbolConfig.Symbol, err)
-	} else {
-		logger.Debugf("空闲同步历史数据成功: %s", symbolConfig.Symbol)
-	}
+	datasync.Submit(datasync.Job{
+		Symbol:   symbol,
+		Name:     "historical",
+		Priority: datasync.PriorityHistorical,
+		Run: func() error {
+			return datasync.SyncSymbolHistorical(symbol, syncConfig.HistoricalStartYear)
+		},
+	})
 
 	// 如果还有小币种，也同步它们的近期数据
 	minorSymbols, err := config.GetMinorSymbols()
@@ -204,11 +203,16 @@ func (s *PrioritySyncService) syncIdleSymbols() {
 		// 每次空闲同步时，同步一个小币种的近期数据
 		minorIdx := currentIdx % len(minorSymbols)
 		if minorIdx < len(minorSymbols) {
-			minorSymbol := minorSymbols[minorIdx]
-			logger.Debugf("空闲同步: 同步小币种 %s 的近期数据", minorSymbol.Symbol)
-			if err := datasync.SyncSymbolWithPriority(minorSymbol.Symbol, true); err != nil {
-				logger.Errorf("空闲同步小币种失败: symbol=%s, error=%v", minorSymbol.Symbol, err)
-			}
+			minorSymbol := minorSymbols[minorIdx].Symbol
+			logger.Debugf("空闲同步: 同步小币种 %s 的近期数据", minorSymbol)
+			datasync.Submit(datasync.Job{
+				Symbol:   minorSymbol,
+				Name:     "recent",
+				Priority: datasync.PriorityRealtime,
+				Run: func() error {
+					return datasync.SyncSymbolWithPriority(minorSymbol, true)
+				},
+			})
 		}
 	}
 }
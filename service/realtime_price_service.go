@@ -1,6 +1,7 @@
 package service
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -16,7 +17,7 @@ type RealtimePriceService struct {
 	mu           sync.RWMutex
 	running      bool
 	stopChan     chan struct{}
-	ctx          interface{} // runtime.Context
+	ctx          interface{}                             // runtime.Context
 	eventEmitter func(event string, data ...interface{}) // EventEmitter函数
 }
 
@@ -98,60 +99,75 @@ func (s *RealtimePriceService) fetchAndPushLatestPrice() {
 
 	logger.Debugf("开始获取实时价格: %d 个币种", len(allSymbols))
 
-	// 获取每个币种的最新价格
+	// 获取每个币种的最新价格（由调度器以最高优先级并发执行）
 	for _, symbolConfig := range allSymbols {
 		symbol := symbolConfig.Symbol
-		
-		// 同步最新数据（只同步最近几分钟的数据）
-		if err := datasync.SyncSymbolWithPriority(symbol, true); err != nil {
-			logger.Errorf("❌ 实时价格同步失败: symbol=%s, error=%v", symbol, err)
-			continue
-		}
 
-		// 从数据库获取最新的一条K线数据
-		latestKLine, err := database.GetLatestKLine1m(symbol)
-		if err != nil {
-			logger.Errorf("获取最新K线数据失败: symbol=%s, error=%v", symbol, err)
+		// 已通过 WebSocket 实时接收的币种不再轮询
+		if isStreamed(symbol) {
 			continue
 		}
 
-		if latestKLine != nil {
-			// 构建价格数据
-			priceData := map[string]interface{}{
-				"symbol":    symbol,
-				"time":      latestKLine.CloseTime,
-				"open":      latestKLine.Open,
-				"high":      latestKLine.High,
-				"low":       latestKLine.Low,
-				"close":     latestKLine.Close,
-				"volume":    latestKLine.Volume,
-				"timestamp": time.Now().UnixMilli(),
-			}
+		datasync.Submit(datasync.Job{
+			Symbol:   symbol,
+			Name:     "realtime_price",
+			Priority: datasync.PriorityRealtime,
+			Run: func() error {
+				return s.pushLatestPrice(symbol)
+			},
+		})
+	}
+}
 
-			// 推送到前端
-			if s.eventEmitter != nil {
-				s.eventEmitter("realtime-price", priceData)
-			} else if s.ctx != nil {
-				// 使用runtime.EventsEmit
-				if ctx, ok := s.ctx.(interface{ EventsEmit(string, ...interface{}) }); ok {
-					ctx.EventsEmit("realtime-price", priceData)
-				}
-			}
+// pushLatestPrice 同步单个币种的最新数据并推送最新价格
+func (s *RealtimePriceService) pushLatestPrice(symbol string) error {
+	// 同步最新数据（只同步最近几分钟的数据）
+	if err := datasync.SyncSymbolWithPriority(symbol, true); err != nil {
+		return fmt.Errorf("实时价格同步失败: %w", err)
+	}
+
+	// 从数据库获取最新的一条K线数据
+	latestKLine, err := database.GetLatestKLine1m(symbol)
+	if err != nil {
+		return fmt.Errorf("获取最新K线数据失败: %w", err)
+	}
+
+	if latestKLine != nil {
+		// 构建价格数据
+		priceData := map[string]interface{}{
+			"symbol":      symbol,
+			"time":        latestKLine.CloseTime,
+			"open":        latestKLine.Open,
+			"high":        latestKLine.High,
+			"low":         latestKLine.Low,
+			"close":       latestKLine.Close,
+			"volume":      latestKLine.Volume,
+			"quoteVolume": latestKLine.QuoteVolume,
+			"tradeCount":  latestKLine.TradeCount,
+			"timestamp":   time.Now().UnixMilli(),
+		}
 
-			logger.Debugf("✅ 推送实时价格: symbol=%s, price=%.2f", symbol, latestKLine.Close)
+		// 推送到前端
+		if s.eventEmitter != nil {
+			s.eventEmitter("realtime-price", priceData)
+		} else if s.ctx != nil {
+			// 使用runtime.EventsEmit
+			if ctx, ok := s.ctx.(interface{ EventsEmit(string, ...interface{}) }); ok {
+				ctx.EventsEmit("realtime-price", priceData)
+			}
 		}
 
-		// 每个币种之间稍作延迟，避免API限流
-		time.Sleep(200 * time.Millisecond)
+		logger.Debugf("✅ 推送实时价格: symbol=%s, price=%.2f", symbol, latestKLine.Close)
 	}
+	return nil
 }
 
 // GapFillService 历史空缺补充服务
-// 检测当天的空缺并补充
+// 检测当天的空缺并补充，同时重新同步之前失败的时间段
 type GapFillService struct {
-	mu           sync.RWMutex
-	running      bool
-	stopChan     chan struct{}
+	mu            sync.RWMutex
+	running       bool
+	stopChan      chan struct{}
 	checkInterval time.Duration // 检查间隔（默认5分钟）
 }
 
@@ -208,6 +224,7 @@ func (s *GapFillService) gapFillLoop() {
 
 	// 立即执行一次
 	s.checkAndFillGaps()
+	s.retryFailedRanges()
 
 	for {
 		select {
@@ -215,6 +232,7 @@ func (s *GapFillService) gapFillLoop() {
 			return
 		case <-ticker.C:
 			s.checkAndFillGaps()
+			s.retryFailedRanges()
 		}
 	}
 }
@@ -235,50 +253,67 @@ func (s *GapFillService) checkAndFillGaps() {
 
 	logger.Infof("开始检查历史空缺: %d 个币种", len(allSymbols))
 
-	now := time.Now().UnixMilli()
-	// 检查今天的数据（从今天00:00:00到现在）
+	// 检查今天的数据（从今天00:00:00到上一根已收盘的K线）
 	today := time.Now().UTC()
 	todayStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
-	todayEnd := now
+	todayEnd := today.Truncate(time.Minute).UnixMilli() - 1
 
+	// 每个币种一个空缺补充任务，由调度器按优先级（低于实时数据、高于历史回填）执行
 	for _, symbolConfig := range allSymbols {
 		symbol := symbolConfig.Symbol
+		datasync.Submit(datasync.Job{
+			Symbol:   symbol,
+			Name:     "gap_fill",
+			Priority: datasync.PriorityGapFill,
+			Run: func() error {
+				return fillGaps(symbol, todayStart, todayEnd)
+			},
+		})
+	}
+}
 
-		logger.Debugf("检查币种 %s 的当天空缺", symbol)
-
-		// 查找当天的缺失时间段
-		missingRanges, err := database.FindMissingRanges(symbol, todayStart, todayEnd)
-		if err != nil {
-			logger.Errorf("查找缺失时间段失败: symbol=%s, error=%v", symbol, err)
-			continue
-		}
+// retryFailedRanges 重新同步到期的失败时间段（限流、网络错误等导致同步失败的时间段不会被遗忘）
+func (s *GapFillService) retryFailedRanges() {
+	if _, err := datasync.RetryFailedRanges(); err != nil {
+		logger.Errorf("获取失败时间段失败: %v", err)
+	}
+}
 
-		if len(missingRanges) == 0 {
-			logger.Debugf("[%s] ✓ 当天数据完整，无空缺", symbol)
-			continue
-		}
+// fillGaps 查找并补充币种在 [startTime, endTime] 内的空缺
+func fillGaps(symbol string, startTime, endTime int64) error {
+	logger.Debugf("检查币种 %s 的当天空缺", symbol)
 
-		logger.Infof("[%s] 发现 %d 个当天空缺时间段，开始补充", symbol, len(missingRanges))
+	// 查找当天的缺失时间段
+	missingRanges, err := database.FindMissingRanges(symbol, startTime, endTime)
+	if err != nil {
+		return fmt.Errorf("查找缺失时间段失败: %w", err)
+	}
 
-		// 补充每个缺失的时间段
-		for _, missingRange := range missingRanges {
-			rangeStartStr := time.Unix(missingRange.StartTime/1000, 0).Format("2006-01-02 15:04:05")
-			rangeEndStr := time.Unix(missingRange.EndTime/1000, 0).Format("2006-01-02 15:04:05")
-			logger.Infof("[%s] 补充空缺时间段: %s ~ %s", symbol, rangeStartStr, rangeEndStr)
+	if len(missingRanges) == 0 {
+		logger.Debugf("[%s] ✓ 当天数据完整，无空缺", symbol)
+		return nil
+	}
 
-			// 使用历史数据同步方法补充
-			if err := datasync.SyncSymbolHistoricalBackward(symbol, 2024, 300, 7); err != nil {
-				logger.Errorf("❌ 补充空缺失败: symbol=%s, range=%s~%s, error=%v", 
-					symbol, rangeStartStr, rangeEndStr, err)
-			} else {
-				logger.Infof("✅ 补充空缺成功: symbol=%s, range=%s~%s", symbol, rangeStartStr, rangeEndStr)
-			}
+	logger.Infof("[%s] 发现 %d 个当天空缺时间段，开始补充", symbol, len(missingRanges))
+
+	// 补充每个缺失的时间段
+	failed := 0
+	for _, missingRange := range missingRanges {
+		rangeStartStr := time.Unix(missingRange.StartTime/1000, 0).Format("2006-01-02 15:04:05")
+		rangeEndStr := time.Unix(missingRange.EndTime/1000, 0).Format("2006-01-02 15:04:05")
+		logger.Infof("[%s] 补充空缺时间段: %s ~ %s", symbol, rangeStartStr, rangeEndStr)
+
+		if err := datasync.SyncRange(symbol, missingRange.StartTime, missingRange.EndTime); err != nil {
+			logger.Errorf("❌ 补充空缺失败: symbol=%s, range=%s~%s, error=%v",
+				symbol, rangeStartStr, rangeEndStr, err)
+			failed++
+		} else {
+			logger.Infof("✅ 补充空缺成功: symbol=%s, range=%s~%s", symbol, rangeStartStr, rangeEndStr)
 		}
-
-		// 每个币种之间稍作延迟
-		time.Sleep(500 * time.Millisecond)
 	}
 
-	logger.Debugf("完成历史空缺检查: %d 个币种", len(allSymbols))
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 个空缺时间段补充失败", failed, len(missingRanges))
+	}
+	return nil
 }
-
@@ -103,23 +103,25 @@ func (s *RealtimeSyncService) syncRealtimeData() {
 
 	logger.Infof("开始实时数据同步: %d 个币种", len(allSymbols))
 
-	// 顺序同步所有币种的实时数据（近期数据模式）
+	// 同步所有币种的实时数据（近期数据模式，由调度器并发执行），全部完成后返回
+	jobs := make([]datasync.Job, 0, len(allSymbols))
 	for _, symbolConfig := range allSymbols {
-		logger.Infof("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		logger.Infof("实时同步币种: %s", symbolConfig.Symbol)
-
-		// 实时模式：只同步近期数据（使用时间段状态表，智能跳过已同步的数据）
-		if err := datasync.SyncSymbolWithPriority(symbolConfig.Symbol, true); err != nil {
-			logger.Errorf("❌ 实时数据同步失败: symbol=%s, error=%v", symbolConfig.Symbol, err)
-		} else {
-			logger.Infof("✅ 实时数据同步成功: %s", symbolConfig.Symbol)
-		}
-
-		logger.Infof("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-
-		// 每个币种之间稍作延迟，避免API限流
-		time.Sleep(200 * time.Millisecond)
+		symbol := symbolConfig.Symbol
+		jobs = append(jobs, datasync.Job{
+			Symbol:   symbol,
+			Name:     "recent",
+			Priority: datasync.PriorityRealtime,
+			Run: func() error {
+				// 实时模式：只同步近期数据（使用时间段状态表，智能跳过已同步的数据）
+				if err := datasync.SyncSymbolWithPriority(symbol, true); err != nil {
+					return err
+				}
+				logger.Infof("✅ 实时数据同步成功: %s", symbol)
+				return nil
+			},
+		})
 	}
+	datasync.SubmitAndWait(jobs...)
 
-		logger.Debugf("完成实时数据同步: %d 个币种", len(allSymbols))
+	logger.Debugf("完成实时数据同步: %d 个币种", len(allSymbols))
 }
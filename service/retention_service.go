@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// RetentionService 数据保留服务
+// 按 symbols.json 中的保留策略定期在后台分批清理过期的1分钟K线（聚合K线永久保留）
+type RetentionService struct {
+	mu            sync.RWMutex
+	running       bool
+	stopChan      chan struct{}
+	checkInterval time.Duration // 检查间隔（默认60分钟）
+}
+
+// NewRetentionService 创建数据保留服务
+func NewRetentionService(intervalMinutes int) *RetentionService {
+	if intervalMinutes <= 0 {
+		intervalMinutes = 60 // 默认60分钟检查一次
+	}
+	return &RetentionService{
+		stopChan:      make(chan struct{}),
+		checkInterval: time.Duration(intervalMinutes) * time.Minute,
+	}
+}
+
+// Start 启动数据保留服务
+func (s *RetentionService) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		logger.Warn("数据保留服务已在运行")
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	logger.Infof("启动数据保留服务，检查间隔: %v", s.checkInterval)
+
+	go s.purgeLoop()
+}
+
+// Stop 停止数据保留服务（正在进行的清理会在当前批次完成后中止）
+func (s *RetentionService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.running = false
+		close(s.stopChan)
+		logger.Info("数据保留服务已停止")
+	}
+}
+
+// IsRunning 检查服务是否运行中
+func (s *RetentionService) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+// purgeLoop 数据清理循环
+func (s *RetentionService) purgeLoop() {
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	// 立即执行一次
+	s.purge()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.purge()
+		}
+	}
+}
+
+// purge 清理所有启用币种的过期数据
+func (s *RetentionService) purge() {
+	results, err := database.PurgeExpiredFromConfig(s.stopChan)
+	if err != nil {
+		logger.Errorf("清理过期K线失败: %v", err)
+		return
+	}
+
+	deleted := 0
+	for _, result := range results {
+		deleted += result.DeletedRows
+	}
+	logger.Debugf("过期K线检查完成: %d 个币种, 删除 %d 条", len(results), deleted)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+	"wails-contract-warn/stream"
+	datasync "wails-contract-warn/sync"
+)
+
+// streamRangeFlushInterval 收盘K线对应的已同步时间段在内存中合并，每隔该时间写入一次 sync_time_ranges
+const streamRangeFlushInterval = 5 * time.Minute
+
+// StreamService WebSocket 实时K线服务
+// 订阅 Gate.io 现货的1分钟K线：交易所标记收盘（w=true）的K线写入数据库，所有推送都发送到前端（realtime-price 事件），
+// 断线期间缺失的K线和没有收到收盘推送的K线通过 REST 接口补齐
+type StreamService struct {
+	mu           sync.RWMutex
+	running      bool
+	client       *stream.Client
+	symbols      map[string]bool
+	stopChan     chan struct{}
+	eventEmitter func(event string, data ...interface{}) // EventEmitter函数
+
+	rangesMu sync.Mutex
+	ranges   map[string]*database.SyncTimeRange // 已保存但还没有写入 sync_time_ranges 的连续时间段（key: symbol）
+}
+
+// activeStream 正在运行的实时K线服务（实时价格服务据此跳过已通过 WebSocket 接收的币种）
+var activeStream struct {
+	mu      sync.RWMutex
+	service *StreamService
+}
+
+// NewStreamService 创建 WebSocket 实时K线服务
+func NewStreamService(eventEmitter func(event string, data ...interface{})) *StreamService {
+	return &StreamService{
+		symbols:      make(map[string]bool),
+		eventEmitter: eventEmitter,
+		ranges:       make(map[string]*database.SyncTimeRange),
+	}
+}
+
+// Start 启动 WebSocket 实时K线服务（订阅所有启用的 Gate.io 现货币种）
+func (s *StreamService) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		logger.Warn("实时K线服务已在运行")
+		return
+	}
+
+	allSymbols, err := config.GetAllEnabledSymbols()
+	if err != nil {
+		s.mu.Unlock()
+		logger.Errorf("获取币种配置失败: %v", err)
+		return
+	}
+
+	protocol := stream.NewGateSpotProtocol("")
+	symbols := make([]string, 0, len(allSymbols))
+	for _, symbolConfig := range allSymbols {
+		if protocol.Supports(symbolConfig.Symbol) && !s.symbols[symbolConfig.Symbol] && !datasync.IsSymbolInactive(symbolConfig.Symbol) {
+			s.symbols[symbolConfig.Symbol] = true
+			symbols = append(symbols, symbolConfig.Symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		s.mu.Unlock()
+		logger.Debug("没有可通过 WebSocket 订阅的币种，跳过实时K线服务")
+		return
+	}
+
+	client := stream.NewClient(protocol, symbols)
+	client.OnCandle = s.onCandle
+	client.OnGap = s.onGap
+	s.client = client
+	s.stopChan = make(chan struct{})
+	s.running = true
+	s.mu.Unlock()
+
+	activeStream.mu.Lock()
+	activeStream.service = s
+	activeStream.mu.Unlock()
+
+	logger.Infof("启动实时K线服务，通过 WebSocket 订阅 %d 个币种", len(symbols))
+	client.Start()
+	go s.flushLoop(s.stopChan)
+}
+
+// Stop 停止 WebSocket 实时K线服务
+func (s *StreamService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.running = false
+		s.client.Stop()
+		close(s.stopChan)
+		s.flushRanges()
+
+		activeStream.mu.Lock()
+		if activeStream.service == s {
+			activeStream.service = nil
+		}
+		activeStream.mu.Unlock()
+		logger.Info("实时K线服务已停止")
+	}
+}
+
+// IsRunning 检查服务是否运行中
+func (s *StreamService) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+// covers 该币种当前是否通过 WebSocket 接收（连接断开期间返回 false，由轮询兜底）
+func (s *StreamService) covers(symbol string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running && s.symbols[symbol] && s.client.IsConnected()
+}
+
+// isStreamed 该币种当前是否由运行中的实时K线服务接收
+func isStreamed(symbol string) bool {
+	activeStream.mu.RLock()
+	defer activeStream.mu.RUnlock()
+	return activeStream.service != nil && activeStream.service.covers(symbol)
+}
+
+// onCandle 收盘的K线写入数据库并合并到待记录的已同步时间段，所有K线推送到前端
+func (s *StreamService) onCandle(kline database.KLine1m, closed bool) {
+	if closed {
+		if _, err := database.SaveKLine1mWithRecentUpsert([]database.KLine1m{kline}, upsertMinutes()); err != nil {
+			logger.Errorf("[%s] 保存实时K线失败: %v", kline.Symbol, err)
+		} else {
+			s.extendRange(kline)
+		}
+	}
+
+	if s.eventEmitter != nil {
+		s.eventEmitter("realtime-price", map[string]interface{}{
+			"symbol":      kline.Symbol,
+			"time":        kline.CloseTime,
+			"open":        kline.Open,
+			"high":        kline.High,
+			"low":         kline.Low,
+			"close":       kline.Close,
+			"volume":      kline.Volume,
+			"quoteVolume": kline.QuoteVolume,
+			"tradeCount":  kline.TradeCount,
+			"closed":      closed,
+			"timestamp":   time.Now().UnixMilli(),
+		})
+	}
+}
+
+// extendRange 把收盘K线合并到币种待记录的时间段：与上一段相连时延长，不相连时先记录上一段再开始新的一段
+func (s *StreamService) extendRange(kline database.KLine1m) {
+	s.rangesMu.Lock()
+	r, ok := s.ranges[kline.Symbol]
+	if ok && r.EndTime+1 == kline.OpenTime {
+		r.EndTime = kline.CloseTime
+		s.rangesMu.Unlock()
+		return
+	}
+	s.ranges[kline.Symbol] = &database.SyncTimeRange{StartTime: kline.OpenTime, EndTime: kline.CloseTime}
+	s.rangesMu.Unlock()
+
+	if ok {
+		recordStreamedRange(kline.Symbol, *r)
+	}
+}
+
+// flushLoop 定时把待记录的时间段写入 sync_time_ranges
+func (s *StreamService) flushLoop(stopChan <-chan struct{}) {
+	ticker := time.NewTicker(streamRangeFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			s.flushRanges()
+		}
+	}
+}
+
+// flushRanges 写入所有待记录的时间段
+// 每个币种只记录一次合并后的时间段，避免每分钟一次写入和合并；未写入时服务退出的分钟之后会被重新检查并补齐
+func (s *StreamService) flushRanges() {
+	s.rangesMu.Lock()
+	ranges := s.ranges
+	s.ranges = make(map[string]*database.SyncTimeRange)
+	s.rangesMu.Unlock()
+
+	for symbol, r := range ranges {
+		recordStreamedRange(symbol, *r)
+	}
+}
+
+// recordStreamedRange 记录通过 WebSocket 保存的时间段
+func recordStreamedRange(symbol string, r database.SyncTimeRange) {
+	if err := database.AddSyncTimeRange(symbol, r.StartTime, r.EndTime); err != nil {
+		logger.Warnf("[%s] 记录同步时间段失败: %v", symbol, err)
+	}
+}
+
+// onGap 通过 REST 接口补齐缺失或不完整的K线（交给调度器执行，不阻塞推送的读取）
+func (s *StreamService) onGap(symbol string, startTime, endTime int64) {
+	logger.Infof("[%s] WebSocket 推送缺失 %s ~ %s，通过 REST 接口补齐", symbol,
+		time.UnixMilli(startTime).Format("2006-01-02 15:04:05"),
+		time.UnixMilli(endTime).Format("2006-01-02 15:04:05"))
+
+	datasync.Submit(datasync.Job{
+		Symbol:   symbol,
+		Name:     fmt.Sprintf("stream_gap_%d", startTime),
+		Priority: datasync.PriorityGapFill,
+		Run: func() error {
+			return datasync.SyncRange(symbol, startTime, endTime)
+		},
+	})
+}
+
+// upsertMinutes 最近N分钟的K线使用 upsert 保存（配置加载失败时使用默认值）
+func upsertMinutes() int {
+	syncConfig, err := config.GetSyncConfig()
+	if err != nil {
+		return config.DefaultUpsertRecentMinutes
+	}
+	return syncConfig.UpsertRecentMinutes
+}
@@ -102,20 +102,24 @@ func (s *SyncService) syncAll() {
 
 	logger.Debugf("开始同步 %d 个交易对: %v", len(symbols), symbols)
 
-	// 并发同步多个交易对
-	var wg sync.WaitGroup
+	// 由调度器并发同步多个交易对（并发数和请求频率受调度器和限流控制）
+	jobs := make([]datasync.Job, 0, len(symbols))
 	for _, symbol := range symbols {
-		wg.Add(1)
-		go func(sym string) {
-			defer wg.Done()
-			if err := datasync.SyncSymbol(sym); err != nil {
-				logger.Errorf("同步交易对失败: symbol=%s, error=%v", sym, err)
-			} else {
+		sym := symbol
+		jobs = append(jobs, datasync.Job{
+			Symbol:   sym,
+			Name:     "recent",
+			Priority: datasync.PriorityRealtime,
+			Run: func() error {
+				if err := datasync.SyncSymbol(sym); err != nil {
+					return err
+				}
 				logger.Debugf("同步交易对成功: %s", sym)
-			}
-		}(symbol)
+				return nil
+			},
+		})
 	}
-	wg.Wait()
+	datasync.SubmitAndWait(jobs...)
 
 	logger.Debugf("完成同步 %d 个交易对", len(symbols))
 }
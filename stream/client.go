@@ -0,0 +1,285 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// 连接参数
+const (
+	pingInterval     = 10 * time.Second // 心跳间隔
+	readTimeout      = 30 * time.Second // 超过该时间没有收到任何消息，认为连接已断开并重连
+	writeTimeout     = 10 * time.Second // 单条消息的发送超时
+	handshakeTimeout = 10 * time.Second // 建立连接的超时
+	minReconnectWait = time.Second      // 首次重连等待时间（之后每次翻倍）
+	maxReconnectWait = time.Minute      // 重连等待时间上限
+	stableDuration   = time.Minute      // 连接保持超过该时间后，重连等待时间恢复为初始值
+)
+
+// candleState 某个交易对最近收到的一根K线
+type candleState struct {
+	kline  database.KLine1m
+	closed bool // 交易所是否已推送该K线的收盘数据（已作为收盘K线交给 OnCandle）
+}
+
+// Client WebSocket K线订阅客户端
+// 断线后按指数退避自动重连并重新订阅，定时发送心跳，按开盘时间检查推送顺序：
+// 旧K线的推送直接丢弃；开盘时间前进时，跳过的分钟（断线期间）和没有收到收盘推送的上一根K线通过 OnGap 通知补齐
+type Client struct {
+	protocol Protocol
+	symbols  []string
+
+	// OnCandle 收到K线推送（closed 为 true 表示交易所已标记该K线收盘，每根K线只会以收盘状态回调一次）
+	OnCandle func(kline database.KLine1m, closed bool)
+	// OnGap 检测到 [startTime, endTime] 内的K线缺失或不完整（断线、推送跳过了某些分钟、没有收到收盘推送）
+	OnGap func(symbol string, startTime, endTime int64)
+
+	mu        sync.RWMutex
+	running   bool
+	connected bool
+	stopChan  chan struct{}
+	conn      *websocket.Conn
+
+	writeMu sync.Mutex              // 心跳和订阅消息不能并发写入
+	candles map[string]*candleState // 只在读取协程中访问
+}
+
+// NewClient 创建 WebSocket 客户端
+func NewClient(protocol Protocol, symbols []string) *Client {
+	return &Client{
+		protocol: protocol,
+		symbols:  symbols,
+		stopChan: make(chan struct{}),
+		candles:  make(map[string]*candleState),
+	}
+}
+
+// Start 在后台连接并订阅
+func (c *Client) Start() {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	go c.run()
+}
+
+// Stop 断开连接并停止重连
+func (c *Client) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		c.running = false
+		close(c.stopChan)
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// IsRunning 检查客户端是否运行中
+func (c *Client) IsRunning() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.running
+}
+
+// IsConnected 检查当前是否已连接并完成订阅
+func (c *Client) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected
+}
+
+// Symbols 订阅的交易对
+func (c *Client) Symbols() []string {
+	return c.symbols
+}
+
+// run 连接循环：断线后按指数退避重连
+func (c *Client) run() {
+	wait := minReconnectWait
+	for {
+		connectedAt := time.Now()
+		err := c.serve()
+
+		select {
+		case <-c.stopChan:
+			return
+		default:
+		}
+
+		// 连接稳定运行过一段时间，说明不是持续性故障，重连等待时间恢复为初始值
+		if time.Since(connectedAt) > stableDuration {
+			wait = minReconnectWait
+		}
+		logger.Warnf("[%s] WebSocket 连接断开: %v，%v 后重连", c.protocol.Name(), err, wait)
+
+		select {
+		case <-c.stopChan:
+			return
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > maxReconnectWait {
+			wait = maxReconnectWait
+		}
+	}
+}
+
+// serve 建立一次连接：订阅、启动心跳并读取推送，连接断开时返回
+func (c *Client) serve() error {
+	// 代理、域名解析和证书与 HTTP 请求一致（symbols.json 的 network 配置）
+	transport := api.DefaultTransport()
+	dialer := websocket.Dialer{
+		Proxy:            transport.Proxy,
+		NetDialContext:   transport.DialContext,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: handshakeTimeout,
+	}
+	conn, _, err := dialer.Dial(c.protocol.URL(), nil)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		conn.Close()
+		return fmt.Errorf("客户端已停止")
+	}
+	c.conn = conn
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.connected = false
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	// 重新连接后重新订阅所有交易对
+	messages, err := c.protocol.SubscribeMessages(c.symbols)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		if err := c.write(conn, message); err != nil {
+			return fmt.Errorf("发送订阅消息失败: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.connected = true
+	c.mu.Unlock()
+	logger.Infof("[%s] WebSocket 已连接并订阅 %d 个交易对: %v", c.protocol.Name(), len(c.symbols), c.symbols)
+
+	// 收到任何消息（包括协议层的 pong）都延长读取超时
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.heartbeat(conn, done)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("读取消息失败: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		updates, err := c.protocol.Parse(data)
+		if err != nil {
+			logger.Warnf("[%s] %v", c.protocol.Name(), err)
+			continue
+		}
+		for _, update := range updates {
+			c.handle(update)
+		}
+	}
+}
+
+// heartbeat 定时发送心跳（协议层 ping 和交易所的应用层心跳），发送失败时关闭连接触发重连
+func (c *Client) heartbeat(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			message, err := c.protocol.PingMessage()
+			if err == nil && message != nil {
+				err = c.write(conn, message)
+			} else if err == nil {
+				c.writeMu.Lock()
+				err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+				c.writeMu.Unlock()
+			}
+			if err != nil {
+				logger.Warnf("[%s] 发送心跳失败: %v", c.protocol.Name(), err)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// write 发送一条文本消息
+func (c *Client) write(conn *websocket.Conn, message []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// handle 按开盘时间检查推送顺序并回调
+func (c *Client) handle(update CandleUpdate) {
+	kline := update.KLine
+	prev, ok := c.candles[kline.Symbol]
+	if ok {
+		switch {
+		case kline.OpenTime < prev.kline.OpenTime:
+			// 乱序或重复推送的旧K线
+			logger.Debugf("[%s] 丢弃过期的K线推送: %d < %d", kline.Symbol, kline.OpenTime, prev.kline.OpenTime)
+			return
+		case kline.OpenTime == prev.kline.OpenTime:
+			if prev.closed {
+				return
+			}
+		default:
+			gapStart := prev.kline.OpenTime + 60000
+			if !prev.closed {
+				// 没有收到上一根K线的收盘推送（w=true），最后一次推送只是未收盘时的快照，与跳过的分钟一起补齐
+				gapStart = prev.kline.OpenTime
+			}
+			if kline.OpenTime > gapStart && c.OnGap != nil {
+				c.OnGap(kline.Symbol, gapStart, kline.OpenTime-1)
+			}
+		}
+	}
+
+	c.candles[kline.Symbol] = &candleState{kline: kline, closed: update.Closed}
+	c.emit(kline, update.Closed)
+}
+
+// emit 回调 OnCandle
+func (c *Client) emit(kline database.KLine1m, closed bool) {
+	if c.OnCandle != nil {
+		c.OnCandle(kline, closed)
+	}
+}
@@ -0,0 +1,211 @@
+package stream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"wails-contract-warn/database"
+)
+
+// minute 测试使用的第 n 分钟的开盘时间
+func minute(n int) int64 {
+	return 1700000040000 + int64(n)*60000
+}
+
+// candleEvent OnCandle 和 OnGap 的回调记录
+type candleEvent struct {
+	Gap      bool
+	OpenTime int64 // OnCandle: K线开盘时间；OnGap: 开始时间
+	EndTime  int64 // OnGap: 结束时间
+	Closed   bool
+	Close    float64
+}
+
+// recorder 记录客户端的回调（回调可能来自读取协程）
+type recorder struct {
+	mu     sync.Mutex
+	events []candleEvent
+}
+
+func (r *recorder) attach(c *Client) {
+	c.OnCandle = func(kline database.KLine1m, closed bool) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, candleEvent{OpenTime: kline.OpenTime, Closed: closed, Close: kline.Close})
+	}
+	c.OnGap = func(symbol string, startTime, endTime int64) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, candleEvent{Gap: true, OpenTime: startTime, EndTime: endTime})
+	}
+}
+
+func (r *recorder) snapshot() []candleEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]candleEvent(nil), r.events...)
+}
+
+// update 第 n 分钟的K线推送
+func update(n int, close float64, closed bool) CandleUpdate {
+	return CandleUpdate{KLine: database.KLine1m{Symbol: "BTC_USDT", OpenTime: minute(n), Close: close, CloseTime: minute(n) + 59999}, Closed: closed}
+}
+
+func candle(n int, close float64, closed bool) candleEvent {
+	return candleEvent{OpenTime: minute(n), Closed: closed, Close: close}
+}
+
+func gap(from, to int) candleEvent {
+	return candleEvent{Gap: true, OpenTime: minute(from), EndTime: minute(to) - 1}
+}
+
+func TestClientHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []CandleUpdate
+		want    []candleEvent
+	}{
+		{
+			name:    "按顺序推送",
+			updates: []CandleUpdate{update(0, 1, false), update(0, 2, false), update(0, 3, true), update(1, 4, false)},
+			want:    []candleEvent{candle(0, 1, false), candle(0, 2, false), candle(0, 3, true), candle(1, 4, false)},
+		},
+		{
+			name:    "过期的推送",
+			updates: []CandleUpdate{update(1, 1, false), update(0, 2, true), update(1, 3, true)},
+			want:    []candleEvent{candle(1, 1, false), candle(1, 3, true)},
+		},
+		{
+			name:    "收盘后的重复推送",
+			updates: []CandleUpdate{update(0, 1, true), update(0, 2, false), update(0, 3, true), update(1, 4, false)},
+			want:    []candleEvent{candle(0, 1, true), candle(1, 4, false)},
+		},
+		{
+			name:    "连接内跳过的分钟",
+			updates: []CandleUpdate{update(0, 1, true), update(3, 2, false)},
+			want:    []candleEvent{candle(0, 1, true), gap(1, 3), candle(3, 2, false)},
+		},
+		{
+			// 最后一次推送只是未收盘时的快照，不作为收盘K线保存，通过 REST 接口补齐
+			name:    "没有收到收盘推送",
+			updates: []CandleUpdate{update(0, 1, false), update(1, 2, false)},
+			want:    []candleEvent{candle(0, 1, false), gap(0, 1), candle(1, 2, false)},
+		},
+		{
+			name:    "没有收到收盘推送且跳过了分钟",
+			updates: []CandleUpdate{update(0, 1, false), update(2, 2, true)},
+			want:    []candleEvent{candle(0, 1, false), gap(0, 2), candle(2, 2, true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(NewGateSpotProtocol("ws://127.0.0.1"), []string{"BTC_USDT"})
+			r := &recorder{}
+			r.attach(c)
+			for _, u := range tt.updates {
+				c.handle(u)
+			}
+			assertEvents(t, r.snapshot(), tt.want)
+		})
+	}
+}
+
+func assertEvents(t *testing.T, got, want []candleEvent) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("回调 = %+v，期望 %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 次回调 = %+v，期望 %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// gateCandleMessage Gate.io 第 n 分钟的K线推送
+func gateCandleMessage(n int, close string, closed bool) string {
+	return `{"channel":"spot.candlesticks","event":"update","result":{"t":"` + strconv.FormatInt(minute(n)/1000, 10) +
+		`","v":"1","c":"` + close + `","h":"1","l":"1","o":"1","n":"1m_BTC_USDT","a":"1","w":` + strconv.FormatBool(closed) + `}}`
+}
+
+func TestClientReconnect(t *testing.T) {
+	// 第一次连接推送第0分钟（收到收盘推送）和第1分钟（未收盘）后断开；
+	// 重连后应重新订阅，第4分钟的推送触发补齐第1~3分钟
+	connections := [][]string{
+		{gateCandleMessage(0, "1", true), gateCandleMessage(1, "2", false)},
+		{gateCandleMessage(4, "3", false)},
+	}
+
+	var mu sync.Mutex
+	var subscriptions []string
+	connCount := 0
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		subscriptions = append(subscriptions, string(message))
+		n := connCount
+		connCount++
+		mu.Unlock()
+
+		if n < len(connections) {
+			for _, message := range connections[n] {
+				conn.WriteMessage(websocket.TextMessage, []byte(message))
+			}
+		}
+		if n == 0 {
+			return // 断开第一次连接
+		}
+		// 之后的连接保持到客户端停止
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(NewGateSpotProtocol("ws"+strings.TrimPrefix(server.URL, "http")), []string{"BTC_USDT"})
+	r := &recorder{}
+	r.attach(c)
+	c.Start()
+	defer c.Stop()
+
+	want := []candleEvent{candle(0, 1, true), candle(1, 2, false), gap(1, 4), candle(4, 3, false)}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.snapshot()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assertEvents(t, r.snapshot(), want)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(subscriptions) != 2 {
+		t.Fatalf("订阅次数 = %d，期望 2（重连后重新订阅）", len(subscriptions))
+	}
+	for _, message := range subscriptions {
+		if !strings.Contains(message, `"event":"subscribe","payload":["1m","BTC_USDT"]`) {
+			t.Errorf("订阅消息不正确: %s", message)
+		}
+	}
+	if !c.IsConnected() {
+		t.Errorf("重连后应处于已连接状态")
+	}
+}
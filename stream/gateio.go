@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+)
+
+// gateSpotWSURL Gate.io 现货 WebSocket 的官方地址
+const gateSpotWSURL = "wss://api.gateio.ws/ws/v4/"
+
+// gateSpotProtocol Gate.io 现货 spot.candlesticks 频道
+// 订阅: {"time":秒,"channel":"spot.candlesticks","event":"subscribe","payload":["1m","BTC_USDT"]}
+// 心跳: {"time":秒,"channel":"spot.ping"}，服务端回复 spot.pong
+type gateSpotProtocol struct {
+	url string // 为空时使用 symbols.json 中配置的地址或官方地址
+}
+
+// NewGateSpotProtocol 创建 Gate.io 现货K线频道协议（url 为空时使用配置或官方地址）
+func NewGateSpotProtocol(url string) Protocol {
+	return &gateSpotProtocol{url: url}
+}
+
+// Name 交易所名称
+func (g *gateSpotProtocol) Name() string { return "gateio" }
+
+// URL WebSocket 地址
+func (g *gateSpotProtocol) URL() string {
+	if g.url != "" {
+		return g.url
+	}
+	return config.GetExchangeWSURL(g.Name(), gateSpotWSURL)
+}
+
+// Supports 只支持 Gate.io 现货交易对
+func (g *gateSpotProtocol) Supports(symbol string) bool {
+	_, market := config.SplitMarketSymbol(symbol)
+	return market == config.MarketSpot && config.GetSymbolExchange(symbol) == g.Name()
+}
+
+// gateRequest Gate.io WebSocket 请求
+type gateRequest struct {
+	Time    int64    `json:"time"`
+	Channel string   `json:"channel"`
+	Event   string   `json:"event,omitempty"`
+	Payload []string `json:"payload,omitempty"`
+}
+
+// SubscribeMessages 每个交易对一条订阅消息
+func (g *gateSpotProtocol) SubscribeMessages(symbols []string) ([][]byte, error) {
+	messages := make([][]byte, 0, len(symbols))
+	for _, symbol := range symbols {
+		message, err := json.Marshal(gateRequest{
+			Time:    time.Now().Unix(),
+			Channel: "spot.candlesticks",
+			Event:   "subscribe",
+			Payload: []string{"1m", strings.ToUpper(symbol)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("生成订阅消息失败: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// PingMessage spot.ping 心跳
+func (g *gateSpotProtocol) PingMessage() ([]byte, error) {
+	return json.Marshal(gateRequest{Time: time.Now().Unix(), Channel: "spot.ping"})
+}
+
+// Parse 解析推送消息
+// K线推送: {"channel":"spot.candlesticks","event":"update","result":{"t":"1606292580","v":"2362.32","c":"19128.1","h":"19128.1","l":"19128.1","o":"19128.1","n":"1m_BTC_USDT","a":"3.8283","w":false}}
+// v 为计价币的成交额（与 REST 接口索引1一致），a 为基础币的成交量，w 为该K线是否已收盘
+func (g *gateSpotProtocol) Parse(data []byte) ([]CandleUpdate, error) {
+	var message struct {
+		Channel string `json:"channel"`
+		Event   string `json:"event"`
+		Error   *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("解析推送消息失败: %w", err)
+	}
+	if message.Error != nil {
+		return nil, fmt.Errorf("%s %s 失败: %s (code=%d)", message.Channel, message.Event, message.Error.Message, message.Error.Code)
+	}
+	if message.Channel != "spot.candlesticks" || message.Event != "update" {
+		return nil, nil
+	}
+
+	var candle struct {
+		T string `json:"t"`
+		V string `json:"v"` // 成交额（计价币种），与 REST 接口的索引1一致
+		C string `json:"c"`
+		H string `json:"h"`
+		L string `json:"l"`
+		O string `json:"o"`
+		N string `json:"n"`
+		A string `json:"a"` // 成交量（基础币种）
+		W bool   `json:"w"`
+	}
+	if err := json.Unmarshal(message.Result, &candle); err != nil {
+		return nil, fmt.Errorf("解析K线推送失败: %w", err)
+	}
+
+	// n 的格式为 "周期_交易对"，如 1m_BTC_USDT
+	interval, pair, ok := strings.Cut(candle.N, "_")
+	if !ok || interval != "1m" {
+		return nil, nil
+	}
+
+	ts, errTs := strconv.ParseInt(candle.T, 10, 64)
+	open, errOpen := strconv.ParseFloat(candle.O, 64)
+	high, errHigh := strconv.ParseFloat(candle.H, 64)
+	low, errLow := strconv.ParseFloat(candle.L, 64)
+	close, errClose := strconv.ParseFloat(candle.C, 64)
+	quoteVolume, errQuote := strconv.ParseFloat(candle.V, 64)
+	volume, errVolume := strconv.ParseFloat(candle.A, 64)
+	if errTs != nil || errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil || errQuote != nil {
+		return nil, fmt.Errorf("解析K线推送失败: %s", string(message.Result))
+	}
+
+	openTime := ts * 1000
+	return []CandleUpdate{{
+		KLine: database.KLine1m{
+			Symbol:      pair,
+			OpenTime:    openTime,
+			Open:        open,
+			High:        high,
+			Low:         low,
+			Close:       close,
+			Volume:      volume,
+			QuoteVolume: quoteVolume,
+			CloseTime:   openTime + 60000 - 1,
+		},
+		Closed: candle.W,
+	}}, nil
+}
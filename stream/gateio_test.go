@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"strings"
+	"testing"
+
+	"wails-contract-warn/database"
+)
+
+func TestGateSpotParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []CandleUpdate
+		wantErr bool
+	}{
+		{
+			name:    "未收盘的K线",
+			message: `{"time":1606292600,"channel":"spot.candlesticks","event":"update","result":{"t":"1606292580","v":"2362.32","c":"19128.1","h":"19130.5","l":"19120","o":"19125.3","n":"1m_BTC_USDT","a":"0.1235","w":false}}`,
+			want: []CandleUpdate{{KLine: database.KLine1m{
+				Symbol: "BTC_USDT", OpenTime: 1606292580000, CloseTime: 1606292639999,
+				Open: 19125.3, High: 19130.5, Low: 19120, Close: 19128.1,
+				Volume: 0.1235, QuoteVolume: 2362.32,
+			}}},
+		},
+		{
+			name:    "收盘的K线",
+			message: `{"channel":"spot.candlesticks","event":"update","result":{"t":"1606292580","v":"4000","c":"19129","h":"19131","l":"19120","o":"19125.3","n":"1m_ETH_USDT","a":"0.2","w":true}}`,
+			want: []CandleUpdate{{KLine: database.KLine1m{
+				Symbol: "ETH_USDT", OpenTime: 1606292580000, CloseTime: 1606292639999,
+				Open: 19125.3, High: 19131, Low: 19120, Close: 19129,
+				Volume: 0.2, QuoteVolume: 4000,
+			}, Closed: true}},
+		},
+		{
+			name:    "其他周期",
+			message: `{"channel":"spot.candlesticks","event":"update","result":{"t":"1606292400","v":"1","c":"1","h":"1","l":"1","o":"1","n":"5m_BTC_USDT","a":"1","w":false}}`,
+		},
+		{
+			name:    "订阅确认",
+			message: `{"time":1606292600,"channel":"spot.candlesticks","event":"subscribe","result":{"status":"success"}}`,
+		},
+		{
+			name:    "心跳回复",
+			message: `{"time":1606292600,"channel":"spot.pong","event":"","result":null}`,
+		},
+		{
+			name:    "订阅失败",
+			message: `{"channel":"spot.candlesticks","event":"subscribe","error":{"code":2,"message":"unknown currency pair NOPE_USDT"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "无法解析的数值",
+			message: `{"channel":"spot.candlesticks","event":"update","result":{"t":"1606292580","v":"abc","c":"1","h":"1","l":"1","o":"1","n":"1m_BTC_USDT","a":"1","w":false}}`,
+			wantErr: true,
+		},
+		{
+			name:    "不是 JSON",
+			message: `pong`,
+			wantErr: true,
+		},
+	}
+
+	protocol := NewGateSpotProtocol("ws://127.0.0.1")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, err := protocol.Parse([]byte(tt.message))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v，期望出错: %v", err, tt.wantErr)
+			}
+			if len(updates) != len(tt.want) {
+				t.Fatalf("解析出 %d 根K线，期望 %d: %+v", len(updates), len(tt.want), updates)
+			}
+			for i := range updates {
+				if updates[i] != tt.want[i] {
+					t.Errorf("第 %d 根K线 = %+v，期望 %+v", i, updates[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGateSpotSubscribeMessages(t *testing.T) {
+	messages, err := NewGateSpotProtocol("ws://127.0.0.1").SubscribeMessages([]string{"btc_usdt", "ETH_USDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("订阅消息数量 = %d，期望 2", len(messages))
+	}
+	for i, pair := range []string{"BTC_USDT", "ETH_USDT"} {
+		want := `"channel":"spot.candlesticks","event":"subscribe","payload":["1m","` + pair + `"]}`
+		if got := string(messages[i]); !strings.HasSuffix(got, want) {
+			t.Errorf("第 %d 条订阅消息 = %s", i, got)
+		}
+	}
+}
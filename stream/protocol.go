@@ -0,0 +1,26 @@
+package stream
+
+import "wails-contract-warn/database"
+
+// CandleUpdate WebSocket 推送的一根1分钟K线
+type CandleUpdate struct {
+	KLine  database.KLine1m // Symbol 为本地交易对（BTC_USDT）
+	Closed bool             // 交易所是否已标记为收盘
+}
+
+// Protocol 交易所 WebSocket K线频道的协议
+// 连接管理（重连、重新订阅、心跳、顺序检查）与交易所无关，地址、订阅消息和推送格式由协议处理
+type Protocol interface {
+	// Name 交易所名称（与 symbols.json 中的 exchange 字段对应）
+	Name() string
+	// URL WebSocket 地址
+	URL() string
+	// Supports 是否支持订阅该本地交易对
+	Supports(symbol string) bool
+	// SubscribeMessages 订阅指定交易对1分钟K线的消息
+	SubscribeMessages(symbols []string) ([][]byte, error)
+	// PingMessage 应用层心跳消息（交易所不需要时返回 nil）
+	PingMessage() ([]byte, error)
+	// Parse 解析一条推送消息，心跳回复、订阅确认等非K线消息返回空列表，订阅失败等错误消息返回 error
+	Parse(data []byte) ([]CandleUpdate, error)
+}
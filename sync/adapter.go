@@ -0,0 +1,217 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+)
+
+// TimestampUnit 交易所接口使用的时间戳单位
+type TimestampUnit int
+
+const (
+	TimestampSeconds TimestampUnit = iota // 秒（Gate.io）
+	TimestampMillis                       // 毫秒
+)
+
+// toUnit 毫秒时间戳转换为指定单位
+func (u TimestampUnit) toUnit(ms int64) int64 {
+	if u == TimestampSeconds {
+		return ms / 1000
+	}
+	return ms
+}
+
+// toMillis 指定单位的时间戳转换为毫秒
+func (u TimestampUnit) toMillis(ts int64) int64 {
+	if u == TimestampSeconds {
+		return ts * 1000
+	}
+	return ts
+}
+
+// RateLimit 交易所K线接口的请求频率限制
+type RateLimit struct {
+	Requests int           // 时间窗口内允许的请求数
+	Window   time.Duration // 时间窗口
+}
+
+// Interval 按频率限制均匀分布时两次请求之间的最小间隔
+func (r RateLimit) Interval() time.Duration {
+	if r.Requests <= 0 {
+		return 0
+	}
+	return r.Window / time.Duration(r.Requests)
+}
+
+// ExchangeAdapter 交易所K线接口适配器
+// 同步逻辑（分页、限流、保存、记录已同步时间段）与交易所无关，接口地址、参数和响应格式由适配器处理
+type ExchangeAdapter interface {
+	// Name 交易所名称（与 symbols.json 中的 exchange 字段对应）
+	Name() string
+	// MaxPageSize 单次请求最多返回的K线数量
+	MaxPageSize() int
+	// TimestampUnit 接口使用的时间戳单位
+	TimestampUnit() TimestampUnit
+	// ExchangeSymbol 本地交易对（BTC_USDT，合约为 BTC_USDT_PERP）转换为交易所的交易对格式
+	ExchangeSymbol(symbol string) string
+	// RateLimit K线接口的请求频率限制
+	RateLimit() RateLimit
+	// FetchCandles 拉取 [startTime, endTime] 内的1分钟K线（毫秒时间戳，时间范围不超过 MaxPageSize 分钟）
+	// 返回的K线按开盘时间升序排列，Symbol 为本地交易对
+	FetchCandles(client *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error)
+}
+
+// adapterRegistry 已注册的交易所适配器（全局单例）
+var adapterRegistry = struct {
+	mu       sync.RWMutex
+	adapters map[string]ExchangeAdapter
+}{
+	adapters: make(map[string]ExchangeAdapter),
+}
+
+// RegisterAdapter 注册交易所适配器（同名覆盖）
+func RegisterAdapter(adapter ExchangeAdapter) {
+	adapterRegistry.mu.Lock()
+	defer adapterRegistry.mu.Unlock()
+	adapterRegistry.adapters[adapter.Name()] = adapter
+}
+
+// GetAdapter 按交易所名称获取适配器
+func GetAdapter(name string) (ExchangeAdapter, error) {
+	adapterRegistry.mu.RLock()
+	defer adapterRegistry.mu.RUnlock()
+	adapter, ok := adapterRegistry.adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的交易所: %s（可选: %v）", name, adapterNamesLocked())
+	}
+	return adapter, nil
+}
+
+// AdapterNames 已注册的交易所名称
+func AdapterNames() []string {
+	adapterRegistry.mu.RLock()
+	defer adapterRegistry.mu.RUnlock()
+	return adapterNamesLocked()
+}
+
+func adapterNamesLocked() []string {
+	names := make([]string, 0, len(adapterRegistry.adapters))
+	for name := range adapterRegistry.adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// marketAdapters 同一交易所其他市场的适配器（key: 交易所/市场类型）
+var marketAdapters = map[string]string{
+	"gateio/" + config.MarketUSDTFutures: "gateio_futures",
+}
+
+// AdapterForSymbol 获取币种配置的交易所适配器（未配置时使用 Gate.io，合约币种使用该交易所的合约适配器）
+func AdapterForSymbol(symbol string) (ExchangeAdapter, error) {
+	return GetAdapter(marketAdapterName(config.GetSymbolExchange(symbol), symbol))
+}
+
+// ReferenceAdapterForSymbol 获取币种交叉校验的参考交易所适配器（未配置或与数据来源相同时返回 nil）
+func ReferenceAdapterForSymbol(symbol string) (ExchangeAdapter, error) {
+	reference := config.GetSymbolReferenceExchange(symbol)
+	if reference == "" {
+		return nil, nil
+	}
+	name := marketAdapterName(reference, symbol)
+	if name == marketAdapterName(config.GetSymbolExchange(symbol), symbol) {
+		return nil, nil
+	}
+	return GetAdapter(name)
+}
+
+// marketAdapterName 合约币种使用该交易所的合约适配器（没有对应的适配器时使用原名称）
+func marketAdapterName(name, symbol string) string {
+	if _, market := config.SplitMarketSymbol(symbol); market != config.MarketSpot {
+		if adapter, ok := marketAdapters[name+"/"+market]; ok {
+			return adapter
+		}
+	}
+	return name
+}
+
+// exchangePair 去掉存储名中的市场后缀（BTC_USDT_PERP → BTC_USDT），再由适配器转换为交易所的格式
+func exchangePair(symbol string) string {
+	pair, _ := config.SplitMarketSymbol(symbol)
+	return strings.ToUpper(pair)
+}
+
+// fetchBackward 拉取倒序分页接口（OKX、Bybit，从最新的K线开始返回）在 [startTime, endTime] 内的K线
+// fetchPage 返回结束时间不晚于 pageEnd 的一页K线（顺序不限），以本页最早一根K线的前一毫秒作为下一页的结束时间，
+// 直到覆盖 startTime 或没有更多数据。结果去重并按开盘时间升序返回
+func fetchBackward(startTime, endTime int64, fetchPage func(pageEnd int64) ([]database.KLine1m, error)) ([]database.KLine1m, error) {
+	seen := make(map[int64]bool)
+	var klines []database.KLine1m
+	pageEnd := endTime
+	for pageEnd >= startTime {
+		page, err := fetchPage(pageEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		oldest := pageEnd + 1
+		for _, k := range page {
+			oldest = min(oldest, k.OpenTime)
+			if k.OpenTime < startTime || k.OpenTime > endTime || seen[k.OpenTime] {
+				continue
+			}
+			seen[k.OpenTime] = true
+			klines = append(klines, k)
+		}
+		// 没有更多数据，或接口没有按结束时间向前推进（避免死循环）
+		if len(page) == 0 || oldest > pageEnd {
+			break
+		}
+		pageEnd = oldest - 1
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].OpenTime < klines[j].OpenTime
+	})
+	return klines, nil
+}
+
+// resolveBaseURL 适配器的接口地址：创建时指定的地址优先，其次是 symbols.json 中 exchanges 配置的 base_url，最后是官方地址
+func resolveBaseURL(baseURL, exchange, defaultURL string) string {
+	if baseURL != "" {
+		return baseURL
+	}
+	return config.GetExchangeBaseURL(exchange, defaultURL)
+}
+
+// adapterFactories 内置的交易所适配器
+var adapterFactories = map[string]func(baseURL string) ExchangeAdapter{
+	"gateio":          NewGateAdapter,
+	"gateio_futures":  NewGateFuturesAdapter,
+	"binance_futures": NewBinanceFuturesAdapter,
+	"okx_swap":        NewOKXSwapAdapter,
+	"bybit_linear":    NewBybitLinearAdapter,
+}
+
+// NewAdapter 按交易所名称创建使用指定接口地址的适配器（如指向本地回放录制响应的服务，不影响已注册的适配器）
+func NewAdapter(name, baseURL string) (ExchangeAdapter, error) {
+	factory, ok := adapterFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的交易所: %s", name)
+	}
+	return factory(baseURL), nil
+}
+
+func init() {
+	for _, factory := range adapterFactories {
+		RegisterAdapter(factory(""))
+	}
+}
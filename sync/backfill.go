@@ -0,0 +1,341 @@
+package sync
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// 历史回填参数
+const (
+	backfillMaxAttempts   = 3         // 同一时间段连续失败的次数上限，超过后跳过（该时间段已记录到失败时间段，由重试队列继续处理）
+	backfillBatchesPerRun = 20        // 每次任务最多处理的时间段数，之后让出 worker，下一轮从进度处继续
+	backfillExtendAfter   = time.Hour // 回填完成后，目标结束时间之后新增的时间超过该长度时开始新一段回填
+)
+
+// backfillMu 串行化回填进度的“读取-修改-保存”（回填任务和前端的暂停/恢复可能同时修改同一币种的进度）
+var backfillMu sync.Mutex
+
+// backfillSession 本次运行中某个币种的回填速度（用于估算剩余时间，不持久化）
+type backfillSession struct {
+	startedAt    time.Time
+	startNextEnd int64 // 开始统计时的 NextEnd
+}
+
+// backfillSessions key: symbol
+var backfillSessions = struct {
+	mu       sync.Mutex
+	sessions map[string]*backfillSession
+}{
+	sessions: make(map[string]*backfillSession),
+}
+
+// BackfillProgress 回填进度（持久化的进度加上完成百分比和预计剩余时间）
+type BackfillProgress struct {
+	database.BackfillCursor
+	Percent    float64 `json:"percent"`    // 完成百分比（0-100）
+	ETASeconds int64   `json:"etaSeconds"` // 预计剩余秒数（-1 表示暂时无法估算）
+}
+
+// backfillTarget 回填的目标时间范围：从 maxDays 天前（不早于 startYear 年初和交易对的上线时间）到10分钟前
+func backfillTarget(symbol string, startYear, maxDays int) (targetStart, targetEnd int64) {
+	today := time.Now().UTC()
+	todayStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
+	targetStart = todayStart - int64(maxDays)*24*60*60*1000
+	if startYearTime := time.Date(startYear, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(); targetStart < startYearTime {
+		targetStart = startYearTime
+	}
+	targetStart = clampToListing(symbol, targetStart)
+	targetEnd = time.Now().Truncate(time.Minute).Add(-10*time.Minute).UnixMilli() - 1
+	return targetStart, targetEnd
+}
+
+// BackfillSymbol 按持久化的进度从新到旧回填币种的历史数据
+// 每处理完一个时间段（batchSize 分钟）就保存进度，应用重启后从中断处继续；已暂停或失败的币种直接返回
+// startYear: 起始年份；maxDays: 最多回填的天数
+func BackfillSymbol(symbol string, startYear, batchSize, maxDays int) error {
+	if batchSize <= 0 {
+		batchSize = 300 // 默认300条
+	}
+	if maxDays <= 0 {
+		maxDays = 7 // 默认7天
+	}
+
+	cursor, ok, err := prepareBackfill(symbol, startYear, maxDays)
+	if err != nil || !ok {
+		return err
+	}
+
+	adapter, err := AdapterForSymbol(symbol)
+	if err != nil {
+		return err
+	}
+	proxyClient := api.NewProxyClient()
+	batchSpan := int64(batchSize) * 60 * 1000
+
+	for batch := 0; batch < backfillBatchesPerRun; batch++ {
+		if cursor.NextEnd < cursor.TargetStart {
+			cursor.Status = database.BackfillCompleted
+			if _, err := saveBackfillProgress(cursor); err != nil {
+				return err
+			}
+			logger.Infof("[%s] ✓ 历史回填完成: %s ~ %s", symbol,
+				time.UnixMilli(cursor.TargetStart).Format("2006-01-02 15:04:05"),
+				time.UnixMilli(cursor.TargetEnd).Format("2006-01-02 15:04:05"))
+			return nil
+		}
+
+		// 先保存下一个时间段，中断后从这里继续
+		cursor.NextStart = max(cursor.TargetStart, cursor.NextEnd-batchSpan+1)
+		if saved, err := saveBackfillProgress(cursor); err != nil || !saved {
+			return err
+		}
+
+		if err := backfillRange(adapter, symbol, cursor.NextStart, cursor.NextEnd, proxyClient); err != nil {
+			cursor.Attempts++
+			cursor.LastError = err.Error()
+			switch {
+			case ErrorKindOf(err) == ErrInvalidPair:
+				cursor.Status = database.BackfillFailed
+			case cursor.Attempts >= backfillMaxAttempts:
+				logger.Warnf("[%s] 回填时间段连续失败 %d 次，跳过: %s ~ %s", symbol, cursor.Attempts,
+					time.UnixMilli(cursor.NextStart).Format("2006-01-02 15:04:05"),
+					time.UnixMilli(cursor.NextEnd).Format("2006-01-02 15:04:05"))
+				cursor.NextEnd = cursor.NextStart - 1
+				cursor.Attempts = 0
+				continue
+			}
+			// 下一轮重试同一时间段
+			if _, saveErr := saveBackfillProgress(cursor); saveErr != nil {
+				logger.Warnf("[%s] %v", symbol, saveErr)
+			}
+			return err
+		}
+
+		cursor.NextEnd = cursor.NextStart - 1
+		cursor.Attempts = 0
+		cursor.LastError = ""
+	}
+
+	cursor.NextStart = max(cursor.TargetStart, cursor.NextEnd-batchSpan+1)
+
+	_, err = saveBackfillProgress(cursor)
+	return err
+}
+
+// prepareBackfill 读取或创建币种的回填进度，返回 false 表示本轮不需要回填（已暂停、已失败或已完成）
+func prepareBackfill(symbol string, startYear, maxDays int) (database.BackfillCursor, bool, error) {
+	backfillMu.Lock()
+	defer backfillMu.Unlock()
+
+	targetStart, targetEnd := backfillTarget(symbol, startYear, maxDays)
+	stored, err := database.GetBackfillCursor(symbol)
+	if err != nil {
+		return database.BackfillCursor{}, false, err
+	}
+
+	var cursor database.BackfillCursor
+	switch {
+	case stored == nil || stored.TargetEnd == 0:
+		// 第一次回填（或暂停时还没有开始过）
+		cursor = database.BackfillCursor{
+			Symbol:      symbol,
+			Direction:   database.BackfillDirectionBackward,
+			Status:      database.BackfillRunning,
+			TargetStart: targetStart,
+			TargetEnd:   targetEnd,
+			NextEnd:     targetEnd,
+		}
+		if stored != nil {
+			cursor.Status = stored.Status
+		}
+		logger.Infof("[%s] 开始历史回填: %s ~ %s", symbol,
+			time.UnixMilli(targetStart).Format("2006-01-02 15:04:05"),
+			time.UnixMilli(targetEnd).Format("2006-01-02 15:04:05"))
+	case stored.Status == database.BackfillCompleted:
+		cursor = *stored
+		switch {
+		case targetEnd-cursor.TargetEnd >= backfillExtendAfter.Milliseconds():
+			// 应用关闭期间新增的时间：从现在倒推到上一段回填的结束时间
+			cursor.TargetStart = max(cursor.TargetEnd+1, targetStart)
+			cursor.TargetEnd = targetEnd
+			cursor.NextEnd = targetEnd
+		case targetStart < cursor.TargetStart:
+			// 起始年份或天数调大：继续向更早回填
+			cursor.NextEnd = cursor.TargetStart - 1
+			cursor.TargetStart = targetStart
+		default:
+			return cursor, false, nil
+		}
+		cursor.Status = database.BackfillRunning
+		cursor.Attempts = 0
+		cursor.LastError = ""
+		resetBackfillSession(symbol)
+	default:
+		cursor = *stored
+		// 起始年份或天数调大时继续向更早回填；获取到上线时间后不再回填上线之前的时间
+		cursor.TargetStart = clampToListing(symbol, min(cursor.TargetStart, targetStart))
+	}
+
+	if cursor.Status != database.BackfillRunning {
+		logger.Debugf("[%s] 历史回填状态为 %s，跳过", symbol, cursor.Status)
+		return cursor, false, nil
+	}
+	return cursor, true, nil
+}
+
+// backfillRange 同步 [startTime, endTime] 内缺失的K线（已同步的部分不再请求）
+func backfillRange(adapter ExchangeAdapter, symbol string, startTime, endTime int64, proxyClient *api.ProxyClient) error {
+	missingRanges, err := database.FindMissingRanges(symbol, startTime, endTime)
+	if err != nil {
+		return fmt.Errorf("查找缺失时间段失败: %w", err)
+	}
+
+	for _, missingRange := range missingRanges {
+		if err := syncTimeRange(adapter, symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
+			return err
+		}
+		if err := database.AddSyncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime); err != nil {
+			logger.Warnf("[%s] 记录同步时间段失败: %v", symbol, err)
+		}
+	}
+	return nil
+}
+
+// saveBackfillProgress 保存回填进度；前端已暂停该币种时不覆盖状态，返回 false
+func saveBackfillProgress(cursor database.BackfillCursor) (bool, error) {
+	backfillMu.Lock()
+	defer backfillMu.Unlock()
+
+	if cursor.Status == database.BackfillRunning {
+		stored, err := database.GetBackfillCursor(cursor.Symbol)
+		if err != nil {
+			return false, err
+		}
+		if stored != nil && stored.Status == database.BackfillPaused {
+			logger.Infof("[%s] 历史回填已暂停", cursor.Symbol)
+			return false, nil
+		}
+	}
+
+	cursor.UpdatedAt = time.Now().UnixMilli()
+	if err := database.SaveBackfillCursor(cursor); err != nil {
+		return false, err
+	}
+	touchBackfillSession(cursor)
+	return true, nil
+}
+
+// PauseBackfill 暂停币种的历史回填（执行中的任务在当前时间段完成后停止）
+func PauseBackfill(symbol string) error {
+	return setBackfillStatus(symbol, database.BackfillPaused)
+}
+
+// ResumeBackfill 恢复币种的历史回填（包括因错误停止的回填），下一轮从保存的进度继续
+func ResumeBackfill(symbol string) error {
+	resetBackfillSession(symbol)
+	return setBackfillStatus(symbol, database.BackfillRunning)
+}
+
+// setBackfillStatus 修改回填状态（还没有回填记录时创建一条，目标时间范围在第一次回填时确定）
+func setBackfillStatus(symbol, status string) error {
+	backfillMu.Lock()
+	defer backfillMu.Unlock()
+
+	cursor := database.BackfillCursor{Symbol: symbol, Direction: database.BackfillDirectionBackward}
+	stored, err := database.GetBackfillCursor(symbol)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		cursor = *stored
+	}
+	if status == database.BackfillRunning {
+		if cursor.Status == database.BackfillCompleted {
+			return nil
+		}
+		cursor.Attempts = 0
+		cursor.LastError = ""
+	}
+	cursor.Status = status
+	cursor.UpdatedAt = time.Now().UnixMilli()
+	if err := database.SaveBackfillCursor(cursor); err != nil {
+		return err
+	}
+	logger.Infof("[%s] 历史回填状态: %s", symbol, status)
+	return nil
+}
+
+// GetBackfillProgress 获取回填进度（symbol 为空时返回所有币种）
+func GetBackfillProgress(symbol string) ([]BackfillProgress, error) {
+	var cursors []database.BackfillCursor
+	if symbol != "" {
+		cursor, err := database.GetBackfillCursor(symbol)
+		if err != nil {
+			return nil, err
+		}
+		if cursor != nil {
+			cursors = append(cursors, *cursor)
+		}
+	} else {
+		var err error
+		if cursors, err = database.GetBackfillCursors(); err != nil {
+			return nil, err
+		}
+	}
+
+	progress := make([]BackfillProgress, 0, len(cursors))
+	for _, cursor := range cursors {
+		progress = append(progress, newBackfillProgress(cursor))
+	}
+	return progress, nil
+}
+
+// newBackfillProgress 计算完成百分比和预计剩余时间（按本次运行以来的回填速度估算）
+func newBackfillProgress(cursor database.BackfillCursor) BackfillProgress {
+	p := BackfillProgress{BackfillCursor: cursor, ETASeconds: -1}
+	total := cursor.TargetEnd - cursor.TargetStart + 1
+	if cursor.TargetEnd == 0 || total <= 0 {
+		return p
+	}
+	if cursor.Status == database.BackfillCompleted || cursor.NextEnd < cursor.TargetStart {
+		p.Percent = 100
+		p.ETASeconds = 0
+		return p
+	}
+
+	remaining := cursor.NextEnd - cursor.TargetStart + 1
+	p.Percent = float64(total-remaining) / float64(total) * 100
+
+	backfillSessions.mu.Lock()
+	session := backfillSessions.sessions[cursor.Symbol]
+	backfillSessions.mu.Unlock()
+	if session != nil && cursor.Status == database.BackfillRunning {
+		done := session.startNextEnd - cursor.NextEnd
+		elapsed := time.Since(session.startedAt)
+		if done > 0 && elapsed > 0 {
+			p.ETASeconds = int64(float64(remaining) / float64(done) * elapsed.Seconds())
+		}
+	}
+	return p
+}
+
+// touchBackfillSession 第一次保存进度时开始统计回填速度
+func touchBackfillSession(cursor database.BackfillCursor) {
+	backfillSessions.mu.Lock()
+	defer backfillSessions.mu.Unlock()
+	if _, ok := backfillSessions.sessions[cursor.Symbol]; !ok {
+		backfillSessions.sessions[cursor.Symbol] = &backfillSession{startedAt: time.Now(), startNextEnd: cursor.NextEnd}
+	}
+}
+
+// resetBackfillSession 重新统计回填速度（开始新一段回填或恢复暂停的回填时）
+func resetBackfillSession(symbol string) {
+	backfillSessions.mu.Lock()
+	defer backfillSessions.mu.Unlock()
+	delete(backfillSessions.sessions, symbol)
+}
@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// binanceFuturesBaseURL Binance U本位合约 API 的官方地址
+const binanceFuturesBaseURL = "https://fapi.binance.com"
+
+// Binance 按请求权重限流（按 IP，每分钟）
+const (
+	binanceWeightLimit   = 2400 // 每分钟权重上限
+	binanceKlinesWeight  = 10   // limit > 1000 时 /fapi/v1/klines 的权重
+	binanceWeightReserve = 0.9  // 已用权重超过上限的 90% 时等待下一分钟，为其他请求留出余量
+)
+
+// binanceFuturesAdapter Binance U本位合约K线接口
+// GET /fapi/v1/klines?symbol=BTCUSDT&interval=1m&startTime=毫秒&endTime=毫秒&limit=1500
+type binanceFuturesAdapter struct {
+	baseURL string // 为空时使用 symbols.json 中配置的地址或官方地址
+
+	mu           sync.Mutex
+	usedWeight   int       // 响应头 X-MBX-USED-WEIGHT-1M 中的已用权重
+	weightMinute int64     // usedWeight 所在的分钟（Unix 分钟）
+	bannedUntil  time.Time // 触发限流（429/418）后，在此之前不再请求
+}
+
+// NewBinanceFuturesAdapter 创建 Binance U本位合约适配器（baseURL 为空时使用配置或官方地址）
+func NewBinanceFuturesAdapter(baseURL string) ExchangeAdapter {
+	return &binanceFuturesAdapter{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Name 交易所名称
+func (b *binanceFuturesAdapter) Name() string { return "binance_futures" }
+
+// MaxPageSize 每次最多返回1500条数据（25小时）
+func (b *binanceFuturesAdapter) MaxPageSize() int { return 1500 }
+
+// TimestampUnit Binance 使用毫秒级时间戳
+func (b *binanceFuturesAdapter) TimestampUnit() TimestampUnit { return TimestampMillis }
+
+// ExchangeSymbol BTC_USDT → BTCUSDT
+func (b *binanceFuturesAdapter) ExchangeSymbol(symbol string) string {
+	return strings.ReplaceAll(exchangePair(symbol), "_", "")
+}
+
+// RateLimit 每分钟 2400 权重，每次K线请求 10 权重
+func (b *binanceFuturesAdapter) RateLimit() RateLimit {
+	return RateLimit{Requests: binanceWeightLimit / binanceKlinesWeight, Window: time.Minute}
+}
+
+// FetchCandles 拉取 [startTime, endTime] 内的1分钟K线
+func (b *binanceFuturesAdapter) FetchCandles(client *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	unit := b.TimestampUnit()
+	url := fmt.Sprintf("%s/fapi/v1/klines?symbol=%s&interval=1m&startTime=%d&endTime=%d&limit=%d",
+		resolveBaseURL(b.baseURL, b.Name(), binanceFuturesBaseURL), b.ExchangeSymbol(symbol),
+		unit.toUnit(startTime), unit.toUnit(endTime), b.MaxPageSize())
+
+	b.waitForWeight(symbol)
+	logger.Debugf("[%s] 请求K线数据: %s", symbol, url)
+
+	rawBody, header, err := client.FetchAPIRawWithHeader(url, nil)
+	b.recordWeight(header)
+	if err != nil {
+		return nil, b.apiError(rawBody, header, err)
+	}
+
+	// 返回格式: [openTime, open, high, low, close, volume, closeTime, quoteVolume, trades, takerBuyBase, takerBuyQuote, ignore]
+	// 时间为毫秒数字，价格和成交量为字符串
+	var candlesticks [][]interface{}
+	if err := json.Unmarshal(rawBody, &candlesticks); err != nil {
+		return nil, badPayload(fmt.Errorf("解析API响应失败: %w", err))
+	}
+
+	klines := make([]database.KLine1m, 0, len(candlesticks))
+	for _, candle := range candlesticks {
+		if len(candle) < 7 {
+			continue
+		}
+		ts, ok := candle[0].(float64)
+		if !ok {
+			continue
+		}
+
+		open, errOpen := parseFloat(candle[1])
+		high, errHigh := parseFloat(candle[2])
+		low, errLow := parseFloat(candle[3])
+		close, errClose := parseFloat(candle[4])
+		volume, errVolume := parseFloat(candle[5])
+		if errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil {
+			logger.Warnf("[%s] 解析价格数据失败: open=%v, high=%v, low=%v, close=%v, volume=%v",
+				symbol, errOpen, errHigh, errLow, errClose, errVolume)
+			continue
+		}
+
+		// 成交额和成交笔数缺失或无法解析时保持为 0
+		var quoteVolume float64
+		var tradeCount int64
+		if len(candle) > 8 {
+			quoteVolume, _ = parseFloat(candle[7])
+			if trades, ok := candle[8].(float64); ok {
+				tradeCount = int64(trades)
+			}
+		}
+
+		openTime := unit.toMillis(int64(ts))
+		klines = append(klines, database.KLine1m{
+			Symbol:      symbol,
+			OpenTime:    openTime,
+			Open:        open,
+			High:        high,
+			Low:         low,
+			Close:       close,
+			Volume:      volume,
+			QuoteVolume: quoteVolume,
+			TradeCount:  tradeCount,
+			CloseTime:   openTime + 60000 - 1,
+		})
+	}
+	return klines, nil
+}
+
+// waitForWeight 请求前检查权重：被限流时等到解除，本分钟已用权重接近上限时等到下一分钟
+func (b *binanceFuturesAdapter) waitForWeight(symbol string) {
+	b.mu.Lock()
+	now := time.Now()
+	var wait time.Duration
+	var reason string
+	if now.Before(b.bannedUntil) {
+		wait, reason = b.bannedUntil.Sub(now), "请求被限流"
+	} else if now.Unix()/60 == b.weightMinute &&
+		float64(b.usedWeight+binanceKlinesWeight) > binanceWeightLimit*binanceWeightReserve {
+		wait, reason = now.Truncate(time.Minute).Add(time.Minute).Sub(now), fmt.Sprintf("本分钟已用权重 %d", b.usedWeight)
+	}
+	b.mu.Unlock()
+
+	if wait > 0 {
+		logger.Warnf("[%s] Binance %s，等待 %v", symbol, reason, wait.Round(time.Millisecond))
+		time.Sleep(wait)
+	}
+}
+
+// recordWeight 记录响应头中的已用权重
+func (b *binanceFuturesAdapter) recordWeight(header http.Header) {
+	if header == nil {
+		return
+	}
+	used, err := strconv.Atoi(header.Get("X-MBX-USED-WEIGHT-1M"))
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	b.usedWeight = used
+	b.weightMinute = time.Now().Unix() / 60
+	b.mu.Unlock()
+}
+
+// apiError 转换接口错误：429（超过权重上限）和 418（多次超限被封禁）按 Retry-After 暂停后续请求并标记为限流，
+// 其他错误附上 Binance 返回的 code 和 msg（-1121 交易对不存在标记为交易对无效）
+func (b *binanceFuturesAdapter) apiError(body []byte, header http.Header, err error) error {
+	if header != nil {
+		if seconds, convErr := strconv.Atoi(header.Get("Retry-After")); convErr == nil && seconds > 0 {
+			b.mu.Lock()
+			b.bannedUntil = time.Now().Add(time.Duration(seconds) * time.Second)
+			b.mu.Unlock()
+			return rateLimited(fmt.Errorf("API请求被限流，%d 秒后重试: %w", seconds, err), time.Duration(seconds)*time.Second)
+		}
+	}
+
+	var apiErr struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if len(body) > 0 && json.Unmarshal(body, &apiErr) == nil && apiErr.Msg != "" {
+		wrapped := fmt.Errorf("API请求失败: %s (code=%d): %w", apiErr.Msg, apiErr.Code, err)
+		if apiErr.Code == -1121 {
+			return invalidPair(wrapped)
+		}
+		return wrapped
+	}
+	return fmt.Errorf("API请求失败: %w", err)
+}
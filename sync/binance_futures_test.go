@@ -0,0 +1,146 @@
+package sync
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBinanceFuturesFetchCandles(t *testing.T) {
+	server := newReplayServer(t, func(r *http.Request) recordedResponse {
+		return recordedResponse{
+			Header: map[string]string{"X-MBX-USED-WEIGHT-1M": "120"},
+			File:   "binance_futures/klines.json",
+		}
+	})
+	adapter := NewBinanceFuturesAdapter(server.URL).(*binanceFuturesAdapter)
+
+	startTime, endTime := int64(1699999980000), int64(1700000159999)
+	minuteBefore := time.Now().Unix() / 60
+	klines, err := adapter.FetchCandles(newTestClient(t), "BTC_USDT_PERP", startTime, endTime)
+	if err != nil {
+		t.Fatalf("FetchCandles 返回错误: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("请求次数 = %d，期望 1", len(requests))
+	}
+	query := requests[0].Query()
+	if requests[0].Path != "/fapi/v1/klines" || query.Get("symbol") != "BTCUSDT" || query.Get("interval") != "1m" ||
+		query.Get("startTime") != "1699999980000" || query.Get("endTime") != "1700000159999" || query.Get("limit") != "1500" {
+		t.Errorf("请求地址不正确: %s", requests[0])
+	}
+
+	if len(klines) != 3 {
+		t.Fatalf("K线数量 = %d，期望 3", len(klines))
+	}
+	first := klines[0]
+	if first.Symbol != "BTC_USDT_PERP" || first.OpenTime != 1699999980000 || first.CloseTime != 1700000039999 {
+		t.Errorf("第一根K线的币种或时间不正确: %+v", first)
+	}
+	if first.Open != 35000.10 || first.High != 35010.00 || first.Low != 34990.50 || first.Close != 35005.20 {
+		t.Errorf("第一根K线的价格不正确: %+v", first)
+	}
+	if first.Volume != 12.345 || first.QuoteVolume != 432100.12345 || first.TradeCount != 321 {
+		t.Errorf("第一根K线的成交量、成交额或成交笔数不正确: %+v", first)
+	}
+
+	// 响应头中的已用权重
+	adapter.mu.Lock()
+	usedWeight, weightMinute := adapter.usedWeight, adapter.weightMinute
+	adapter.mu.Unlock()
+	if usedWeight != 120 || weightMinute < minuteBefore || weightMinute > time.Now().Unix()/60 {
+		t.Errorf("已用权重 = %d（分钟 %d），期望 120（当前分钟）", usedWeight, weightMinute)
+	}
+}
+
+func TestBinanceFuturesWeightWait(t *testing.T) {
+	adapter := NewBinanceFuturesAdapter("http://127.0.0.1").(*binanceFuturesAdapter)
+
+	// 上一分钟的已用权重不影响本分钟的请求
+	adapter.usedWeight = binanceWeightLimit
+	adapter.weightMinute = time.Now().Unix()/60 - 1
+	start := time.Now()
+	adapter.waitForWeight("BTC_USDT_PERP")
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("上一分钟的权重不应等待，实际等待 %v", elapsed)
+	}
+
+	// 本分钟的已用权重没有超过上限的 90% 时不等待
+	adapter.recordWeight(http.Header{"X-Mbx-Used-Weight-1m": []string{"2000"}})
+	start = time.Now()
+	adapter.waitForWeight("BTC_USDT_PERP")
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("权重未接近上限时不应等待，实际等待 %v", elapsed)
+	}
+	if adapter.usedWeight != 2000 {
+		t.Errorf("已用权重 = %d，期望 2000", adapter.usedWeight)
+	}
+
+	// 无法解析的响应头不覆盖已记录的权重
+	adapter.recordWeight(http.Header{"X-Mbx-Used-Weight-1m": []string{"abc"}})
+	adapter.recordWeight(nil)
+	if adapter.usedWeight != 2000 {
+		t.Errorf("已用权重 = %d，期望保持 2000", adapter.usedWeight)
+	}
+}
+
+func TestBinanceFuturesRateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		file       string
+		retryAfter time.Duration
+	}{
+		{name: "超过权重上限", status: http.StatusTooManyRequests, file: "binance_futures/too_many_requests.json", retryAfter: 30 * time.Second},
+		{name: "IP 被封禁", status: 418, file: "binance_futures/banned.json", retryAfter: 120 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newReplayServer(t, func(r *http.Request) recordedResponse {
+				return recordedResponse{
+					Status: tt.status,
+					Header: map[string]string{"Retry-After": strconv.Itoa(int(tt.retryAfter / time.Second))},
+					File:   tt.file,
+				}
+			})
+			adapter := NewBinanceFuturesAdapter(server.URL).(*binanceFuturesAdapter)
+
+			_, err := adapter.FetchCandles(newTestClient(t), "BTC_USDT_PERP", 1699999980000, 1700000159999)
+			if kind := ErrorKindOf(err); kind != ErrRateLimited {
+				t.Fatalf("错误类型 = %q，期望 %q（err=%v）", kind, ErrRateLimited, err)
+			}
+			if retryAfter := retryAfterOf(err); retryAfter != tt.retryAfter {
+				t.Errorf("Retry-After = %v，期望 %v", retryAfter, tt.retryAfter)
+			}
+			if wait := retryWait(1, err); wait < tt.retryAfter {
+				t.Errorf("重试等待 %v，不应少于 Retry-After %v", wait, tt.retryAfter)
+			}
+
+			// 限流解除前不再请求
+			adapter.mu.Lock()
+			bannedFor := time.Until(adapter.bannedUntil)
+			adapter.mu.Unlock()
+			if bannedFor <= tt.retryAfter-5*time.Second || bannedFor > tt.retryAfter {
+				t.Errorf("暂停请求 %v，期望约 %v", bannedFor, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestBinanceFuturesInvalidSymbol(t *testing.T) {
+	server := newReplayServer(t, func(r *http.Request) recordedResponse {
+		return recordedResponse{Status: http.StatusBadRequest, File: "binance_futures/invalid_symbol.json"}
+	})
+	adapter := NewBinanceFuturesAdapter(server.URL)
+
+	_, err := adapter.FetchCandles(newTestClient(t), "NOPE_USDT_PERP", 1699999980000, 1700000159999)
+	if kind := ErrorKindOf(err); kind != ErrInvalidPair {
+		t.Fatalf("错误类型 = %q，期望 %q（err=%v）", kind, ErrInvalidPair, err)
+	}
+	if IsRetryable(err) {
+		t.Errorf("交易对无效的错误不应重试")
+	}
+}
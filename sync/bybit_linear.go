@@ -0,0 +1,135 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// bybitBaseURL Bybit API 的官方地址
+const bybitBaseURL = "https://api.bybit.com"
+
+// bybitLinearAdapter Bybit USDT 永续合约K线接口
+// GET /v5/market/kline?category=linear&symbol=BTCUSDT&interval=1&start=毫秒&end=毫秒&limit=1000
+// 返回 [start, end] 内最新的 limit 根K线，从新到旧排列
+type bybitLinearAdapter struct {
+	baseURL string // 为空时使用 symbols.json 中配置的地址或官方地址
+}
+
+// NewBybitLinearAdapter 创建 Bybit USDT 永续合约适配器（baseURL 为空时使用配置或官方地址）
+func NewBybitLinearAdapter(baseURL string) ExchangeAdapter {
+	return &bybitLinearAdapter{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Name 交易所名称
+func (b *bybitLinearAdapter) Name() string { return "bybit_linear" }
+
+// MaxPageSize 每次最多返回1000条数据
+func (b *bybitLinearAdapter) MaxPageSize() int { return 1000 }
+
+// TimestampUnit Bybit 使用毫秒级时间戳
+func (b *bybitLinearAdapter) TimestampUnit() TimestampUnit { return TimestampMillis }
+
+// ExchangeSymbol BTC_USDT → BTCUSDT
+func (b *bybitLinearAdapter) ExchangeSymbol(symbol string) string {
+	return strings.ReplaceAll(exchangePair(symbol), "_", "")
+}
+
+// RateLimit 公共接口每5秒600次（按 IP）
+func (b *bybitLinearAdapter) RateLimit() RateLimit {
+	return RateLimit{Requests: 600, Window: 5 * time.Second}
+}
+
+// FetchCandles 拉取 [startTime, endTime] 内的1分钟K线（从 endTime 向前倒序分页）
+func (b *bybitLinearAdapter) FetchCandles(client *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	unit := b.TimestampUnit()
+	baseURL := resolveBaseURL(b.baseURL, b.Name(), bybitBaseURL)
+
+	pages := 0
+	return fetchBackward(startTime, endTime, func(pageEnd int64) ([]database.KLine1m, error) {
+		// 第一页的令牌已由 FetchRange 取得
+		if pages++; pages > 1 {
+			waitForRequest(b)
+		}
+		url := fmt.Sprintf("%s/v5/market/kline?category=linear&symbol=%s&interval=1&start=%d&end=%d&limit=%d",
+			baseURL, b.ExchangeSymbol(symbol), unit.toUnit(startTime), unit.toUnit(pageEnd), b.MaxPageSize())
+		logger.Debugf("[%s] 请求K线数据: %s", symbol, url)
+		return b.fetchPage(client, symbol, url)
+	})
+}
+
+// fetchPage 请求并解析一页K线
+func (b *bybitLinearAdapter) fetchPage(client *api.ProxyClient, symbol, url string) ([]database.KLine1m, error) {
+	rawBody, header, err := client.FetchAPIRawWithHeader(url, nil)
+	if err != nil {
+		// 超过频率限制时返回 403，X-Bapi-Limit-Reset-Timestamp 为限制解除的时间（毫秒）
+		if reset, convErr := strconv.ParseInt(header.Get("X-Bapi-Limit-Reset-Timestamp"), 10, 64); convErr == nil && reset > 0 {
+			return nil, rateLimited(fmt.Errorf("API请求被限流，%s 后解除: %w", time.UnixMilli(reset).Format("15:04:05"), err), time.Until(time.UnixMilli(reset)))
+		}
+		return nil, fmt.Errorf("API请求失败: %w", err)
+	}
+
+	// 返回格式: {"retCode":0,"retMsg":"OK","result":{"list":[[startTime, open, high, low, close, volume, turnover], ...]}}
+	// volume 为币的数量，turnover 为计价币的成交额；数值均为字符串
+	var resp struct {
+		RetCode int    `json:"retCode"`
+		RetMsg  string `json:"retMsg"`
+		Result  struct {
+			List [][]string `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(rawBody, &resp); err != nil {
+		return nil, badPayload(fmt.Errorf("解析API响应失败: %w", err))
+	}
+	if resp.RetCode != 0 {
+		apiErr := fmt.Errorf("API请求失败: %s (retCode=%d)", resp.RetMsg, resp.RetCode)
+		switch {
+		case resp.RetCode == 10006: // 请求频率太高
+			return nil, rateLimited(apiErr, 0)
+		case resp.RetCode == 10001 && strings.Contains(strings.ToLower(resp.RetMsg), "symbol"): // 参数错误（交易对不存在）
+			return nil, invalidPair(apiErr)
+		}
+		return nil, apiErr
+	}
+
+	klines := make([]database.KLine1m, 0, len(resp.Result.List))
+	for _, candle := range resp.Result.List {
+		if len(candle) < 6 {
+			continue
+		}
+		ts, errTs := strconv.ParseInt(candle[0], 10, 64)
+		open, errOpen := strconv.ParseFloat(candle[1], 64)
+		high, errHigh := strconv.ParseFloat(candle[2], 64)
+		low, errLow := strconv.ParseFloat(candle[3], 64)
+		close, errClose := strconv.ParseFloat(candle[4], 64)
+		volume, errVolume := strconv.ParseFloat(candle[5], 64)
+		if errTs != nil || errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil {
+			logger.Warnf("[%s] 解析K线数据失败: %v", symbol, candle)
+			continue
+		}
+		var quoteVolume float64
+		if len(candle) > 6 {
+			quoteVolume, _ = strconv.ParseFloat(candle[6], 64) // turnover（缺失时为 0）
+		}
+
+		openTime := b.TimestampUnit().toMillis(ts)
+		klines = append(klines, database.KLine1m{
+			Symbol:      symbol,
+			OpenTime:    openTime,
+			Open:        open,
+			High:        high,
+			Low:         low,
+			Close:       close,
+			Volume:      volume,
+			QuoteVolume: quoteVolume,
+			CloseTime:   openTime + 60000 - 1,
+		})
+	}
+	return klines, nil
+}
@@ -0,0 +1,104 @@
+package sync
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBybitLinearFetchCandles(t *testing.T) {
+	server := newReplayServer(t, func(r *http.Request) recordedResponse {
+		switch r.URL.Query().Get("end") {
+		case "1700000279999":
+			return recordedResponse{File: "bybit_linear/page1.json"}
+		case "1700000099999":
+			return recordedResponse{File: "bybit_linear/page2.json"}
+		}
+		t.Errorf("意外的请求: %s", r.URL)
+		return recordedResponse{Status: http.StatusNotFound, File: "bybit_linear/invalid_symbol.json"}
+	})
+	adapter := NewBybitLinearAdapter(server.URL)
+
+	klines, err := adapter.FetchCandles(newTestClient(t), "BTC_USDT_PERP", fixtureStart, fixtureEnd)
+	if err != nil {
+		t.Fatalf("FetchCandles 返回错误: %v", err)
+	}
+
+	// 倒序分页：下一页的 end 为上一页最早一根K线的前一毫秒，start 固定为开始时间
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("请求次数 = %d，期望 2", len(requests))
+	}
+	for i, end := range []string{"1700000279999", "1700000099999"} {
+		query := requests[i].Query()
+		if requests[i].Path != "/v5/market/kline" || query.Get("category") != "linear" || query.Get("symbol") != "BTCUSDT" ||
+			query.Get("interval") != "1" || query.Get("start") != "1699999980000" || query.Get("end") != end {
+			t.Errorf("第 %d 页请求地址不正确: %s", i+1, requests[i])
+		}
+	}
+
+	// 接口从新到旧返回，结果按开盘时间升序
+	if len(klines) != 5 {
+		t.Fatalf("K线数量 = %d，期望 5", len(klines))
+	}
+	for i, k := range klines {
+		if want := fixtureStart + int64(i)*60*1000; k.OpenTime != want || k.CloseTime != want+60000-1 {
+			t.Errorf("第 %d 根K线的时间 = %d，期望 %d", i, k.OpenTime, want)
+		}
+	}
+
+	k := klines[1]
+	if k.Open != 35000.1 || k.High != 35010 || k.Low != 34990.5 || k.Close != 35005.2 {
+		t.Errorf("价格解析不正确: %+v", k)
+	}
+	if k.Volume != 0.123 || k.QuoteVolume != 4321.0012 {
+		t.Errorf("成交量或成交额解析不正确: %+v", k)
+	}
+	if klines[2].QuoteVolume != 432100.12345678 {
+		t.Errorf("成交额 = %v，期望 432100.12345678", klines[2].QuoteVolume)
+	}
+}
+
+func TestBybitLinearRateLimited(t *testing.T) {
+	reset := time.Now().Add(3 * time.Second)
+	server := newReplayServer(t, func(r *http.Request) recordedResponse {
+		return recordedResponse{
+			Status: http.StatusForbidden,
+			Header: map[string]string{"X-Bapi-Limit-Reset-Timestamp": strconv.FormatInt(reset.UnixMilli(), 10)},
+			File:   "bybit_linear/forbidden.txt",
+		}
+	})
+	adapter := NewBybitLinearAdapter(server.URL)
+
+	_, err := adapter.FetchCandles(newTestClient(t), "BTC_USDT_PERP", fixtureStart, fixtureEnd)
+	if kind := ErrorKindOf(err); kind != ErrRateLimited {
+		t.Fatalf("错误类型 = %q，期望 %q（err=%v）", kind, ErrRateLimited, err)
+	}
+	// 等待到限制解除的时间
+	if retryAfter := retryAfterOf(err); retryAfter <= 0 || retryAfter > 3*time.Second {
+		t.Errorf("等待时间 = %v，期望在 (0, 3s] 内", retryAfter)
+	}
+}
+
+func TestBybitLinearErrors(t *testing.T) {
+	t.Run("没有限流响应头的 403", func(t *testing.T) {
+		server := newReplayServer(t, func(r *http.Request) recordedResponse {
+			return recordedResponse{Status: http.StatusForbidden, File: "bybit_linear/forbidden.txt"}
+		})
+		_, err := NewBybitLinearAdapter(server.URL).FetchCandles(newTestClient(t), "BTC_USDT_PERP", fixtureStart, fixtureEnd)
+		if kind := ErrorKindOf(err); kind != ErrUnknown {
+			t.Errorf("错误类型 = %q，期望 %q（err=%v）", kind, ErrUnknown, err)
+		}
+	})
+
+	t.Run("交易对不存在", func(t *testing.T) {
+		server := newReplayServer(t, func(r *http.Request) recordedResponse {
+			return recordedResponse{File: "bybit_linear/invalid_symbol.json"}
+		})
+		_, err := NewBybitLinearAdapter(server.URL).FetchCandles(newTestClient(t), "NOPE_USDT_PERP", fixtureStart, fixtureEnd)
+		if kind := ErrorKindOf(err); kind != ErrInvalidPair {
+			t.Errorf("错误类型 = %q，期望 %q（err=%v）", kind, ErrInvalidPair, err)
+		}
+	})
+}
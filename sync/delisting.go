@@ -0,0 +1,148 @@
+package sync
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// EventSymbolStatus 交易对停止或恢复同步时推送给前端的事件（数据为 database.InactiveSymbol，恢复同步时 status 为 active）
+const EventSymbolStatus = "symbol-status"
+
+// symbolActive 恢复同步时事件中的状态
+const symbolActive = "active"
+
+// gateUntradable Gate.io 交易对接口中暂停交易的 trade_status
+const gateUntradable = "untradable"
+
+// eventEmitter 推送前端事件的函数（由应用启动时设置，未设置时不推送）
+var eventEmitter struct {
+	mu   sync.RWMutex
+	emit func(event string, data ...interface{})
+}
+
+// SetEventEmitter 设置推送前端事件的函数
+func SetEventEmitter(emit func(event string, data ...interface{})) {
+	eventEmitter.mu.Lock()
+	defer eventEmitter.mu.Unlock()
+	eventEmitter.emit = emit
+}
+
+// emitEvent 推送前端事件
+func emitEvent(event string, data ...interface{}) {
+	eventEmitter.mu.RLock()
+	emit := eventEmitter.emit
+	eventEmitter.mu.RUnlock()
+	if emit != nil {
+		emit(event, data...)
+	}
+}
+
+// IsSymbolInactive 交易对是否已停止同步（已下架、暂停交易或已更名）
+func IsSymbolInactive(symbol string) bool {
+	_, ok := database.LookupInactiveSymbol(symbol)
+	return ok
+}
+
+// markInactive 标记交易对停止同步并通知前端（状态相同时不重复标记）
+func markInactive(symbol, status, reason string) {
+	if current, ok := database.LookupInactiveSymbol(symbol); ok && current.Status == status {
+		return
+	}
+	inactive := database.InactiveSymbol{
+		Symbol:     symbol,
+		Status:     status,
+		Reason:     reason,
+		DetectedAt: time.Now().UnixMilli(),
+	}
+	if err := database.MarkSymbolInactive(inactive); err != nil {
+		logger.Errorf("[%s] 保存交易对状态失败: %v", symbol, err)
+		return
+	}
+	logger.Warnf("[%s] ⚠️ 交易对已停止同步（%s）: %s", symbol, status, reason)
+	emitEvent(EventSymbolStatus, inactive)
+}
+
+// ReactivateSymbol 恢复交易对的同步并通知前端，返回之前是否已停止同步
+// 因交易对无效而失败的历史回填同时恢复
+func ReactivateSymbol(symbol string) (bool, error) {
+	reactivated, err := database.ReactivateSymbol(symbol)
+	if err != nil || !reactivated {
+		return reactivated, err
+	}
+	if cursor, err := database.GetBackfillCursor(symbol); err == nil && cursor != nil && cursor.Status == database.BackfillFailed {
+		if err := ResumeBackfill(symbol); err != nil {
+			logger.Warnf("[%s] 恢复历史回填失败: %v", symbol, err)
+		}
+	}
+	logger.Infof("[%s] ✓ 交易对已恢复同步", symbol)
+	emitEvent(EventSymbolStatus, database.InactiveSymbol{
+		Symbol:     symbol,
+		Status:     symbolActive,
+		DetectedAt: time.Now().UnixMilli(),
+	})
+	return true, nil
+}
+
+// handleJobError 同步任务失败时，交易对不存在或已下架的错误标记该交易对停止同步
+func handleJobError(symbol string, err error) {
+	if ErrorKindOf(err) == ErrInvalidPair {
+		markInactive(symbol, database.SymbolDelisted, err.Error())
+	}
+}
+
+// checkTradeStatus 按交易对接口的结果检查配置中使用 Gate.io 现货数据的币种：
+// 列表中没有的标记为已下架，暂停交易的标记为暂停；之前因下架或暂停而停止同步、现在可以交易的恢复同步
+// statuses: 交易对 → trade_status（交易对接口返回的所有交易对）
+func checkTradeStatus(statuses map[string]string) {
+	allSymbols, err := config.GetAllEnabledSymbols()
+	if err != nil || len(statuses) == 0 {
+		return
+	}
+
+	for _, symbolConfig := range allSymbols {
+		symbol := symbolConfig.Symbol
+		if adapter, err := AdapterForSymbol(symbol); err != nil || adapter.Name() != "gateio" {
+			continue
+		}
+
+		status, listed := statuses[symbol]
+		switch {
+		case !listed:
+			markInactive(symbol, database.SymbolDelisted, "交易所的交易对列表中已没有该交易对")
+		case status == gateUntradable:
+			markInactive(symbol, database.SymbolSuspended, fmt.Sprintf("交易所暂停交易（trade_status=%s）", status))
+		default:
+			if current, ok := database.LookupInactiveSymbol(symbol); ok && current.Status != database.SymbolRenamed {
+				if _, err := ReactivateSymbol(symbol); err != nil {
+					logger.Warnf("[%s] 恢复同步失败: %v", symbol, err)
+				}
+			}
+		}
+	}
+}
+
+// ApplySymbolAliases 处理 symbols.json 中 symbol_aliases 配置的更名交易对：
+// 把旧名称的历史数据合并到新名称下，并把旧名称标记为已更名、停止同步（已合并过的跳过）
+func ApplySymbolAliases() error {
+	for from, to := range config.GetSymbolAliases() {
+		if to == "" || from == to {
+			continue
+		}
+		if current, ok := database.LookupInactiveSymbol(from); ok && current.Status == database.SymbolRenamed {
+			continue
+		}
+
+		inserted, err := database.MergeSymbolHistory(from, to)
+		if err != nil {
+			return fmt.Errorf("合并 %s 的历史数据到 %s 失败: %w", from, to, err)
+		}
+		logger.Infof("[%s] ✓ 已更名为 %s，合并 %d 根1分钟K线", from, to, inserted)
+		markInactive(from, database.SymbolRenamed, fmt.Sprintf("已更名为 %s", to))
+	}
+	return nil
+}
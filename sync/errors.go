@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// ErrorKind 同步错误的类型
+type ErrorKind string
+
+const (
+	ErrRateLimited ErrorKind = "rate_limited" // 被交易所限流（可重试，需等待 Retry-After）
+	ErrInvalidPair ErrorKind = "invalid_pair" // 交易对不存在或已下线（重试无意义）
+	ErrNetwork     ErrorKind = "network"      // 网络错误、超时或交易所 5xx（可重试）
+	ErrBadPayload  ErrorKind = "bad_payload"  // 响应格式无法解析
+	ErrQuarantined ErrorKind = "quarantined"  // 拉取成功，但部分K线未通过校验、写入了隔离表（只用于失败时间段）
+	ErrUnknown     ErrorKind = "unknown"      // 其他错误
+)
+
+// 重试参数
+const (
+	defaultRetryAttempts = 4           // 每页请求的默认最多尝试次数
+	retryBaseWait        = time.Second // 第一次重试的等待时间上限（之后每次翻倍）
+	retryMaxWait         = time.Minute // 单次重试等待时间上限
+)
+
+// SyncError 带类型的同步错误
+type SyncError struct {
+	Kind       ErrorKind
+	RetryAfter time.Duration // 交易所要求的等待时间（仅限流错误）
+	Err        error
+}
+
+func (e *SyncError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SyncError) Unwrap() error {
+	return e.Err
+}
+
+// rateLimited 标记为限流错误
+func rateLimited(err error, retryAfter time.Duration) error {
+	return &SyncError{Kind: ErrRateLimited, RetryAfter: retryAfter, Err: err}
+}
+
+// invalidPair 标记为交易对无效错误
+func invalidPair(err error) error {
+	return &SyncError{Kind: ErrInvalidPair, Err: err}
+}
+
+// badPayload 标记为响应格式错误
+func badPayload(err error) error {
+	return &SyncError{Kind: ErrBadPayload, Err: err}
+}
+
+// ErrorKindOf 判断错误的类型
+// 适配器已标记类型的错误直接返回；否则按 HTTP 状态码和底层错误推断
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var syncErr *SyncError
+	if errors.As(err, &syncErr) {
+		return syncErr.Kind
+	}
+
+	var statusErr *api.StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == 418:
+			return ErrRateLimited
+		case statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusRequestTimeout:
+			return ErrNetwork
+		}
+		return ErrUnknown
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) {
+		return ErrNetwork
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return ErrBadPayload
+	}
+	return ErrUnknown
+}
+
+// IsRetryable 错误是否值得立即重试（限流和网络错误）
+func IsRetryable(err error) bool {
+	kind := ErrorKindOf(err)
+	return kind == ErrRateLimited || kind == ErrNetwork
+}
+
+// retryAfterOf 交易所要求的等待时间（适配器标记的时间，或响应头中的 Retry-After 秒数）
+func retryAfterOf(err error) time.Duration {
+	var syncErr *SyncError
+	if errors.As(err, &syncErr) && syncErr.RetryAfter > 0 {
+		return syncErr.RetryAfter
+	}
+	var statusErr *api.StatusError
+	if errors.As(err, &statusErr) && statusErr.Header != nil {
+		if seconds, convErr := strconv.Atoi(statusErr.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// retryWait 第 attempt 次重试前的等待时间
+// 指数退避加全随机抖动（避免多个任务同时重试），不少于交易所要求的 Retry-After
+func retryWait(attempt int, err error) time.Duration {
+	backoff := retryBaseWait << (attempt - 1)
+	if backoff <= 0 || backoff > retryMaxWait {
+		backoff = retryMaxWait
+	}
+	wait := time.Duration(rand.Int63n(int64(backoff)) + 1)
+	if retryAfter := retryAfterOf(err); retryAfter > wait {
+		wait = retryAfter
+	}
+	return wait
+}
+
+// retryAttempts 每页请求的最多尝试次数（sync_config.retry_max_attempts）
+func retryAttempts() int {
+	syncConfig, err := config.GetSyncConfig()
+	if err != nil || syncConfig.RetryMaxAttempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return syncConfig.RetryMaxAttempts
+}
+
+// fetchWithRetry 请求一页K线，限流和网络错误按退避时间重试，其他错误直接返回
+// 每次尝试前都取得交易所的令牌（所有同步任务共享交易所的请求频率）
+func fetchWithRetry(adapter ExchangeAdapter, proxyClient *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	attempts := retryAttempts()
+	for attempt := 1; ; attempt++ {
+		waitForRequest(adapter)
+		candles, err := adapter.FetchCandles(proxyClient, symbol, startTime, endTime)
+		if err == nil || !IsRetryable(err) || attempt >= attempts {
+			if err != nil && attempt > 1 {
+				err = fmt.Errorf("重试 %d 次后仍然失败: %w", attempt-1, err)
+			}
+			return candles, err
+		}
+
+		wait := retryWait(attempt, err)
+		logger.Warnf("[%s] 请求失败（%s）: %v，%v 后第 %d 次重试", symbol, ErrorKindOf(err), err, wait.Round(time.Millisecond), attempt)
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"wails-contract-warn/api"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	var syntaxErr error
+	if err := json.Unmarshal([]byte("<html>"), &struct{}{}); err != nil {
+		syntaxErr = err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{name: "无错误", err: nil, want: ""},
+		{name: "适配器标记的类型", err: fmt.Errorf("binance_futures: %w", invalidPair(errors.New("Invalid symbol."))), want: ErrInvalidPair},
+		{name: "429", err: &api.StatusError{StatusCode: http.StatusTooManyRequests}, want: ErrRateLimited},
+		{name: "418", err: &api.StatusError{StatusCode: 418}, want: ErrRateLimited},
+		{name: "502", err: &api.StatusError{StatusCode: http.StatusBadGateway}, want: ErrNetwork},
+		{name: "408", err: &api.StatusError{StatusCode: http.StatusRequestTimeout}, want: ErrNetwork},
+		{name: "400", err: &api.StatusError{StatusCode: http.StatusBadRequest}, want: ErrUnknown},
+		{name: "连接失败", err: fmt.Errorf("请求失败: %w", &url.Error{Op: "Get", URL: "https://fapi.binance.com", Err: errors.New("connection refused")}), want: ErrNetwork},
+		{name: "响应不是 JSON", err: fmt.Errorf("解析失败: %w", syntaxErr), want: ErrBadPayload},
+		{name: "其他错误", err: errors.New("unexpected"), want: ErrUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorKindOf(tt.err); got != tt.want {
+				t.Errorf("ErrorKindOf(%v) = %q，期望 %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfterOf(t *testing.T) {
+	marked := rateLimited(errors.New("too many requests"), 3*time.Second)
+	if got := retryAfterOf(marked); got != 3*time.Second {
+		t.Errorf("适配器标记的等待时间 = %v，期望 3s", got)
+	}
+
+	fromHeader := fmt.Errorf("API请求失败: %w", &api.StatusError{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"7"}},
+	})
+	if got := retryAfterOf(fromHeader); got != 7*time.Second {
+		t.Errorf("响应头中的等待时间 = %v，期望 7s", got)
+	}
+
+	if got := retryAfterOf(errors.New("network")); got != 0 {
+		t.Errorf("没有 Retry-After 时 = %v，期望 0", got)
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	err := errors.New("network")
+	for attempt := 1; attempt <= 10; attempt++ {
+		limit := retryBaseWait << (attempt - 1)
+		if limit > retryMaxWait {
+			limit = retryMaxWait
+		}
+		if wait := retryWait(attempt, err); wait <= 0 || wait > limit {
+			t.Errorf("第 %d 次重试等待 %v，期望在 (0, %v] 内", attempt, wait, limit)
+		}
+	}
+
+	// 交易所要求的等待时间优先于退避时间
+	limited := rateLimited(errors.New("too many requests"), 90*time.Second)
+	if wait := retryWait(1, limited); wait != 90*time.Second {
+		t.Errorf("限流时等待 %v，期望 90s", wait)
+	}
+}
@@ -1,13 +1,13 @@
 package sync
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"sync"
 	"time"
 
 	"wails-contract-warn/api"
+	"wails-contract-warn/config"
 	"wails-contract-warn/database"
 	"wails-contract-warn/logger"
 )
@@ -262,6 +262,18 @@ func SyncSymbolWithPriority(symbol string, priority bool) error {
 		return fmt.Errorf("查找缺失时间段失败: %w", err)
 	}
 
+	// 创建 API 客户端，使用币种配置的交易所
+	adapter, err := AdapterForSymbol(symbol)
+	if err != nil {
+		return err
+	}
+	proxyClient := api.NewProxyClient()
+
+	// 实时模式：刷新最近几分钟的K线，修正之前保存的未收盘数据
+	if priority {
+		refreshRecentKLines(adapter, symbol, targetEnd, proxyClient)
+	}
+
 	if len(missingRanges) == 0 {
 		logger.Debugf("[%s] ✓ 无缺失数据，跳过同步", symbol)
 		// 即使没有缺失数据，也记录本次同步操作，避免重复检查
@@ -271,18 +283,19 @@ func SyncSymbolWithPriority(symbol string, priority bool) error {
 
 	logger.Infof("[%s] 发现 %d 个缺失时间段，开始同步", symbol, len(missingRanges))
 
-	// 创建 API 客户端
-	proxyClient := api.NewProxyClient()
-
 	// 同步每个缺失的时间段
 	successCount := 0
 	for _, missingRange := range missingRanges {
-		if err := syncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
+		if err := syncTimeRange(adapter, symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
 			logger.Errorf("[%s] 同步时间段失败: %s ~ %s, error=%v",
 				symbol,
 				time.Unix(missingRange.StartTime/1000, 0).Format("2006-01-02 15:04:05"),
 				time.Unix(missingRange.EndTime/1000, 0).Format("2006-01-02 15:04:05"),
 				err)
+			// 交易对无效时其他时间段同样会失败，直接返回
+			if ErrorKindOf(err) == ErrInvalidPair {
+				return err
+			}
 			// 继续处理下一个时间段，不中断
 			continue
 		}
@@ -304,167 +317,176 @@ func SyncSymbolWithPriority(symbol string, priority bool) error {
 	return nil
 }
 
-// syncTimeRange 同步指定时间范围的K线数据（支持分页，确保获取完整数据）
-func syncTimeRange(symbol string, startTime, endTime int64, proxyClient *api.ProxyClient) error {
-	// Gate.io API 使用秒级时间戳
-	from := startTime / 1000
-	to := endTime / 1000
-	
-	// API限制：每次最多返回1000条数据（约16.7小时）
-	// 如果时间范围超过1000分钟，需要分页请求
-	const maxLimit = 1000
-	const maxMinutes = 1000 // 1000分钟 = 约16.7小时
-	
+// FetchRange 通过交易所适配器拉取 [startTime, endTime] 内的1分钟K线（按单页上限分页，确保获取完整数据）
+func FetchRange(adapter ExchangeAdapter, proxyClient *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	pageSize := adapter.MaxPageSize()
+	pageSpan := int64(pageSize) * 60 * 1000 // 一页覆盖的时间跨度
+
 	allKlines := make([]database.KLine1m, 0)
-	currentFrom := from
-	
-	// 分页请求，确保获取所有数据
-	for currentFrom < to {
-		// 计算本次请求的结束时间（不超过1000分钟）
-		currentTo := currentFrom + int64(maxMinutes*60)
-		if currentTo > to {
-			currentTo = to
-		}
-		
-		// 构建 API URL
-		url := fmt.Sprintf("https://api.gateio.ws/api/v4/spot/candlesticks?currency_pair=%s&interval=1m&from=%d&to=%d&limit=%d",
-			symbol, currentFrom, currentTo, maxLimit)
+	currentStart := startTime
 
-		logger.Debugf("[%s] 请求K线数据: %s", symbol, url)
+	// 分页请求，确保获取所有数据
+	for currentStart <= endTime {
+		// 计算本次请求的结束时间（不超过一页）
+		currentEnd := min(currentStart+pageSpan-1, endTime)
 
-		// 调用 API（Gate.io 返回数组格式，使用 FetchAPIRaw 获取原始响应）
-		rawBody, err := proxyClient.FetchAPIRaw(url, nil)
+		// 限流和网络错误按退避时间重试
+		candles, err := fetchWithRetry(adapter, proxyClient, symbol, currentStart, currentEnd)
 		if err != nil {
-			return fmt.Errorf("API请求失败: %w", err)
+			return allKlines, fmt.Errorf("%s: %w", adapter.Name(), err)
 		}
 
-		// 解析响应数据（Gate.io 返回数组格式）
-		// Gate.io API v4 实际返回格式: [timestamp, volume, close, high, low, open, base_volume]
-		// 索引对应: [0: timestamp, 1: volume, 2: close, 3: high, 4: low, 5: open, 6: base_volume]
-		var candlesticks [][]interface{}
-		if err := json.Unmarshal(rawBody, &candlesticks); err != nil {
-			return fmt.Errorf("解析API响应失败: %w", err)
+		// 调试：打印第一条数据（仅第一次）
+		if len(allKlines) == 0 && len(candles) > 0 {
+			logger.Debugf("[%s] 第一条K线数据: %+v", symbol, candles[0])
 		}
+		allKlines = append(allKlines, candles...)
 
-		if len(candlesticks) == 0 {
-			// 如果本次请求没有数据，跳到下一个时间段
-			currentFrom = currentTo + 1
-			continue
-		}
-
-		// 调试：打印第一条数据的原始格式（仅第一次）
-		if len(allKlines) == 0 && len(candlesticks) > 0 {
-			logger.Debugf("[%s] 第一条K线原始数据: %v", symbol, candlesticks[0])
+		// 如果返回的数据少于一页，说明已经获取完这个时间段的数据
+		// 否则从最后一条数据的时间 + 1分钟开始
+		if len(candles) < pageSize {
+			currentStart = currentEnd + 1
+		} else {
+			currentStart = max(candles[len(candles)-1].OpenTime+60*1000, currentStart+60*1000)
 		}
+	}
+	return allKlines, nil
+}
 
-		// 转换为 KLine1m 格式
-		for _, candle := range candlesticks {
-			if len(candle) < 7 {
-				continue
-			}
-
-			// 解析时间戳（秒级）
-			var timestamp int64
-			switch v := candle[0].(type) {
-			case float64:
-				timestamp = int64(v)
-			case string:
-				ts, _ := strconv.ParseInt(v, 10, 64)
-				timestamp = ts
-			default:
-				continue
-			}
-
-			// 解析价格和成交量
-			// Gate.io API v4 实际返回格式: [timestamp, volume, close, high, low, open, base_volume]
-			// 索引: [0: timestamp, 1: volume, 2: close, 3: high, 4: low, 5: open, 6: base_volume]
-			open, errOpen := parseFloat(candle[5]) // open (索引5)
-			high, errHigh := parseFloat(candle[3]) // high (索引3)
-			low, errLow := parseFloat(candle[4])   // low (索引4)
-			close, errClose := parseFloat(candle[2]) // close (索引2)
-			volume, errVolume := parseFloat(candle[1]) // volume (索引1)
-			
-			// 验证解析错误
-			if errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil {
-				logger.Warnf("[%s] 解析价格数据失败: open=%v, high=%v, low=%v, close=%v, volume=%v", 
-					symbol, errOpen, errHigh, errLow, errClose, errVolume)
-				continue
-			}
-			
-			// 验证价格合理性（BTC和ETH不应该低于1000）
-			// 如果价格异常，记录详细日志以便调试
-			if (symbol == "BTC_USDT" || symbol == "ETH_USDT") && (open < 1000 || close < 1000) {
-				// 打印原始数据以便调试
-				logger.Warnf("[%s] ⚠️ 检测到异常价格: open=%.2f, high=%.2f, low=%.2f, close=%.2f", 
-					symbol, open, high, low, close)
-				logger.Warnf("[%s] 原始数据: timestamp=%v, volume=%v, [2]=%v, [3]=%v, [4]=%v, [5]=%v", 
-					symbol, candle[0], candle[1], candle[2], candle[3], candle[4], candle[5])
+// syncTimeRange 同步指定时间范围的K线数据
+// 失败的时间段记录到 sync_failed_ranges 等待之后重新同步（交易对无效的除外），同步成功时清除其中的失败记录。
+// 被隔离的分钟同样记录为失败时间段：调用方会把整个时间段记录为已同步，不记录的话这些分钟永远不会被重新拉取；
+// 它们按失败次数退避重试，交易所修正数据、校验通过后记录随之删除
+func syncTimeRange(adapter ExchangeAdapter, symbol string, startTime, endTime int64, proxyClient *api.ProxyClient) error {
+	quarantined, err := fetchAndSave(adapter, symbol, startTime, endTime, proxyClient)
+	if err != nil {
+		if kind := ErrorKindOf(err); kind != ErrInvalidPair {
+			if recordErr := database.RecordFailedRange(symbol, startTime, endTime, string(kind), err.Error()); recordErr != nil {
+				logger.Warnf("[%s] %v", symbol, recordErr)
 			}
-
-			// 转换为毫秒级时间戳
-			openTime := timestamp * 1000
-			closeTime := openTime + 60000 - 1 // 1分钟K线，close_time = open_time + 60秒 - 1毫秒
-
-			allKlines = append(allKlines, database.KLine1m{
-				Symbol:    symbol,
-				OpenTime:  openTime,
-				Open:      open,
-				High:      high,
-				Low:       low,
-				Close:     close,
-				Volume:    volume,
-				CloseTime: closeTime,
-			})
 		}
+		return err
+	}
 
-		// 更新下一次请求的起始时间（使用最后一条数据的时间 + 1分钟）
-		if len(candlesticks) > 0 {
-			var lastTimestamp int64
-			switch v := candlesticks[len(candlesticks)-1][0].(type) {
-			case float64:
-				lastTimestamp = int64(v)
-			case string:
-				ts, _ := strconv.ParseInt(v, 10, 64)
-				lastTimestamp = ts
-			}
-			// 如果返回的数据少于limit，说明已经获取完这个时间段的数据
-			if len(candlesticks) < maxLimit {
-				currentFrom = currentTo + 1
-			} else {
-				// 否则从最后一条数据的时间 + 1分钟开始
-				currentFrom = lastTimestamp + 60
-			}
-		} else {
-			currentFrom = currentTo + 1
+	// 先记录隔离的时间段（已有记录时累加失败次数），再清除其余部分的失败记录
+	deleted, next := 0, startTime
+	for _, r := range quarantinedRanges(quarantined) {
+		message := fmt.Sprintf("K线未通过校验，已写入隔离表: %s ~ %s",
+			time.UnixMilli(r.StartTime).Format("2006-01-02 15:04"), time.UnixMilli(r.EndTime).Format("2006-01-02 15:04"))
+		if err := database.RecordFailedRange(symbol, r.StartTime, r.EndTime, string(ErrQuarantined), message); err != nil {
+			logger.Warnf("[%s] %v", symbol, err)
 		}
+		if r.StartTime > next {
+			deleted += deleteFailedRanges(symbol, next, r.StartTime-1)
+		}
+		next = r.EndTime + 1
+	}
+	if next <= endTime {
+		deleted += deleteFailedRanges(symbol, next, endTime)
+	}
+	if deleted > 0 {
+		logger.Infof("[%s] ✓ %d 个之前失败的时间段已同步成功", symbol, deleted)
+	}
+	return nil
+}
+
+// deleteFailedRanges 删除完全落在 [startTime, endTime] 内的失败时间段，返回删除的数量（失败时记录日志）
+func deleteFailedRanges(symbol string, startTime, endTime int64) int {
+	deleted, err := database.DeleteFailedRanges(symbol, startTime, endTime)
+	if err != nil {
+		logger.Warnf("[%s] %v", symbol, err)
+	}
+	return deleted
+}
 
-		// 避免API限流，稍作延迟
-		time.Sleep(200 * time.Millisecond)
+// fetchAndSave 拉取指定时间范围的K线数据并保存，返回未通过校验、写入隔离表的K线
+func fetchAndSave(adapter ExchangeAdapter, symbol string, startTime, endTime int64, proxyClient *api.ProxyClient) ([]database.QuarantinedKLine, error) {
+	allKlines, err := FetchRange(adapter, proxyClient, symbol, startTime, endTime)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(allKlines) == 0 {
 		logger.Debugf("[%s] 该时间段无数据", symbol)
-		return nil
+		return nil, nil
 	}
 
-	// 保存到数据库
-	result, err := database.SaveKLine1m(allKlines)
+	// 校验K线（与参考交易所交叉校验、尖刺检测），可疑的K线写入隔离表而不保存
+	allKlines, quarantined := validateKLines(symbol, allKlines, proxyClient)
+	if len(allKlines) == 0 {
+		return quarantined, nil
+	}
+
+	// 保存到数据库（最近几分钟的K线可能是未收盘的数据，使用 upsert 覆盖）
+	result, err := database.SaveKLine1mWithRecentUpsert(allKlines, upsertRecentMinutes())
 	if err != nil {
-		return fmt.Errorf("保存K线数据失败: %w", err)
+		return quarantined, fmt.Errorf("保存K线数据失败: %w", err)
 	}
 
-	logger.Infof("[%s] ✓ 成功拉取 %d 条数据 (时间范围: %s ~ %s, 插入=%d, 跳过=%d, 失败=%d)",
+	logger.Infof("[%s] ✓ 成功拉取 %d 条数据 (时间范围: %s ~ %s, 插入=%d, 更新=%d, 跳过=%d, 失败=%d)",
 		symbol,
 		len(allKlines),
 		time.Unix(startTime/1000, 0).Format("2006-01-02 15:04:05"),
 		time.Unix(endTime/1000, 0).Format("2006-01-02 15:04:05"),
 		result.InsertedCount,
+		result.UpdatedCount,
 		result.SkippedCount,
 		result.ErrorCount)
 
+	return quarantined, nil
+}
+
+// SyncRange 同步指定时间范围的K线并记录为已同步（用于补齐 WebSocket 断线期间缺失的K线）
+func SyncRange(symbol string, startTime, endTime int64) error {
+	adapter, err := AdapterForSymbol(symbol)
+	if err != nil {
+		return err
+	}
+	proxyClient := api.NewProxyClient()
+
+	if err := syncTimeRange(adapter, symbol, startTime, endTime, proxyClient); err != nil {
+		return err
+	}
+	if err := database.AddSyncTimeRange(symbol, startTime, endTime); err != nil {
+		return fmt.Errorf("记录同步时间段失败: %w", err)
+	}
 	return nil
 }
 
+// requestInterval 两次请求之间的最小间隔（交易所频率限制和配置的 request_interval_ms 取较大值）
+func requestInterval(adapter ExchangeAdapter) time.Duration {
+	interval := adapter.RateLimit().Interval()
+	if syncConfig, err := config.GetSyncConfig(); err == nil {
+		if configured := time.Duration(syncConfig.RequestIntervalMs) * time.Millisecond; configured > interval {
+			interval = configured
+		}
+	}
+	return interval
+}
+
+// upsertRecentMinutes 最近N分钟的K线使用 upsert 保存（配置加载失败时使用默认值）
+func upsertRecentMinutes() int {
+	syncConfig, err := config.GetSyncConfig()
+	if err != nil {
+		return config.DefaultUpsertRecentMinutes
+	}
+	return syncConfig.UpsertRecentMinutes
+}
+
+// refreshRecentKLines 重新拉取最近N分钟的K线并覆盖数据库中的旧值
+// 实时同步时保存的最新一根K线往往还未收盘，已记录为已同步的时间段不会再被拉取，需要单独刷新
+func refreshRecentKLines(adapter ExchangeAdapter, symbol string, endTime int64, proxyClient *api.ProxyClient) {
+	minutes := upsertRecentMinutes()
+	if minutes <= 0 {
+		return
+	}
+	startTime := endTime - int64(minutes)*60*1000
+	// 刷新失败不记录为失败时间段（下一次实时同步会再次刷新），被隔离的分钟保留数据库中的旧值
+	if _, err := fetchAndSave(adapter, symbol, startTime, endTime, proxyClient); err != nil {
+		logger.Warnf("[%s] 刷新最近 %d 分钟K线失败: %v", symbol, minutes, err)
+	}
+}
+
 // parseFloat 解析浮点数（支持 string 和 float64）
 func parseFloat(v interface{}) (float64, error) {
 	switch val := v.(type) {
@@ -484,8 +506,8 @@ func SyncSymbol(symbol string) error {
 
 // SyncSymbolHistorical 从指定年份开始同步历史数据
 func SyncSymbolHistorical(symbol string, startYear int) error {
-	// 计算目标时间范围：从指定年份的1月1日到现在
-	startTime := time.Date(startYear, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	// 计算目标时间范围：从指定年份的1月1日（交易对上线更晚时从上线时间）到现在
+	startTime := clampToListing(symbol, time.Date(startYear, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
 	now := time.Now().UnixMilli()
 	tenMinutesAgo := now - int64(10*60*1000) // 10分钟前
 
@@ -502,12 +524,16 @@ func SyncSymbolHistorical(symbol string, startYear int) error {
 
 	logger.Infof("[%s] 发现 %d 个缺失时间段，开始同步历史数据", symbol, len(missingRanges))
 
-	// 创建 API 客户端
+	// 创建 API 客户端，使用币种配置的交易所
+	adapter, err := AdapterForSymbol(symbol)
+	if err != nil {
+		return err
+	}
 	proxyClient := api.NewProxyClient()
 
 	// 同步每个缺失的时间段
 	for _, missingRange := range missingRanges {
-		if err := syncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
+		if err := syncTimeRange(adapter, symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
 			logger.Errorf("[%s] 同步时间段失败: %s ~ %s, error=%v",
 				symbol,
 				time.Unix(missingRange.StartTime/1000, 0).Format("2006-01-02 15:04:05"),
@@ -527,120 +553,6 @@ func SyncSymbolHistorical(symbol string, startYear int) error {
 	return nil
 }
 
-// SyncSymbolHistoricalBackward 从指定年份开始向后同步历史数据
-// startYear: 起始年份
-// batchSize: 每批获取的数据量
-// maxDays: 最多拉取的天数（限制数据量）
-func SyncSymbolHistoricalBackward(symbol string, startYear int, batchSize int, maxDays int) error {
-	if batchSize <= 0 {
-		batchSize = 300 // 默认300条
-	}
-	if maxDays <= 0 {
-		maxDays = 7 // 默认7天
-	}
-
-	now := time.Now().UnixMilli()
-	tenMinutesAgo := now - int64(10*60*1000) // 10分钟前
-
-	// 计算目标时间范围：从指定年份的1月1日到现在，但限制最多 maxDays 天
-	today := time.Now().UTC()
-	todayStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
-	targetStart := todayStart - int64(maxDays*24*60*60*1000) // maxDays 天前
-	startYearTime := time.Date(startYear, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
-	
-	// 如果 maxDays 天前的时间早于起始年份，则从起始年份开始
-	if targetStart < startYearTime {
-		targetStart = startYearTime
-	}
-	
-	targetEnd := tenMinutesAgo
-
-	logger.Infof("[%s] 开始向后同步历史数据: 从 %s 到 %s (最多 %d 天)",
-		symbol,
-		time.Unix(targetStart/1000, 0).Format("2006-01-02 15:04:05"),
-		time.Unix(targetEnd/1000, 0).Format("2006-01-02 15:04:05"),
-		maxDays)
-
-	// 查找缺失的时间段
-	missingRanges, err := database.FindMissingRanges(symbol, targetStart, targetEnd)
-	if err != nil {
-		return fmt.Errorf("查找缺失时间段失败: %w", err)
-	}
-
-	if len(missingRanges) == 0 {
-		logger.Debugf("[%s] ✓ 无缺失数据，跳过同步", symbol)
-		return nil
-	}
-
-	logger.Infof("[%s] 发现 %d 个缺失时间段，开始同步", symbol, len(missingRanges))
-
-	// 创建 API 客户端
-	proxyClient := api.NewProxyClient()
-
-	// 同步每个缺失的时间段（从新到旧）
-	for i := len(missingRanges) - 1; i >= 0; i-- {
-		missingRange := missingRanges[i]
-		
-		// 限制每个时间段的长度，分批处理
-		rangeDuration := missingRange.EndTime - missingRange.StartTime
-		maxRangeDuration := int64(batchSize * 60 * 1000) // batchSize 分钟的数据
-
-		if rangeDuration > maxRangeDuration {
-			// 如果时间段太长，分批处理
-			currentStart := missingRange.StartTime
-			for currentStart < missingRange.EndTime {
-				currentEnd := currentStart + maxRangeDuration
-				if currentEnd > missingRange.EndTime {
-					currentEnd = missingRange.EndTime
-				}
-
-				if err := syncTimeRange(symbol, currentStart, currentEnd, proxyClient); err != nil {
-					logger.Errorf("[%s] 同步时间段失败: %s ~ %s, error=%v",
-						symbol,
-						time.Unix(currentStart/1000, 0).Format("2006-01-02 15:04:05"),
-						time.Unix(currentEnd/1000, 0).Format("2006-01-02 15:04:05"),
-						err)
-					// 继续处理下一个时间段
-					currentStart = currentEnd + 1
-					continue
-				}
-
-				// 记录已同步的时间段
-				if err := database.AddSyncTimeRange(symbol, currentStart, currentEnd); err != nil {
-					logger.Warnf("[%s] 记录同步时间段失败: %v", symbol, err)
-				}
-
-				currentStart = currentEnd + 1
-				
-				// 避免API限流，稍作延迟
-				time.Sleep(200 * time.Millisecond)
-			}
-		} else {
-			// 时间段不长，直接同步
-			if err := syncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
-				logger.Errorf("[%s] 同步时间段失败: %s ~ %s, error=%v",
-					symbol,
-					time.Unix(missingRange.StartTime/1000, 0).Format("2006-01-02 15:04:05"),
-					time.Unix(missingRange.EndTime/1000, 0).Format("2006-01-02 15:04:05"),
-					err)
-				// 继续处理下一个时间段，不中断
-				continue
-			}
-
-			// 记录已同步的时间段
-			if err := database.AddSyncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime); err != nil {
-				logger.Warnf("[%s] 记录同步时间段失败: %v", symbol, err)
-			}
-		}
-
-		// 避免API限流，稍作延迟
-		time.Sleep(200 * time.Millisecond)
-	}
-
-	logger.Infof("[%s] ✓ 向后同步历史数据完成", symbol)
-	return nil
-}
-
 // SyncSymbolInitial 初始同步指定币种的数据（同步最近N天的数据）
 func SyncSymbolInitial(symbol string, days int) error {
 	if days <= 0 {
@@ -653,7 +565,7 @@ func SyncSymbolInitial(symbol string, days int) error {
 	// 计算目标时间范围：从 days 天前到现在
 	today := time.Now().UTC()
 	todayStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
-	targetStart := todayStart - int64(days*24*60*60*1000) // days 天前
+	targetStart := clampToListing(symbol, todayStart-int64(days*24*60*60*1000)) // days 天前（不早于上线时间）
 	targetEnd := tenMinutesAgo
 
 	logger.Infof("[%s] 开始初始同步: 从 %s 到 %s (共 %d 天)",
@@ -675,12 +587,16 @@ func SyncSymbolInitial(symbol string, days int) error {
 
 	logger.Infof("[%s] 发现 %d 个缺失时间段，开始同步", symbol, len(missingRanges))
 
-	// 创建 API 客户端
+	// 创建 API 客户端，使用币种配置的交易所
+	adapter, err := AdapterForSymbol(symbol)
+	if err != nil {
+		return err
+	}
 	proxyClient := api.NewProxyClient()
 
 	// 同步每个缺失的时间段
 	for _, missingRange := range missingRanges {
-		if err := syncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
+		if err := syncTimeRange(adapter, symbol, missingRange.StartTime, missingRange.EndTime, proxyClient); err != nil {
 			logger.Errorf("[%s] 同步时间段失败: %s ~ %s, error=%v",
 				symbol,
 				time.Unix(missingRange.StartTime/1000, 0).Format("2006-01-02 15:04:05"),
@@ -694,9 +610,6 @@ func SyncSymbolInitial(symbol string, days int) error {
 		if err := database.AddSyncTimeRange(symbol, missingRange.StartTime, missingRange.EndTime); err != nil {
 			logger.Warnf("[%s] 记录同步时间段失败: %v", symbol, err)
 		}
-
-		// 避免API限流，稍作延迟
-		time.Sleep(200 * time.Millisecond)
 	}
 
 	logger.Infof("[%s] ✓ 初始同步完成", symbol)
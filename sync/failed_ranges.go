@@ -0,0 +1,64 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// 失败时间段的重试间隔
+const (
+	failedRangeBaseWait = time.Minute   // 第一次失败后重新同步的等待时间（之后每次翻倍）
+	failedRangeMaxWait  = 6 * time.Hour // 重新同步的等待时间上限
+)
+
+// failedRangeWait 失败时间段第 attempts 次失败后，重新同步前的等待时间
+func failedRangeWait(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := failedRangeBaseWait << (attempts - 1)
+	if wait <= 0 || wait > failedRangeMaxWait {
+		wait = failedRangeMaxWait
+	}
+	return wait
+}
+
+// RetryFailedRanges 把到期的失败时间段重新提交给调度器（按失败次数指数退避，最长间隔 failedRangeMaxWait）
+// 返回提交的任务数；币种已不在配置中的记录会被跳过
+func RetryFailedRanges() (int, error) {
+	ranges, err := database.GetFailedRanges("")
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	submitted := 0
+	for _, r := range ranges {
+		if now.Before(time.UnixMilli(r.LastFailedAt).Add(failedRangeWait(r.Attempts))) {
+			continue
+		}
+		if _, err := AdapterForSymbol(r.Symbol); err != nil {
+			logger.Debugf("[%s] 跳过失败时间段的重试: %v", r.Symbol, err)
+			continue
+		}
+
+		symbol, startTime, endTime := r.Symbol, r.StartTime, r.EndTime
+		if Submit(Job{
+			Symbol:   symbol,
+			Name:     fmt.Sprintf("failed_range_%d_%d", startTime, endTime),
+			Priority: PriorityGapFill,
+			Run: func() error {
+				return SyncRange(symbol, startTime, endTime)
+			},
+		}) {
+			submitted++
+		}
+	}
+	if submitted > 0 {
+		logger.Infof("重新同步 %d 个失败的时间段", submitted)
+	}
+	return submitted, nil
+}
@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// gateBaseURL Gate.io API v4 的官方地址
+const gateBaseURL = "https://api.gateio.ws/api/v4"
+
+// gateAdapter Gate.io 现货K线接口
+// GET /spot/candlesticks?currency_pair=BTC_USDT&interval=1m&from=秒&to=秒
+type gateAdapter struct {
+	baseURL string // 为空时使用 symbols.json 中配置的地址或官方地址
+}
+
+// NewGateAdapter 创建 Gate.io 现货适配器（baseURL 为空时使用配置或官方地址）
+func NewGateAdapter(baseURL string) ExchangeAdapter {
+	return &gateAdapter{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Name 交易所名称
+func (g *gateAdapter) Name() string { return "gateio" }
+
+// MaxPageSize 每次最多返回1000条数据（约16.7小时）
+func (g *gateAdapter) MaxPageSize() int { return 1000 }
+
+// TimestampUnit Gate.io 使用秒级时间戳
+func (g *gateAdapter) TimestampUnit() TimestampUnit { return TimestampSeconds }
+
+// ExchangeSymbol 本地交易对格式与 Gate.io 一致（BTC_USDT）
+func (g *gateAdapter) ExchangeSymbol(symbol string) string { return exchangePair(symbol) }
+
+// RateLimit 现货公共接口每10秒200次（按 IP）
+func (g *gateAdapter) RateLimit() RateLimit {
+	return RateLimit{Requests: 200, Window: 10 * time.Second}
+}
+
+// FetchCandles 拉取 [startTime, endTime] 内的1分钟K线
+func (g *gateAdapter) FetchCandles(client *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	unit := g.TimestampUnit()
+	url := fmt.Sprintf("%s/spot/candlesticks?currency_pair=%s&interval=1m&from=%d&to=%d&limit=%d",
+		resolveBaseURL(g.baseURL, g.Name(), gateBaseURL), g.ExchangeSymbol(symbol), unit.toUnit(startTime), unit.toUnit(endTime), g.MaxPageSize())
+
+	logger.Debugf("[%s] 请求K线数据: %s", symbol, url)
+
+	// 调用 API（Gate.io 返回数组格式，使用原始响应解析）
+	rawBody, _, err := client.FetchAPIRawWithHeader(url, nil)
+	if err != nil {
+		return nil, gateAPIError(rawBody, err)
+	}
+
+	// Gate.io API v4 实际返回格式: [timestamp, quote_volume, close, high, low, open, base_volume]
+	// 索引对应: [0: timestamp, 1: quote_volume（成交额）, 2: close, 3: high, 4: low, 5: open, 6: base_volume（成交量）]
+	var candlesticks [][]interface{}
+	if err := json.Unmarshal(rawBody, &candlesticks); err != nil {
+		return nil, badPayload(fmt.Errorf("解析API响应失败: %w", err))
+	}
+
+	klines := make([]database.KLine1m, 0, len(candlesticks))
+	for _, candle := range candlesticks {
+		if len(candle) < 7 {
+			continue
+		}
+
+		// 解析时间戳（秒级）
+		var timestamp int64
+		switch v := candle[0].(type) {
+		case float64:
+			timestamp = int64(v)
+		case string:
+			ts, _ := strconv.ParseInt(v, 10, 64)
+			timestamp = ts
+		default:
+			continue
+		}
+
+		open, errOpen := parseFloat(candle[5])         // open (索引5)
+		high, errHigh := parseFloat(candle[3])         // high (索引3)
+		low, errLow := parseFloat(candle[4])           // low (索引4)
+		close, errClose := parseFloat(candle[2])       // close (索引2)
+		quoteVolume, errQuote := parseFloat(candle[1]) // quote_volume (索引1)
+		volume, errVolume := parseFloat(candle[6])     // base_volume (索引6)
+
+		// 验证解析错误
+		if errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil || errQuote != nil {
+			logger.Warnf("[%s] 解析价格数据失败: open=%v, high=%v, low=%v, close=%v, volume=%v, quote_volume=%v",
+				symbol, errOpen, errHigh, errLow, errClose, errVolume, errQuote)
+			continue
+		}
+
+		openTime := unit.toMillis(timestamp)
+		klines = append(klines, database.KLine1m{
+			Symbol:      symbol,
+			OpenTime:    openTime,
+			Open:        open,
+			High:        high,
+			Low:         low,
+			Close:       close,
+			Volume:      volume,
+			QuoteVolume: quoteVolume,
+			CloseTime:   openTime + 60000 - 1, // 1分钟K线，close_time = open_time + 60秒 - 1毫秒
+		})
+	}
+	return klines, nil
+}
+
+// gateAPIError 转换 Gate.io 的错误响应: {"label":"INVALID_CURRENCY_PAIR","message":"..."}
+// 交易对或合约不存在标记为交易对无效，TOO_MANY_REQUESTS 标记为限流
+func gateAPIError(body []byte, err error) error {
+	var apiErr struct {
+		Label   string `json:"label"`
+		Message string `json:"message"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &apiErr) != nil || apiErr.Label == "" {
+		return fmt.Errorf("API请求失败: %w", err)
+	}
+
+	wrapped := fmt.Errorf("API请求失败: %s %s: %w", apiErr.Label, apiErr.Message, err)
+	switch apiErr.Label {
+	case "INVALID_CURRENCY_PAIR", "CONTRACT_NOT_FOUND":
+		return invalidPair(wrapped)
+	case "TOO_MANY_REQUESTS":
+		return rateLimited(wrapped, retryAfterOf(err))
+	}
+	return wrapped
+}
@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// gateFuturesAdapter Gate.io USDT 永续合约K线接口
+// GET /futures/usdt/candlesticks?contract=BTC_USDT&interval=1m&from=秒&to=秒
+// 与现货接口共用 symbols.json 中 gateio 的 base_url
+type gateFuturesAdapter struct {
+	baseURL string // 为空时使用 symbols.json 中配置的地址或官方地址
+
+	mu          sync.Mutex
+	multipliers map[string]float64 // 合约乘数缓存（key: 合约名称，一张合约对应的币的数量）
+}
+
+// NewGateFuturesAdapter 创建 Gate.io USDT 永续合约适配器（baseURL 为空时使用配置或官方地址）
+func NewGateFuturesAdapter(baseURL string) ExchangeAdapter {
+	return &gateFuturesAdapter{baseURL: strings.TrimRight(baseURL, "/"), multipliers: make(map[string]float64)}
+}
+
+// Name 交易所名称
+func (g *gateFuturesAdapter) Name() string { return "gateio_futures" }
+
+// MaxPageSize 指定 from/to 时最多返回2000条数据
+func (g *gateFuturesAdapter) MaxPageSize() int { return 2000 }
+
+// TimestampUnit Gate.io 使用秒级时间戳
+func (g *gateFuturesAdapter) TimestampUnit() TimestampUnit { return TimestampSeconds }
+
+// ExchangeSymbol 合约名称与现货交易对格式一致（BTC_USDT_PERP → BTC_USDT）
+func (g *gateFuturesAdapter) ExchangeSymbol(symbol string) string { return exchangePair(symbol) }
+
+// RateLimit 合约公共接口每10秒200次（按 IP）
+func (g *gateFuturesAdapter) RateLimit() RateLimit {
+	return RateLimit{Requests: 200, Window: 10 * time.Second}
+}
+
+// FetchCandles 拉取 [startTime, endTime] 内的1分钟K线
+func (g *gateFuturesAdapter) FetchCandles(client *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	unit := g.TimestampUnit()
+	multiplier, err := g.quantoMultiplier(client, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 合约接口指定 from/to 时不能同时指定 limit
+	url := fmt.Sprintf("%s/futures/usdt/candlesticks?contract=%s&interval=1m&from=%d&to=%d",
+		resolveBaseURL(g.baseURL, "gateio", gateBaseURL), g.ExchangeSymbol(symbol), unit.toUnit(startTime), unit.toUnit(endTime))
+
+	logger.Debugf("[%s] 请求K线数据: %s", symbol, url)
+
+	rawBody, _, err := client.FetchAPIRawWithHeader(url, nil)
+	if err != nil {
+		// 错误响应: {"label":"CONTRACT_NOT_FOUND","message":"..."}
+		return nil, gateAPIError(rawBody, err)
+	}
+
+	// 返回格式: [{"t": 1539852480, "v": 97151, "c": "1.032", "h": "1.032", "l": "1.032", "o": "1.032", "sum": "3580"}, ...]
+	// v 为合约张数（乘以合约乘数换算为币的数量），sum 为计价币（USDT）的成交额，与现货接口的成交额（索引1）含义一致
+	var candlesticks []struct {
+		T   int64       `json:"t"`
+		V   json.Number `json:"v"`
+		C   string      `json:"c"`
+		H   string      `json:"h"`
+		L   string      `json:"l"`
+		O   string      `json:"o"`
+		Sum string      `json:"sum"`
+	}
+	if err := json.Unmarshal(rawBody, &candlesticks); err != nil {
+		return nil, badPayload(fmt.Errorf("解析API响应失败: %w", err))
+	}
+
+	klines := make([]database.KLine1m, 0, len(candlesticks))
+	for _, candle := range candlesticks {
+		open, errOpen := strconv.ParseFloat(candle.O, 64)
+		high, errHigh := strconv.ParseFloat(candle.H, 64)
+		low, errLow := strconv.ParseFloat(candle.L, 64)
+		close, errClose := strconv.ParseFloat(candle.C, 64)
+		contracts, errVolume := candle.V.Float64()
+		quoteVolume, errQuote := strconv.ParseFloat(candle.Sum, 64)
+		if errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil || errQuote != nil {
+			logger.Warnf("[%s] 解析K线数据失败: %+v", symbol, candle)
+			continue
+		}
+
+		openTime := unit.toMillis(candle.T)
+		klines = append(klines, database.KLine1m{
+			Symbol:      symbol,
+			OpenTime:    openTime,
+			Open:        open,
+			High:        high,
+			Low:         low,
+			Close:       close,
+			Volume:      contracts * multiplier,
+			QuoteVolume: quoteVolume,
+			CloseTime:   openTime + 60000 - 1,
+		})
+	}
+	return klines, nil
+}
+
+// quantoMultiplier 合约乘数（一张合约对应的币的数量），第一次使用时从合约详情接口获取并缓存（请求同样受限流控制）
+// GET /futures/usdt/contracts/BTC_USDT → {"name": "BTC_USDT", "quanto_multiplier": "0.0001", ...}
+func (g *gateFuturesAdapter) quantoMultiplier(client *api.ProxyClient, symbol string) (float64, error) {
+	contract := g.ExchangeSymbol(symbol)
+	g.mu.Lock()
+	multiplier, ok := g.multipliers[contract]
+	g.mu.Unlock()
+	if ok {
+		return multiplier, nil
+	}
+
+	url := fmt.Sprintf("%s/futures/usdt/contracts/%s", resolveBaseURL(g.baseURL, "gateio", gateBaseURL), contract)
+	// 合约详情请求与K线请求共用交易所的令牌桶（K线请求的令牌已由调用方取得）
+	waitForRequest(g)
+	rawBody, _, err := client.FetchAPIRawWithHeader(url, nil)
+	if err != nil {
+		return 0, gateAPIError(rawBody, err)
+	}
+	var detail struct {
+		QuantoMultiplier string `json:"quanto_multiplier"`
+	}
+	if err := json.Unmarshal(rawBody, &detail); err != nil {
+		return 0, badPayload(fmt.Errorf("解析合约详情失败: %w", err))
+	}
+	multiplier, err = strconv.ParseFloat(detail.QuantoMultiplier, 64)
+	if err != nil || multiplier <= 0 {
+		return 0, badPayload(fmt.Errorf("合约乘数无效: %q", detail.QuantoMultiplier))
+	}
+
+	g.mu.Lock()
+	g.multipliers[contract] = multiplier
+	g.mu.Unlock()
+	return multiplier, nil
+}
@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// okxBaseURL OKX API 的官方地址
+const okxBaseURL = "https://www.okx.com"
+
+// okxSwapAdapter OKX 永续合约历史K线接口
+// GET /api/v5/market/history-candles?instId=BTC-USDT-SWAP&bar=1m&after=毫秒&before=毫秒&limit=100
+// 数据从新到旧返回：after 返回早于该时间的K线，before 返回晚于该时间的K线（均不含边界）
+type okxSwapAdapter struct {
+	baseURL string // 为空时使用 symbols.json 中配置的地址或官方地址
+}
+
+// NewOKXSwapAdapter 创建 OKX 永续合约适配器（baseURL 为空时使用配置或官方地址）
+func NewOKXSwapAdapter(baseURL string) ExchangeAdapter {
+	return &okxSwapAdapter{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Name 交易所名称
+func (o *okxSwapAdapter) Name() string { return "okx_swap" }
+
+// MaxPageSize 每次最多返回100条数据
+func (o *okxSwapAdapter) MaxPageSize() int { return 100 }
+
+// TimestampUnit OKX 使用毫秒级时间戳
+func (o *okxSwapAdapter) TimestampUnit() TimestampUnit { return TimestampMillis }
+
+// ExchangeSymbol BTC_USDT → BTC-USDT-SWAP
+func (o *okxSwapAdapter) ExchangeSymbol(symbol string) string {
+	return strings.ReplaceAll(exchangePair(symbol), "_", "-") + "-SWAP"
+}
+
+// RateLimit 历史K线接口每2秒20次
+func (o *okxSwapAdapter) RateLimit() RateLimit {
+	return RateLimit{Requests: 20, Window: 2 * time.Second}
+}
+
+// FetchCandles 拉取 [startTime, endTime] 内的1分钟K线（从 endTime 向前倒序分页）
+func (o *okxSwapAdapter) FetchCandles(client *api.ProxyClient, symbol string, startTime, endTime int64) ([]database.KLine1m, error) {
+	unit := o.TimestampUnit()
+	baseURL := resolveBaseURL(o.baseURL, o.Name(), okxBaseURL)
+
+	pages := 0
+	return fetchBackward(startTime, endTime, func(pageEnd int64) ([]database.KLine1m, error) {
+		// 第一页的令牌已由 FetchRange 取得
+		if pages++; pages > 1 {
+			waitForRequest(o)
+		}
+		url := fmt.Sprintf("%s/api/v5/market/history-candles?instId=%s&bar=1m&after=%d&before=%d&limit=%d",
+			baseURL, o.ExchangeSymbol(symbol), unit.toUnit(pageEnd+1), unit.toUnit(startTime-1), o.MaxPageSize())
+		logger.Debugf("[%s] 请求K线数据: %s", symbol, url)
+		return o.fetchPage(client, symbol, url)
+	})
+}
+
+// fetchPage 请求并解析一页K线
+func (o *okxSwapAdapter) fetchPage(client *api.ProxyClient, symbol, url string) ([]database.KLine1m, error) {
+	rawBody, _, err := client.FetchAPIRawWithHeader(url, nil)
+
+	// 返回格式: {"code":"0","msg":"","data":[[ts, o, h, l, c, vol, volCcy, volCcyQuote, confirm], ...]}
+	// vol 为合约张数，volCcy 为币的数量，volCcyQuote 为计价币的成交额，confirm 为 1 表示已收盘；数值均为字符串
+	var resp struct {
+		Code string     `json:"code"`
+		Msg  string     `json:"msg"`
+		Data [][]string `json:"data"`
+	}
+	if len(rawBody) > 0 {
+		if jsonErr := json.Unmarshal(rawBody, &resp); jsonErr != nil && err == nil {
+			return nil, badPayload(fmt.Errorf("解析API响应失败: %w", jsonErr))
+		}
+	}
+	if resp.Code != "" && resp.Code != "0" {
+		apiErr := fmt.Errorf("API请求失败: %s (code=%s)", resp.Msg, resp.Code)
+		switch resp.Code {
+		case "51001": // 产品ID不存在
+			return nil, invalidPair(apiErr)
+		case "50011": // 请求频率太高
+			return nil, rateLimited(apiErr, retryAfterOf(err))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", apiErr, err)
+		}
+		return nil, apiErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("API请求失败: %w", err)
+	}
+
+	klines := make([]database.KLine1m, 0, len(resp.Data))
+	for _, candle := range resp.Data {
+		if len(candle) < 7 {
+			continue
+		}
+		// confirm 为 0 表示K线还未收盘，不保存（收盘后再次同步时获取最终数据）
+		if len(candle) > 8 && candle[8] == "0" {
+			continue
+		}
+		ts, errTs := strconv.ParseInt(candle[0], 10, 64)
+		open, errOpen := strconv.ParseFloat(candle[1], 64)
+		high, errHigh := strconv.ParseFloat(candle[2], 64)
+		low, errLow := strconv.ParseFloat(candle[3], 64)
+		close, errClose := strconv.ParseFloat(candle[4], 64)
+		volume, errVolume := strconv.ParseFloat(candle[6], 64) // volCcy（币的数量）
+		if errTs != nil || errOpen != nil || errHigh != nil || errLow != nil || errClose != nil || errVolume != nil {
+			logger.Warnf("[%s] 解析K线数据失败: %v", symbol, candle)
+			continue
+		}
+		var quoteVolume float64
+		if len(candle) > 7 {
+			quoteVolume, _ = strconv.ParseFloat(candle[7], 64) // volCcyQuote（缺失时为 0）
+		}
+
+		openTime := o.TimestampUnit().toMillis(ts)
+		klines = append(klines, database.KLine1m{
+			Symbol:      symbol,
+			OpenTime:    openTime,
+			Open:        open,
+			High:        high,
+			Low:         low,
+			Close:       close,
+			Volume:      volume,
+			QuoteVolume: quoteVolume,
+			CloseTime:   openTime + 60000 - 1,
+		})
+	}
+	return klines, nil
+}
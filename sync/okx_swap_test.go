@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOKXSwapFetchCandles(t *testing.T) {
+	server := newReplayServer(t, func(r *http.Request) recordedResponse {
+		switch r.URL.Query().Get("after") {
+		case "1700000280000":
+			return recordedResponse{File: "okx_swap/page1.json"}
+		case "1700000100000":
+			return recordedResponse{File: "okx_swap/page2.json"}
+		}
+		return recordedResponse{File: "okx_swap/empty.json"}
+	})
+	adapter := NewOKXSwapAdapter(server.URL)
+
+	klines, err := adapter.FetchCandles(newTestClient(t), "BTC_USDT_PERP", fixtureStart, fixtureEnd)
+	if err != nil {
+		t.Fatalf("FetchCandles 返回错误: %v", err)
+	}
+
+	// 倒序分页：after 为上一页最早一根K线的开盘时间，before 固定为开始时间的前一毫秒
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("请求次数 = %d，期望 2", len(requests))
+	}
+	for i, after := range []string{"1700000280000", "1700000100000"} {
+		query := requests[i].Query()
+		if requests[i].Path != "/api/v5/market/history-candles" || query.Get("instId") != "BTC-USDT-SWAP" ||
+			query.Get("after") != after || query.Get("before") != "1699999979999" || query.Get("bar") != "1m" {
+			t.Errorf("第 %d 页请求地址不正确: %s", i+1, requests[i])
+		}
+	}
+
+	// 未收盘的K线（confirm=0）不保存，其余按开盘时间升序返回
+	wantTimes := []int64{1699999980000, 1700000040000, 1700000100000, 1700000160000}
+	if len(klines) != len(wantTimes) {
+		t.Fatalf("K线数量 = %d，期望 %d: %+v", len(klines), len(wantTimes), klines)
+	}
+	for i, k := range klines {
+		if k.OpenTime != wantTimes[i] || k.CloseTime != wantTimes[i]+60000-1 || k.Symbol != "BTC_USDT_PERP" {
+			t.Errorf("第 %d 根K线的时间或币种不正确: %+v", i, k)
+		}
+	}
+
+	// 价格和成交量为十进制字符串；成交量取 volCcy（币的数量）而不是 vol（合约张数）
+	k := klines[1]
+	if k.Open != 35000.1 || k.High != 35010 || k.Low != 34990.5 || k.Close != 35005.2 {
+		t.Errorf("价格解析不正确: %+v", k)
+	}
+	if k.Volume != 0.12345678 || k.QuoteVolume != 4321.0012 {
+		t.Errorf("成交量或成交额解析不正确: %+v", k)
+	}
+	if klines[2].QuoteVolume != 432100.12345678 {
+		t.Errorf("成交额 = %v，期望 432100.12345678", klines[2].QuoteVolume)
+	}
+}
+
+func TestOKXSwapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		file   string
+		want   ErrorKind
+	}{
+		{name: "产品ID不存在", status: http.StatusOK, file: "okx_swap/instrument_not_found.json", want: ErrInvalidPair},
+		{name: "请求频率太高", status: http.StatusTooManyRequests, file: "okx_swap/too_many_requests.json", want: ErrRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newReplayServer(t, func(r *http.Request) recordedResponse {
+				return recordedResponse{Status: tt.status, File: tt.file}
+			})
+			adapter := NewOKXSwapAdapter(server.URL)
+
+			_, err := adapter.FetchCandles(newTestClient(t), "BTC_USDT_PERP", fixtureStart, fixtureEnd)
+			if kind := ErrorKindOf(err); kind != tt.want {
+				t.Errorf("错误类型 = %q，期望 %q（err=%v）", kind, tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶限流器
+// 并发的请求按到达顺序预约令牌，令牌不足时等待到预约的令牌补充为止
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64 // 桶容量（允许的突发请求数）
+	tokens float64 // 当前令牌数（为负数表示已被预约的令牌）
+	last   time.Time
+}
+
+// newTokenBucket 按请求间隔创建令牌桶（容量为1秒内允许的请求数，至少为1）
+func newTokenBucket(interval time.Duration) *tokenBucket {
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	burst := float64(time.Second / interval)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(time.Second) / float64(interval),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait 取得一个令牌，令牌不足时阻塞等待
+func (b *tokenBucket) wait() {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// exchangeLimiters 每个交易所一个令牌桶（全局单例，所有同步任务共享）
+var exchangeLimiters = struct {
+	mu       sync.Mutex
+	limiters map[string]*tokenBucket
+}{
+	limiters: make(map[string]*tokenBucket),
+}
+
+// waitForRequest 请求交易所接口前取得该交易所的令牌
+// 速率为交易所频率限制和配置的 request_interval_ms 中较慢的一个
+func waitForRequest(adapter ExchangeAdapter) {
+	exchangeLimiters.mu.Lock()
+	limiter, ok := exchangeLimiters.limiters[adapter.Name()]
+	if !ok {
+		limiter = newTokenBucket(requestInterval(adapter))
+		exchangeLimiters.limiters[adapter.Name()] = limiter
+	}
+	exchangeLimiters.mu.Unlock()
+
+	limiter.wait()
+}
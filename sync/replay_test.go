@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"wails-contract-warn/api"
+)
+
+// 倒序分页接口（OKX、Bybit）录制的响应覆盖的时间范围：1699999980000 起的5分钟
+const (
+	fixtureStart = int64(1699999980000)
+	fixtureEnd   = fixtureStart + 5*60*1000 - 1
+)
+
+// recordedResponse 录制的交易所响应（响应体保存在 testdata 下）
+type recordedResponse struct {
+	Status int               // 状态码（0 表示 200）
+	Header map[string]string // 响应头
+	File   string            // testdata 下的响应体文件
+}
+
+// replayServer 在本地回放录制响应的 HTTP 服务，记录收到的请求供断言
+type replayServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*url.URL
+}
+
+// newReplayServer 启动回放服务，route 根据请求选择录制的响应
+func newReplayServer(t *testing.T, route func(r *http.Request) recordedResponse) *replayServer {
+	t.Helper()
+	s := &replayServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL)
+		s.mu.Unlock()
+
+		resp := route(r)
+		body, err := os.ReadFile(filepath.Join("testdata", resp.File))
+		if err != nil {
+			t.Errorf("读取录制的响应失败: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for k, v := range resp.Header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != 0 {
+			w.WriteHeader(resp.Status)
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests 收到的请求（按到达顺序）
+func (s *replayServer) Requests() []*url.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*url.URL(nil), s.requests...)
+}
+
+// newTestClient 不读取配置文件的代理客户端
+func newTestClient(t *testing.T) *api.ProxyClient {
+	t.Helper()
+	client, err := api.NewProxyClientWithOptions(api.ClientOptions{Timeout: 5 * time.Second, DialTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	return client
+}
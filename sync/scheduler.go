@@ -0,0 +1,377 @@
+package sync
+
+import (
+	"fmt"
+	"sync"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/logger"
+)
+
+// JobPriority 同步任务的优先级（数值越小优先级越高）
+type JobPriority int
+
+const (
+	PriorityRealtime   JobPriority = iota // 实时数据（最近几分钟的K线）
+	PriorityGapFill                       // 空缺补充
+	PriorityHistorical                    // 历史数据回填
+	numPriorities
+)
+
+// String 优先级名称
+func (p JobPriority) String() string {
+	switch p {
+	case PriorityRealtime:
+		return "realtime"
+	case PriorityGapFill:
+		return "gap_fill"
+	case PriorityHistorical:
+		return "historical"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// priorityWeights 每轮调度中各优先级的调度次数：高优先级优先，但低优先级的任务不会被一直饿死
+var priorityWeights = [numPriorities]int{8, 3, 1}
+
+// Job 同步任务
+type Job struct {
+	Symbol   string       // 交易对（同一交易对同一执行通道的任务不会并发执行，见 lane）
+	Name     string       // 任务名称（同一交易对同名的任务排队或执行中时不重复提交）
+	Priority JobPriority  // 优先级
+	Run      func() error // 任务内容
+
+	done func() // 任务结束（执行完成或调度器停止时被丢弃）后调用
+}
+
+// key 去重使用的任务标识
+func (j Job) key() string {
+	return j.Symbol + "/" + j.Name
+}
+
+// lane 任务的执行通道：同一交易对同一通道的任务串行执行
+// 实时任务单独一个通道，不会被同一交易对长时间执行的历史回填或空缺补充任务阻塞（它们写入的时间范围不同，存储层按币种加锁）
+func (j Job) lane() string {
+	if j.Priority == PriorityRealtime {
+		return j.Symbol + "/realtime"
+	}
+	return j.Symbol
+}
+
+// fairQueue 同一优先级的任务队列，按交易对轮流出队，避免某个交易对的大量任务占满 worker
+type fairQueue struct {
+	order []string         // 有排队任务的交易对（轮转顺序）
+	jobs  map[string][]Job // key: symbol
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{jobs: make(map[string][]Job)}
+}
+
+// push 任务入队
+func (q *fairQueue) push(job Job) {
+	if len(q.jobs[job.Symbol]) == 0 {
+		q.order = append(q.order, job.Symbol)
+	}
+	q.jobs[job.Symbol] = append(q.jobs[job.Symbol], job)
+}
+
+// pop 取出轮转顺序中第一个执行通道空闲的交易对的任务，该交易对还有任务时移到队尾
+func (q *fairQueue) pop(busy map[string]bool) (Job, bool) {
+	for i, symbol := range q.order {
+		jobs := q.jobs[symbol]
+		job := jobs[0]
+		if busy[job.lane()] {
+			continue
+		}
+		q.order = append(q.order[:i:i], q.order[i+1:]...)
+		if len(jobs) > 1 {
+			q.jobs[symbol] = jobs[1:]
+			q.order = append(q.order, symbol)
+		} else {
+			delete(q.jobs, symbol)
+		}
+		return job, true
+	}
+	return Job{}, false
+}
+
+// len 排队中的任务数
+func (q *fairQueue) len() int {
+	n := 0
+	for _, jobs := range q.jobs {
+		n += len(jobs)
+	}
+	return n
+}
+
+// drain 取出所有排队的任务
+func (q *fairQueue) drain() []Job {
+	var all []Job
+	for _, symbol := range q.order {
+		all = append(all, q.jobs[symbol]...)
+	}
+	q.order = nil
+	q.jobs = make(map[string][]Job)
+	return all
+}
+
+// SchedulerStats 调度器状态
+type SchedulerStats struct {
+	Workers   int            `json:"workers"`
+	Running   int            `json:"running"`   // 执行中的任务数
+	Queued    map[string]int `json:"queued"`    // 各优先级排队中的任务数
+	Completed int64          `json:"completed"` // 已完成的任务数
+	Failed    int64          `json:"failed"`    // 失败的任务数
+}
+
+// Scheduler 同步任务调度器
+// 固定数量的 worker 按优先级（实时 > 空缺补充 > 历史回填）加权轮流执行任务，同一优先级内按交易对轮转；
+// 交易所接口的请求频率由每个交易所的令牌桶统一控制
+type Scheduler struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	workers    int
+	running    bool
+	queues     [numPriorities]*fairQueue
+	credits    [numPriorities]int // 本轮剩余的调度次数
+	pending    map[string]bool    // 排队或执行中的任务（key: Job.key）
+	busy       map[string]bool    // 有任务在执行的通道（key: Job.lane）
+	lowRunning int                // 执行中的非实时任务数
+	completed  int64
+	failed     int64
+}
+
+// NewScheduler 创建调度器（workers 为并发执行的任务数）
+func NewScheduler(workers int) *Scheduler {
+	if workers <= 0 {
+		workers = 1
+	}
+	s := &Scheduler{
+		workers: workers,
+		credits: priorityWeights,
+		pending: make(map[string]bool),
+		busy:    make(map[string]bool),
+	}
+	for i := range s.queues {
+		s.queues[i] = newFairQueue()
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Start 启动 worker
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	for i := 0; i < s.workers; i++ {
+		go s.worker()
+	}
+	logger.Infof("启动同步任务调度器，并发数: %d", s.workers)
+}
+
+// Stop 停止调度器（排队中的任务被丢弃，执行中的任务继续执行完）
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	var dropped []Job
+	for _, queue := range s.queues {
+		dropped = append(dropped, queue.drain()...)
+	}
+	for _, job := range dropped {
+		delete(s.pending, job.key())
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	for _, job := range dropped {
+		if job.done != nil {
+			job.done()
+		}
+	}
+	logger.Infof("同步任务调度器已停止，丢弃 %d 个排队中的任务", len(dropped))
+}
+
+// Submit 提交任务（同一交易对同名的任务已在排队或执行中、交易对已停止同步、或调度器未运行时返回 false）
+func (s *Scheduler) Submit(job Job) bool {
+	if job.Priority < 0 || job.Priority >= numPriorities {
+		job.Priority = PriorityHistorical
+	}
+	if IsSymbolInactive(job.Symbol) {
+		logger.Debugf("[%s] 交易对已停止同步，跳过任务 %s", job.Symbol, job.Name)
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running || s.pending[job.key()] {
+		return false
+	}
+	s.pending[job.key()] = true
+	s.queues[job.Priority].push(job)
+	s.cond.Signal()
+	return true
+}
+
+// Stats 调度器状态
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := SchedulerStats{
+		Workers:   s.workers,
+		Running:   len(s.busy),
+		Queued:    make(map[string]int, numPriorities),
+		Completed: s.completed,
+		Failed:    s.failed,
+	}
+	for p, queue := range s.queues {
+		stats.Queued[JobPriority(p).String()] = queue.len()
+	}
+	return stats
+}
+
+// worker 循环取出任务并执行
+func (s *Scheduler) worker() {
+	for {
+		s.mu.Lock()
+		job, ok := s.nextLocked()
+		for s.running && !ok {
+			s.cond.Wait()
+			job, ok = s.nextLocked()
+		}
+		if !s.running {
+			s.mu.Unlock()
+			return
+		}
+		s.busy[job.lane()] = true
+		if job.Priority != PriorityRealtime {
+			s.lowRunning++
+		}
+		s.mu.Unlock()
+
+		err := s.run(job)
+
+		s.mu.Lock()
+		delete(s.busy, job.lane())
+		delete(s.pending, job.key())
+		if job.Priority != PriorityRealtime {
+			s.lowRunning--
+		}
+		if err != nil {
+			s.failed++
+		} else {
+			s.completed++
+		}
+		// 该通道的其他任务现在可以执行了
+		s.cond.Broadcast()
+		s.mu.Unlock()
+
+		if job.done != nil {
+			job.done()
+		}
+	}
+}
+
+// run 执行任务（任务 panic 时记录错误，不影响 worker；交易对不存在或已下架时标记该交易对停止同步）
+func (s *Scheduler) run(job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			logger.Errorf("[%s] 同步任务 %s 异常: %v", job.Symbol, job.Name, r)
+		}
+	}()
+
+	if err = job.Run(); err != nil {
+		logger.Errorf("[%s] 同步任务 %s 失败: %v", job.Symbol, job.Name, err)
+		handleJobError(job.Symbol, err)
+	}
+	return err
+}
+
+// nextLocked 按优先级权重选出下一个可执行的任务（需持有锁）
+// 每个优先级每轮最多调度 priorityWeights 次，有任务的优先级都用完本轮次数后开始新一轮；
+// 非实时任务最多占用 workers-1 个 worker，保证实时任务总有空闲的 worker
+func (s *Scheduler) nextLocked() (Job, bool) {
+	for round := 0; round < 2; round++ {
+		for p, queue := range s.queues {
+			if s.credits[p] <= 0 {
+				continue
+			}
+			if JobPriority(p) != PriorityRealtime && s.workers > 1 && s.lowRunning >= s.workers-1 {
+				continue
+			}
+			if job, ok := queue.pop(s.busy); ok {
+				s.credits[p]--
+				return job, true
+			}
+		}
+		s.credits = priorityWeights
+	}
+	return Job{}, false
+}
+
+// scheduler 全局调度器（第一次提交任务时按 sync_config.max_concurrent_syncs 创建并启动）
+var scheduler struct {
+	mu       sync.Mutex
+	instance *Scheduler
+}
+
+// getScheduler 获取全局调度器
+func getScheduler() *Scheduler {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.instance == nil {
+		workers := 4
+		if syncConfig, err := config.GetSyncConfig(); err == nil {
+			workers = syncConfig.MaxConcurrentSyncs
+		}
+		scheduler.instance = NewScheduler(workers)
+		scheduler.instance.Start()
+	}
+	return scheduler.instance
+}
+
+// Submit 向全局调度器提交同步任务（同一交易对同名的任务已在排队或执行中时返回 false）
+func Submit(job Job) bool {
+	return getScheduler().Submit(job)
+}
+
+// SubmitAndWait 向全局调度器提交一组任务并等待它们执行完成（已在排队或执行中的同名任务不会重复提交，也不等待）
+func SubmitAndWait(jobs ...Job) {
+	s := getScheduler()
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		job.done = wg.Done
+		if !s.Submit(job) {
+			wg.Done()
+		}
+	}
+	wg.Wait()
+}
+
+// GetSchedulerStats 全局调度器的状态
+func GetSchedulerStats() SchedulerStats {
+	return getScheduler().Stats()
+}
+
+// StopScheduler 停止全局调度器（之后提交任务时会重新创建）
+func StopScheduler() {
+	scheduler.mu.Lock()
+	instance := scheduler.instance
+	scheduler.instance = nil
+	scheduler.mu.Unlock()
+
+	if instance != nil {
+		instance.Stop()
+	}
+}
@@ -0,0 +1,342 @@
+package sync
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wails-contract-warn/database"
+)
+
+func TestSchedulerRealtimeNotBlockedByHistorical(t *testing.T) {
+	s := NewScheduler(3)
+	s.Start()
+	t.Cleanup(s.Stop)
+
+	release := make(chan struct{})
+	historicalStarted := make(chan struct{})
+	s.Submit(Job{Symbol: "BTC_USDT", Name: "historical", Priority: PriorityHistorical, Run: func() error {
+		close(historicalStarted)
+		<-release
+		return nil
+	}})
+	<-historicalStarted
+
+	// 同一交易对的历史回填执行中时，实时任务照常执行
+	realtimeDone := make(chan struct{})
+	s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Priority: PriorityRealtime, Run: func() error {
+		close(realtimeDone)
+		return nil
+	}})
+	select {
+	case <-realtimeDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("实时任务被同一交易对的历史回填阻塞")
+	}
+
+	// 同一交易对的非实时任务仍然串行执行
+	gapFillDone := make(chan struct{})
+	s.Submit(Job{Symbol: "BTC_USDT", Name: "gap_fill", Priority: PriorityGapFill, Run: func() error {
+		close(gapFillDone)
+		return nil
+	}})
+	select {
+	case <-gapFillDone:
+		t.Fatal("空缺补充任务不应与同一交易对的历史回填并发执行")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-gapFillDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("历史回填结束后空缺补充任务没有执行")
+	}
+}
+
+// queueJobs 直接放入调度器的队列（不经过 Submit，调度器不需要启动）
+func queueJobs(s *Scheduler, priority JobPriority, symbols ...string) {
+	for i, symbol := range symbols {
+		s.queues[priority].push(Job{Symbol: symbol, Name: strconv.Itoa(i), Priority: priority})
+	}
+}
+
+// dispatchOrder 连续调用 nextLocked 直到没有可执行的任务，返回各任务的优先级（R、G、H）
+// 与 worker 一样统计执行中的非实时任务，但任务不会结束
+func dispatchOrder(s *Scheduler) string {
+	letters := map[JobPriority]string{PriorityRealtime: "R", PriorityGapFill: "G", PriorityHistorical: "H"}
+	var order strings.Builder
+	for {
+		job, ok := s.nextLocked()
+		if !ok {
+			return order.String()
+		}
+		if job.Priority != PriorityRealtime {
+			s.lowRunning++
+		}
+		order.WriteString(letters[job.Priority])
+	}
+}
+
+// symbolsN n 个不同的交易对
+func symbolsN(prefix string, n int) []string {
+	symbols := make([]string, n)
+	for i := range symbols {
+		symbols[i] = prefix + strconv.Itoa(i)
+	}
+	return symbols
+}
+
+func TestSchedulerWeightedDispatch(t *testing.T) {
+	tests := []struct {
+		name                string
+		realtime, gap, hist int
+		want                string
+	}{
+		{
+			name:     "每轮按 8/3/1 调度，低优先级不会被饿死",
+			realtime: 20, gap: 10, hist: 4,
+			want: "RRRRRRRRGGGH" + "RRRRRRRRGGGH" + "RRRRGGGH" + "GH",
+		},
+		{
+			name:     "只有实时任务时不受轮次限制",
+			realtime: 10,
+			want:     "RRRRRRRRRR",
+		},
+		{
+			name: "没有实时任务时低优先级按 3/1 轮流",
+			gap:  7, hist: 3,
+			want: "GGGH" + "GGGH" + "GH",
+		},
+		{
+			name:     "空的优先级不占用轮次",
+			realtime: 12, hist: 3,
+			want: "RRRRRRRRH" + "RRRRH" + "H",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler(1)
+			queueJobs(s, PriorityRealtime, symbolsN("R", tt.realtime)...)
+			queueJobs(s, PriorityGapFill, symbolsN("G", tt.gap)...)
+			queueJobs(s, PriorityHistorical, symbolsN("H", tt.hist)...)
+			if got := dispatchOrder(s); got != tt.want {
+				t.Errorf("调度顺序 = %s\n期望       %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerLowPriorityCap(t *testing.T) {
+	// 非实时任务最多占用 workers-1 个 worker
+	s := NewScheduler(3)
+	queueJobs(s, PriorityHistorical, "A", "B", "C")
+	if got := dispatchOrder(s); got != "HH" {
+		t.Fatalf("3 个 worker 时调度顺序 = %s，期望最多调度 2 个历史回填任务", got)
+	}
+
+	s = NewScheduler(3)
+	s.lowRunning = 2
+	queueJobs(s, PriorityGapFill, "A")
+	queueJobs(s, PriorityHistorical, "B")
+	queueJobs(s, PriorityRealtime, "C")
+	if got := dispatchOrder(s); got != "R" {
+		t.Errorf("非实时任务占满 workers-1 时调度顺序 = %s，期望只调度实时任务", got)
+	}
+	s.lowRunning = 1
+	if got := dispatchOrder(s); got != "G" {
+		t.Errorf("空出一个 worker 后调度顺序 = %s，期望调度一个空缺补充任务", got)
+	}
+
+	// 只有一个 worker 时不限制
+	s = NewScheduler(1)
+	queueJobs(s, PriorityHistorical, "A", "B")
+	if got := dispatchOrder(s); got != "HH" {
+		t.Errorf("单个 worker 时调度顺序 = %s，期望 HH", got)
+	}
+}
+
+func TestFairQueueRotatesSymbols(t *testing.T) {
+	q := newFairQueue()
+	for _, symbol := range []string{"A", "A", "A", "A", "A", "B", "B", "C"} {
+		q.push(Job{Symbol: symbol, Priority: PriorityHistorical})
+	}
+
+	var order strings.Builder
+	for {
+		job, ok := q.pop(nil)
+		if !ok {
+			break
+		}
+		order.WriteString(job.Symbol)
+	}
+	// 同一交易对的大量任务不会排在其他交易对前面
+	if got, want := order.String(), "ABCABAAA"; got != want {
+		t.Errorf("出队顺序 = %s，期望 %s", got, want)
+	}
+	if q.len() != 0 {
+		t.Errorf("出队后还有 %d 个任务", q.len())
+	}
+}
+
+func TestFairQueueSkipsBusyLanes(t *testing.T) {
+	q := newFairQueue()
+	q.push(Job{Symbol: "A", Name: "history", Priority: PriorityHistorical})
+	q.push(Job{Symbol: "B", Name: "history", Priority: PriorityHistorical})
+
+	// A 的非实时通道执行中时先出队 B，A 保持在队首
+	busy := map[string]bool{"A": true}
+	if job, ok := q.pop(busy); !ok || job.Symbol != "B" {
+		t.Fatalf("pop = %+v, %v，期望 B", job, ok)
+	}
+	if _, ok := q.pop(busy); ok {
+		t.Fatal("只剩执行中通道的任务时不应出队")
+	}
+	delete(busy, "A")
+	if job, ok := q.pop(busy); !ok || job.Symbol != "A" {
+		t.Fatalf("通道空闲后 pop = %+v, %v，期望 A", job, ok)
+	}
+
+	// 实时任务使用单独的通道
+	q.push(Job{Symbol: "A", Name: "recent", Priority: PriorityRealtime})
+	if job, ok := q.pop(map[string]bool{"A": true}); !ok || job.Name != "recent" {
+		t.Errorf("历史回填执行中时实时任务 pop = %+v, %v", job, ok)
+	}
+	if _, ok := q.pop(map[string]bool{"A/realtime": true}); ok {
+		t.Error("同一交易对的实时任务不应并发执行")
+	}
+}
+
+func TestSchedulerSubmitDedupe(t *testing.T) {
+	s := NewScheduler(1)
+	noop := func() error { return nil }
+
+	if s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Run: noop}) {
+		t.Fatal("调度器未启动时不应接受任务")
+	}
+
+	s.Start()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	if !s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Priority: PriorityRealtime, Run: func() error {
+		close(started)
+		<-release
+		return nil
+	}, done: func() { close(done) }}) {
+		t.Fatal("提交任务失败")
+	}
+	<-started
+
+	// 执行中的同名任务不重复提交
+	if s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Priority: PriorityRealtime, Run: noop}) {
+		t.Error("执行中的同名任务不应重复提交")
+	}
+	// 排队中的同名任务不重复提交（不同交易对或不同名称的任务不受影响）
+	if !s.Submit(Job{Symbol: "BTC_USDT", Name: "gap_fill", Priority: PriorityGapFill, Run: noop}) {
+		t.Error("不同名称的任务应可以提交")
+	}
+	if s.Submit(Job{Symbol: "BTC_USDT", Name: "gap_fill", Priority: PriorityHistorical, Run: noop}) {
+		t.Error("排队中的同名任务不应重复提交（即使优先级不同）")
+	}
+	if !s.Submit(Job{Symbol: "ETH_USDT", Name: "gap_fill", Priority: PriorityGapFill, Run: noop}) {
+		t.Error("不同交易对的同名任务应可以提交")
+	}
+	if stats := s.Stats(); stats.Running != 1 || stats.Queued["gap_fill"] != 2 {
+		t.Errorf("Stats = %+v，期望 1 个执行中、2 个空缺补充任务排队", stats)
+	}
+
+	close(release)
+	<-done
+	// 执行完成后可以再次提交
+	resubmitted := make(chan struct{})
+	if !s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Priority: PriorityRealtime, Run: noop, done: func() { close(resubmitted) }}) {
+		t.Fatal("执行完成后应可以再次提交")
+	}
+	select {
+	case <-resubmitted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("再次提交的任务没有执行")
+	}
+
+	s.Stop()
+	if s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Run: noop}) {
+		t.Error("调度器停止后不应接受任务")
+	}
+}
+
+func TestSchedulerSubmitSkipsInactiveSymbol(t *testing.T) {
+	previous := database.GetStore()
+	database.SetStore(database.NewMemoryStore())
+	t.Cleanup(func() { database.SetStore(previous) })
+
+	if err := database.MarkSymbolInactive(database.InactiveSymbol{Symbol: "LUNA_USDT", Status: database.SymbolDelisted}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.ReactivateSymbol("LUNA_USDT") })
+
+	s := NewScheduler(1)
+	s.Start()
+	t.Cleanup(s.Stop)
+
+	ran := make(chan struct{}, 1)
+	run := func() error { ran <- struct{}{}; return nil }
+	if s.Submit(Job{Symbol: "LUNA_USDT", Name: "recent", Priority: PriorityRealtime, Run: run}) {
+		t.Error("已停止同步的交易对不应接受任务")
+	}
+	if !s.Submit(Job{Symbol: "BTC_USDT", Name: "recent", Priority: PriorityRealtime, Run: run}) {
+		t.Error("其他交易对应可以提交")
+	}
+	<-ran
+
+	// 恢复同步后可以提交
+	if _, err := database.ReactivateSymbol("LUNA_USDT"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Submit(Job{Symbol: "LUNA_USDT", Name: "recent", Priority: PriorityRealtime, Run: run}) {
+		t.Error("恢复同步后应可以提交")
+	}
+	<-ran
+}
+
+func TestSchedulerStopDropsQueuedJobs(t *testing.T) {
+	s := NewScheduler(1)
+	s.Start()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s.Submit(Job{Symbol: "BTC_USDT", Name: "history", Priority: PriorityHistorical, Run: func() error {
+		close(started)
+		<-release
+		return nil
+	}})
+	<-started
+
+	dropped := make(chan string, 2)
+	var ran atomic.Bool
+	for _, symbol := range []string{"ETH_USDT", "SOL_USDT"} {
+		symbol := symbol
+		s.Submit(Job{Symbol: symbol, Name: "history", Priority: PriorityHistorical,
+			Run:  func() error { ran.Store(true); return nil },
+			done: func() { dropped <- symbol }})
+	}
+
+	// 排队中的任务被丢弃并调用 done，执行中的任务继续执行完
+	s.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-dropped:
+		case <-time.After(2 * time.Second):
+			t.Fatal("丢弃的任务没有调用 done")
+		}
+	}
+	close(release)
+	if ran.Load() {
+		t.Error("调度器停止后不应执行排队中的任务")
+	}
+	if stats := s.Stats(); stats.Queued["historical"] != 0 {
+		t.Errorf("停止后还有 %d 个排队任务", stats.Queued["historical"])
+	}
+}
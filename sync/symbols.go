@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// symbolRegistryMaxAge 交易对元数据的有效期，超过后启动时重新拉取
+const symbolRegistryMaxAge = 24 * time.Hour
+
+// maxSymbolLength 交易对名称的最大长度（与表结构中 symbol 列的长度一致）
+const maxSymbolLength = 20
+
+// gateCurrencyPair Gate.io 现货交易对信息
+// GET /spot/currency_pairs → [{"id": "BTC_USDT", "base": "BTC", "quote": "USDT", "precision": 1, "amount_precision": 6, ...}]
+type gateCurrencyPair struct {
+	ID              string `json:"id"`
+	Base            string `json:"base"`
+	Quote           string `json:"quote"`
+	MinBaseAmount   string `json:"min_base_amount"`
+	MinQuoteAmount  string `json:"min_quote_amount"`
+	AmountPrecision int    `json:"amount_precision"`
+	Precision       int    `json:"precision"`
+	TradeStatus     string `json:"trade_status"`
+	BuyStart        int64  `json:"buy_start"` // 开放买入的时间（秒级时间戳，0 表示未知）
+}
+
+// RefreshSymbolRegistry 从 Gate.io 的交易对接口拉取所有现货交易对的元数据并保存，返回保存的数量
+// 同时检查配置中的币种是否已下架或暂停交易（见 checkTradeStatus）
+func RefreshSymbolRegistry() (int, error) {
+	adapter := NewGateAdapter("")
+	url := fmt.Sprintf("%s/spot/currency_pairs", resolveBaseURL("", adapter.Name(), gateBaseURL))
+
+	waitForRequest(adapter)
+	rawBody, _, err := api.NewProxyClient().FetchAPIRawWithHeader(url, nil)
+	if err != nil {
+		return 0, gateAPIError(rawBody, err)
+	}
+
+	var pairs []gateCurrencyPair
+	if err := json.Unmarshal(rawBody, &pairs); err != nil {
+		return 0, badPayload(fmt.Errorf("解析交易对列表失败: %w", err))
+	}
+
+	now := time.Now().UnixMilli()
+	metas := make([]database.SymbolMeta, 0, len(pairs))
+	statuses := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		symbol := strings.ToUpper(pair.ID)
+		statuses[symbol] = pair.TradeStatus
+		if symbol == "" || len(symbol) > maxSymbolLength {
+			continue
+		}
+		minBase, _ := strconv.ParseFloat(pair.MinBaseAmount, 64)
+		minQuote, _ := strconv.ParseFloat(pair.MinQuoteAmount, 64)
+		metas = append(metas, database.SymbolMeta{
+			Symbol:          symbol,
+			Exchange:        adapter.Name(),
+			Base:            strings.ToUpper(pair.Base),
+			Quote:           strings.ToUpper(pair.Quote),
+			PricePrecision:  pair.Precision,
+			AmountPrecision: pair.AmountPrecision,
+			MinBaseAmount:   minBase,
+			MinQuoteAmount:  minQuote,
+			ListedAt:        pair.BuyStart * 1000,
+			TradeStatus:     pair.TradeStatus,
+			UpdatedAt:       now,
+		})
+	}
+
+	if err := database.SaveSymbolMetadata(metas); err != nil {
+		return 0, fmt.Errorf("保存交易对元数据失败: %w", err)
+	}
+	logger.Infof("✓ 交易对元数据已更新: %d 个交易对", len(metas))
+
+	checkTradeStatus(statuses)
+	return len(metas), nil
+}
+
+// RefreshSymbolRegistryIfStale 已加载的交易对元数据为空或超过有效期时重新拉取
+// 拉取失败时继续使用已缓存的元数据（没有元数据时规范化交易对退回到按计价币种拆分）
+func RefreshSymbolRegistryIfStale() error {
+	if updatedAt := database.SymbolRegistryUpdatedAt(); updatedAt > 0 && time.Since(time.UnixMilli(updatedAt)) < symbolRegistryMaxAge {
+		return nil
+	}
+	_, err := RefreshSymbolRegistry()
+	return err
+}
+
+// clampToListing 把同步的开始时间推迟到交易对的上线时间（整分钟），上线时间未知时原样返回
+// 避免为新上线的币种请求上线之前的数据
+func clampToListing(symbol string, startTime int64) int64 {
+	listedAt := database.SymbolListedAt(symbol)
+	if listedAt <= 0 {
+		return startTime
+	}
+	return max(startTime, time.UnixMilli(listedAt).Truncate(time.Minute).UnixMilli())
+}
@@ -0,0 +1,204 @@
+package sync
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"wails-contract-warn/api"
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+	"wails-contract-warn/logger"
+)
+
+// spikeReference 尖刺检测的比较对象（写入隔离表的 reference 字段）
+const spikeReference = "neighbors"
+
+// validateKLines 保存前校验K线，返回可以保存的K线和写入隔离表的可疑K线
+// 只有第二个数据源（币种配置的参考交易所）同一分钟的K线不一致时才隔离，单个交易所真实的剧烈行情不会被丢弃：
+//  1. 收盘价与参考交易所的偏差超过 max_deviation_bps 时以 cross_exchange 隔离
+//  2. 开盘价或收盘价同时偏离前后各 spike_window 根K线收盘价的中位数超过 spike_bps，且参考交易所同一价格没有相同的变化时以 spike 隔离
+//     （最高价、最低价不参与检测，剧烈波动的分钟出现长影线是正常的）
+//
+// 未配置参考交易所、参考交易所请求失败或缺少该分钟的参考数据时，尖刺只记录日志，K线照常保存；
+// 缺少前面或后面的K线（如最新一根）时不做尖刺检测
+func validateKLines(symbol string, klines []database.KLine1m, proxyClient *api.ProxyClient) ([]database.KLine1m, []database.QuarantinedKLine) {
+	validation, err := config.GetValidationConfig()
+	if err != nil || !validation.Enabled || len(klines) == 0 {
+		return klines, nil
+	}
+
+	referenceName, referenceKLines := fetchReferenceKLines(symbol, klines, proxyClient)
+
+	now := time.Now().UnixMilli()
+	kept := make([]database.KLine1m, 0, len(klines))
+	var quarantined []database.QuarantinedKLine
+	for i, kline := range klines {
+		reference, hasReference := referenceKLines[kline.OpenTime]
+		if hasReference {
+			if deviation := deviationBps(kline.Close, reference.Close); deviation > validation.MaxDeviationBps {
+				quarantined = append(quarantined, quarantineRow(kline, database.QuarantineCrossExchange, referenceName, reference.Close, deviation, now))
+				continue
+			}
+		}
+
+		beforeMedian, afterMedian, ok := neighborMedians(klines, i, validation.SpikeWindow)
+		if !ok {
+			kept = append(kept, kline)
+			continue
+		}
+		spiked := false
+		for _, prices := range [][2]float64{{kline.Open, reference.Open}, {kline.Close, reference.Close}} {
+			median, deviation := spikeDeviation(prices[0], beforeMedian, afterMedian)
+			if deviation <= validation.SpikeBps {
+				continue
+			}
+			if !hasReference {
+				logger.Warnf("[%s] %s 价格突变 %.0fbps（价格=%.8g，前后中位数=%.8g），没有参考数据，保留",
+					symbol, time.UnixMilli(kline.OpenTime).Format("2006-01-02 15:04"), deviation, prices[0], median)
+				break
+			}
+			// 参考交易所同一分钟出现相同的变化，说明是真实行情
+			if deviationBps(prices[0], prices[1]) <= validation.MaxDeviationBps {
+				continue
+			}
+			quarantined = append(quarantined, quarantineRow(kline, database.QuarantineSpike, spikeReference, median, deviation, now))
+			spiked = true
+			break
+		}
+		if !spiked {
+			kept = append(kept, kline)
+		}
+	}
+
+	if len(quarantined) > 0 {
+		for _, row := range quarantined {
+			logger.Warnf("[%s] ⚠️ 隔离可疑K线 %s（%s，参考=%s，参考价=%.8g，收盘价=%.8g，偏差=%.0fbps）",
+				symbol, time.UnixMilli(row.OpenTime).Format("2006-01-02 15:04"),
+				row.Reason, row.Reference, row.ReferencePrice, row.Close, row.DeviationBps)
+		}
+		if err := database.QuarantineKLines(quarantined); err != nil {
+			logger.Errorf("[%s] 写入隔离表失败: %v", symbol, err)
+		}
+	}
+	return kept, quarantined
+}
+
+// quarantinedRanges 隔离的分钟所在的时间段（相邻的分钟合并为一段，按开始时间升序）
+func quarantinedRanges(quarantined []database.QuarantinedKLine) []database.SyncTimeRange {
+	if len(quarantined) == 0 {
+		return nil
+	}
+	openTimes := make([]int64, len(quarantined))
+	for i, row := range quarantined {
+		openTimes[i] = row.OpenTime
+	}
+	sort.Slice(openTimes, func(i, j int) bool { return openTimes[i] < openTimes[j] })
+
+	var ranges []database.SyncTimeRange
+	current := database.SyncTimeRange{StartTime: openTimes[0], EndTime: openTimes[0] + 60*1000 - 1}
+	for _, openTime := range openTimes[1:] {
+		if openTime > current.EndTime+1 {
+			ranges = append(ranges, current)
+			current.StartTime = openTime
+		}
+		current.EndTime = openTime + 60*1000 - 1
+	}
+	return append(ranges, current)
+}
+
+// fetchReferenceKLines 从参考交易所拉取同一时间范围的K线，返回 开盘时间 → K线（未配置或请求失败时返回空）
+func fetchReferenceKLines(symbol string, klines []database.KLine1m, proxyClient *api.ProxyClient) (string, map[int64]database.KLine1m) {
+	adapter, err := ReferenceAdapterForSymbol(symbol)
+	if err != nil {
+		logger.Warnf("[%s] 参考交易所不可用，不隔离K线: %v", symbol, err)
+		return "", nil
+	}
+	if adapter == nil {
+		return "", nil
+	}
+
+	startTime, endTime := klines[0].OpenTime, klines[0].OpenTime
+	for _, kline := range klines {
+		startTime = min(startTime, kline.OpenTime)
+		endTime = max(endTime, kline.OpenTime)
+	}
+
+	reference, err := FetchRange(adapter, proxyClient, symbol, startTime, endTime+60*1000-1)
+	if err != nil {
+		logger.Warnf("[%s] 拉取参考交易所 %s 的K线失败，不隔离K线: %v", symbol, adapter.Name(), err)
+		return "", nil
+	}
+
+	result := make(map[int64]database.KLine1m, len(reference))
+	for _, kline := range reference {
+		if kline.Open > 0 && kline.Close > 0 {
+			result[kline.OpenTime] = kline
+		}
+	}
+	return adapter.Name(), result
+}
+
+// neighborMedians 第 i 根K线前、后各 window 分钟内K线收盘价的中位数（缺少前面或后面的K线时返回 false）
+func neighborMedians(klines []database.KLine1m, i, window int) (float64, float64, bool) {
+	span := int64(window) * 60 * 1000
+	var before, after []float64
+	for j := i - 1; j >= 0 && klines[i].OpenTime-klines[j].OpenTime <= span; j-- {
+		before = append(before, klines[j].Close)
+	}
+	for j := i + 1; j < len(klines) && klines[j].OpenTime-klines[i].OpenTime <= span; j++ {
+		after = append(after, klines[j].Close)
+	}
+	if len(before) == 0 || len(after) == 0 {
+		return 0, 0, false
+	}
+	return median(before), median(after), true
+}
+
+// spikeDeviation 价格相对前后中位数的偏差（基点），取偏离两侧的较小值（持续的行情变化只偏离一侧），同时返回较接近的中位数
+func spikeDeviation(price, beforeMedian, afterMedian float64) (float64, float64) {
+	beforeDeviation, afterDeviation := deviationBps(price, beforeMedian), deviationBps(price, afterMedian)
+	if afterDeviation < beforeDeviation {
+		return afterMedian, afterDeviation
+	}
+	return beforeMedian, beforeDeviation
+}
+
+// deviationBps 价格相对参考价的偏差（基点，参考价无效时返回 0）
+func deviationBps(price, reference float64) float64 {
+	if reference <= 0 {
+		return 0
+	}
+	return math.Abs(price-reference) / reference * 10000
+}
+
+// median 中位数（会对传入的切片排序）
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// quarantineRow 构造隔离表的记录
+func quarantineRow(kline database.KLine1m, reason, reference string, referencePrice, deviation float64, detectedAt int64) database.QuarantinedKLine {
+	return database.QuarantinedKLine{
+		Symbol:         kline.Symbol,
+		OpenTime:       kline.OpenTime,
+		Open:           kline.Open,
+		High:           kline.High,
+		Low:            kline.Low,
+		Close:          kline.Close,
+		Volume:         kline.Volume,
+		QuoteVolume:    kline.QuoteVolume,
+		TradeCount:     kline.TradeCount,
+		CloseTime:      kline.CloseTime,
+		Reason:         reason,
+		Reference:      reference,
+		ReferencePrice: referencePrice,
+		DeviationBps:   deviation,
+		DetectedAt:     detectedAt,
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"testing"
+
+	"wails-contract-warn/database"
+)
+
+// testBase 测试数据的起始时间（按天对齐，2023-11-15 00:00 UTC）
+const testBase = int64(1700006400000)
+
+// useMemoryStore 使用新的内存存储作为当前存储后端，测试结束后恢复
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	previous := database.GetStore()
+	database.SetStore(database.NewMemoryStore())
+	t.Cleanup(func() { database.SetStore(previous) })
+}
+
+// saveMinutes 保存第 from 到 to 分钟（含）的1分钟K线：收盘价为 100 + 分钟序号，最高价 +1，最低价 -1，成交量 1
+func saveMinutes(t *testing.T, symbol string, from, to int) {
+	t.Helper()
+	var klines []database.KLine1m
+	for n := from; n <= to; n++ {
+		openTime := testBase + int64(n)*60000
+		price := 100 + float64(n)
+		klines = append(klines, database.KLine1m{
+			Symbol: symbol, OpenTime: openTime,
+			Open: price - 0.5, High: price + 1, Low: price - 1, Close: price,
+			Volume: 1, QuoteVolume: price, TradeCount: 2,
+			CloseTime: openTime + 59999,
+		})
+	}
+	if _, err := database.SaveKLine1m(klines); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKLinesFrom1m(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "BTC_USDT"
+	saveMinutes(t, symbol, 0, 14) // 3 根完整的5分钟K线
+
+	klines, err := LoadKLines(symbol, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 读取最近 2*5 根1分钟K线，聚合为最近2根5分钟K线
+	if len(klines) != 2 {
+		t.Fatalf("K线数量 = %d，期望 2: %+v", len(klines), klines)
+	}
+	for i, k := range klines {
+		first := 5 * (i + 1) // 第 5、10 分钟开始的周期
+		want := KLine{
+			OpenTime:    testBase + int64(first)*60000,
+			Open:        100 + float64(first) - 0.5,
+			High:        100 + float64(first+4) + 1,
+			Low:         100 + float64(first) - 1,
+			Close:       100 + float64(first+4),
+			Volume:      5,
+			QuoteVolume: 5 * (100 + float64(first) + 2),
+			TradeCount:  10,
+			CloseTime:   testBase + int64(first+5)*60000 - 1,
+		}
+		if k != want {
+			t.Errorf("第 %d 根K线 = %+v，期望 %+v", i, k, want)
+		}
+	}
+}
+
+func TestLoadKLinesPartialPeriod(t *testing.T) {
+	useMemoryStore(t)
+	const symbol = "ETH_USDT"
+	saveMinutes(t, symbol, 0, 6) // 第二个5分钟周期只有2根
+
+	klines, err := LoadKLines(symbol, 5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("K线数量 = %d，期望 2", len(klines))
+	}
+	if last := klines[1]; last.OpenTime != testBase+5*60000 || last.Volume != 2 || last.Close != 106 {
+		t.Errorf("未走完的周期 = %+v", last)
+	}
+
+	// 1分钟周期直接返回
+	klines, err = LoadKLines(symbol, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 3 || klines[0].OpenTime != testBase+4*60000 {
+		t.Errorf("1分钟K线 = %+v", klines)
+	}
+}
+
+func TestLoadKLinesEmpty(t *testing.T) {
+	useMemoryStore(t)
+	klines, err := LoadKLines("NONE_USDT", 60, 10)
+	if err != nil || len(klines) != 0 {
+		t.Errorf("没有数据的币种: %+v, %v", klines, err)
+	}
+}
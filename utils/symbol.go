@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"wails-contract-warn/config"
+	"wails-contract-warn/database"
+)
+
+// quoteCurrencies 识别无分隔符交易对（如 BTCUSDT）时使用的计价币种（较长的放在前面，避免 FDUSD 被识别为 USD）
+var quoteCurrencies = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "BTC", "ETH", "BNB", "DAI", "EUR", "TRY"}
+
+// symbolPattern 规范化后的交易对格式（同时用于拼接表名，只允许大写字母和数字）
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{1,20}_[A-Z0-9]{1,10}$`)
+
+// NormalizeSymbol 把各种写法的交易对统一为 Gate.io 格式（BTC_USDT）
+// 支持 BTC_USDT、btc_usdt、BTC-USDT、BTC/USDT、BTCUSDT（优先按交易对元数据拆分，没有元数据时按常见计价币种拆分），
+// 合约的存储名（BTC_USDT_PERP）保留后缀；symbols.json 中 symbol_aliases 配置的更名交易对返回新名称
+func NormalizeSymbol(symbol string) (string, error) {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	s = strings.NewReplacer("-", "_", "/", "_", ".", "_").Replace(s)
+
+	suffix := ""
+	if strings.HasSuffix(s, config.FuturesSymbolSuffix) {
+		s, suffix = strings.TrimSuffix(s, config.FuturesSymbolSuffix), config.FuturesSymbolSuffix
+	}
+
+	if !strings.Contains(s, "_") {
+		if resolved, ok := database.ResolveCompactSymbol(s); ok {
+			s = resolved
+		} else {
+			for _, quote := range quoteCurrencies {
+				if strings.HasSuffix(s, quote) && len(s) > len(quote) {
+					s = s[:len(s)-len(quote)] + "_" + quote
+					break
+				}
+			}
+		}
+	}
+
+	if !symbolPattern.MatchString(s) {
+		return "", fmt.Errorf("无效的交易对: %q（应为 BTC_USDT 格式）", symbol)
+	}
+	return config.ResolveSymbolAlias(s + suffix), nil
+}